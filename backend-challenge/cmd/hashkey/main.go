@@ -0,0 +1,45 @@
+// Command hashkey prints the API_KEY_HASHES entry, an ID and bcrypt hash, for
+// an API key read from standard input, so the key stays out of shell history
+// and process lists
+//
+//	echo -n "$KEY" | go run ./cmd/hashkey partner
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/apikey"
+)
+
+func main() {
+	if len(os.Args) != 2 || os.Args[1] == "" {
+		fmt.Fprintln(os.Stderr, "usage: echo -n KEY | hashkey ID")
+		os.Exit(1)
+	}
+	id := os.Args[1]
+	if strings.ContainsAny(id, ":,") {
+		fmt.Fprintln(os.Stderr, "key ID must not contain ':' or ','")
+		os.Exit(1)
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading API key: %v\n", err)
+		os.Exit(1)
+	}
+	key := strings.TrimRight(string(input), "\r\n")
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "usage: echo -n KEY | hashkey ID")
+		os.Exit(1)
+	}
+
+	hash, err := apikey.Hash(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hashing API key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s:%s\n", id, hash)
+}
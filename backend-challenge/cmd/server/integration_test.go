@@ -0,0 +1,160 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+)
+
+// fixtureCouponValidator is a coupon validator backed by a fixed set of codes
+type fixtureCouponValidator map[string]bool
+
+func (f fixtureCouponValidator) IsValid(ctx context.Context, code string) bool {
+	return f[code]
+}
+
+// newIntegrationServer starts a test server running the full application router
+func newIntegrationServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	cfg := &config.Config{
+		Auth:       config.AuthConfig{APIKeys: []string{"apitest"}},
+		Pagination: config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+		LogLevel:   "error",
+	}
+	validator := fixtureCouponValidator{"HAPPYHOURS": true, "FIFTYOFF": true}
+
+	router, err := newRouter(cfg, logger.New(cfg.LogLevel), routerDeps{
+		couponValidator: validator,
+		discounts:       service.DefaultDiscountRules(),
+	})
+	if err != nil {
+		t.Fatalf("newRouter() error = %v", err)
+	}
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// postOrder sends an order request through the full stack
+func postOrder(t *testing.T, srv *httptest.Server, apiKey string, req models.OrderRequest) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, srv.URL+"/api/order", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		httpReq.Header.Set("api_key", apiKey)
+	}
+
+	resp, err := srv.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestIntegration_OrderWithValidCoupon(t *testing.T) {
+	srv := newIntegrationServer(t)
+
+	resp := postOrder(t, srv, "apitest", models.OrderRequest{
+		CouponCode: "HAPPYHOURS",
+		Items: []models.OrderItem{
+			{ProductID: "1", Quantity: 2},
+			{ProductID: "4", Quantity: 1},
+		},
+	})
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	// Decode strictly so the response shape must match the model
+	var order models.Order
+	decoder := json.NewDecoder(resp.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&order); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if order.ID == "" {
+		t.Error("order ID is empty")
+	}
+	if len(order.Items) != 2 {
+		t.Errorf("items count = %d, want 2", len(order.Items))
+	}
+	if len(order.Products) != 2 {
+		t.Errorf("products count = %d, want 2", len(order.Products))
+	}
+
+	// Subtotal 34.97 with 18% off
+	if !order.CouponApplied || order.AppliedCoupon != "HAPPYHOURS" {
+		t.Errorf("coupon applied = %v (%q), want true (HAPPYHOURS)", order.CouponApplied, order.AppliedCoupon)
+	}
+	if order.Discount != 6.29 {
+		t.Errorf("discount = %v, want 6.29", order.Discount)
+	}
+	if order.Total != 28.68 {
+		t.Errorf("total = %v, want 28.68", order.Total)
+	}
+}
+
+func TestIntegration_OrderWithInvalidCoupon(t *testing.T) {
+	srv := newIntegrationServer(t)
+
+	resp := postOrder(t, srv, "apitest", models.OrderRequest{
+		CouponCode: "SUPER100",
+		Items: []models.OrderItem{
+			{ProductID: "1", Quantity: 1},
+		},
+	})
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if body["error"] != "Coupon code is not valid" {
+		t.Errorf("error = %q, want %q", body["error"], "Coupon code is not valid")
+	}
+}
+
+func TestIntegration_OrderRequiresAPIKey(t *testing.T) {
+	srv := newIntegrationServer(t)
+
+	resp := postOrder(t, srv, "", models.OrderRequest{
+		Items: []models.OrderItem{
+			{ProductID: "1", Quantity: 1},
+		},
+	})
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
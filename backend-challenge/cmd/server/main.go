@@ -2,24 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
-	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/handlers"
-	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/middleware"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/metrics"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
-	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -30,6 +31,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Cancel the run context on interrupt signal to gracefully shutdown the server
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	// SIGHUP reloads the coupon files without shutting down
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	err = run(ctx, cfg, reload)
+	signal.Stop(reload)
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run wires up all dependencies, starts the HTTP server and blocks until ctx
+// is cancelled, after which the server is shut down gracefully
+// Each signal received on reload builds a new coupon validator and swaps it
+// in; a nil channel disables reloading
+func run(ctx context.Context, cfg *config.Config, reload <-chan os.Signal) error {
 	// Initialize structured logger
 	log := logger.New(cfg.LogLevel)
 	slog.SetDefault(log)
@@ -40,106 +62,369 @@ func main() {
 		"log_level", cfg.LogLevel,
 	)
 
-	// Initialize coupon validator
-	log.Info("loading coupon file paths...")
-	couponValidator := coupon.NewValidator()
-	couponFilePaths := []string{
-		fmt.Sprintf("%s/couponbase1", cfg.Coupon.DataDir),
-		fmt.Sprintf("%s/couponbase2", cfg.Coupon.DataDir),
-		fmt.Sprintf("%s/couponbase3", cfg.Coupon.DataDir),
+	// Load discount definitions, falling back to the built-in discounts
+	discounts, err := service.ParseDiscountRules(cfg.Coupon.Discounts)
+	if err != nil {
+		return err
+	}
+	flags, err := service.ParseFeatureFlags(cfg.Coupon.FeatureFlags)
+	if err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	if err := couponValidator.LoadFromFiles(ctx, couponFilePaths); err != nil {
-		log.Error("failed to load coupon file paths", "error", err)
-		os.Exit(1)
+	// Products come from the seed file when one is configured
+	var products repository.ProductRepository
+	if cfg.ProductSeedPath != "" {
+		seeded, err := repository.NewInMemoryProductRepositoryFromFile(cfg.ProductSeedPath)
+		if err != nil {
+			return err
+		}
+		products = seeded
 	}
 
-	stats := couponValidator.GetStats()
-	log.Info("coupon files configured successfully",
-		"total_files", stats["total_files"],
-		"file_paths", stats["file_paths"],
-	)
+	// Order audit records go to their own JSON sink, apart from access logs
+	auditOut, closeAudit, err := openAuditLog(cfg.AuditLogPath)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+	auditor := service.NewLogAuditor(slog.New(slog.NewJSONHandler(auditOut, nil)))
+
+	// Per-run registry so repeated runs (e.g. in tests) don't collide
+	registry := prometheus.NewRegistry()
+
+	// Initialize coupon validator, or explicitly disable coupons
+	var couponValidator service.CouponValidator = service.CouponsDisabled
+	if cfg.Coupon.Enabled {
+		validator, err := loadCouponValidator(ctx, cfg, log)
+		if err != nil {
+			return err
+		}
+		if cfg.Coupon.CheckDiscountCodes {
+			checkDiscountCodes(ctx, validator, discounts, log)
+		}
+
+		// Serve through a provider so a rebuilt validator can be swapped in
+		provider := coupon.NewValidatorProvider(validator, coupon.DefaultCloseGrace)
+		defer func() {
+			if err := provider.Close(); err != nil {
+				log.Error("failed to close coupon validator", "error", err)
+			}
+		}()
+		couponValidator = provider
 
-	// Initialize repositories
-	productRepo := repository.NewInMemoryProductRepository()
-
-	// Initialize services
-	productService := service.NewProductService(productRepo)
-	orderService := service.NewOrderService(productRepo, couponValidator)
-
-	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler(log)
-	productHandler := handlers.NewProductHandler(productService, log)
-	orderHandler := handlers.NewOrderHandler(orderService, log)
-
-	// Create router
-	r := chi.NewRouter()
-
-	// Apply middleware
-	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
-	r.Use(middleware.Logger(log))
-	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.Timeout(60 * time.Second))
-
-	// CORS configuration
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "api_key"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300,
-	}))
-
-	// Register health check endpoint
-	r.Get("/health", healthHandler.ServeHTTP)
-
-	// API routes
-	r.Route("/api", func(r chi.Router) {
-		// Product endpoints
-		r.Get("/product", productHandler.ListProducts)
-		r.Get("/product/{productId}", productHandler.GetProduct)
-
-		// Order endpoints - requires API key authentication per OpenAPI spec
-		r.With(middleware.APIKeyAuth(cfg.Auth)).Post("/order", orderHandler.CreateOrder)
+		// Periodically export filter and cache stats; stopped with the run context
+		collector, err := metrics.NewCouponCollector(provider, registry, time.Duration(cfg.Coupon.MetricsInterval)*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to register coupon metrics: %w", err)
+		}
+		collectorCtx, stopCollector := context.WithCancel(ctx)
+		collectorDone := make(chan struct{})
+		go func() {
+			defer close(collectorDone)
+			collector.Run(collectorCtx)
+		}()
+		defer func() {
+			stopCollector()
+			<-collectorDone
+		}()
+
+		// Reload on request until shutdown; the server keeps serving meanwhile
+		reloaderCtx, stopReloader := context.WithCancel(ctx)
+		reloaderDone := make(chan struct{})
+		go func() {
+			defer close(reloaderDone)
+			for generation := 1; ; generation++ {
+				select {
+				case <-reloaderCtx.Done():
+					return
+				case sig := <-reload:
+					reloadCoupons(reloaderCtx, provider, cfg, generation, log.With("signal", sig.String()))
+				}
+			}
+		}()
+		defer func() {
+			stopReloader()
+			<-reloaderDone
+		}()
+	} else {
+		log.Warn("coupons are disabled, coupon codes will be ignored")
+	}
+
+	router, err := newRouter(cfg, log, routerDeps{
+		couponValidator: couponValidator,
+		discounts:       discounts,
+		flags:           flags,
+		auditor:         auditor,
+		metrics:         promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+		products:        products,
 	})
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      r,
+		Handler:      router,
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
 	}
 
 	// Start server in a goroutine
+	serverErr := make(chan error, 1)
 	go func() {
 		log.Info("server listening", "address", addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("server failed to start", "error", err)
-			os.Exit(1)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
+		close(serverErr)
 	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Wait for the context to be cancelled or the server to fail
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server failed to start: %w", err)
+	case <-ctx.Done():
+	}
 
 	log.Info("shutting down server...")
 
 	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	// Attempt graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("server forced to shutdown", "error", err)
-		os.Exit(1)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
 	log.Info("server stopped gracefully")
+	return nil
+}
+
+// loadCouponExpiries parses the coupon expiry JSON, read from
+// COUPON_EXPIRIES_FILE when that is set
+func loadCouponExpiries(cfg config.CouponConfig) (map[string]time.Time, error) {
+	data := []byte(cfg.Expiries)
+	if cfg.ExpiriesFile != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.ExpiriesFile); err != nil {
+			return nil, fmt.Errorf("failed to read coupon expiries: %w", err)
+		}
+	}
+	return coupon.ParseExpiries(data)
+}
+
+// loadCouponValidator creates the coupon validator and loads the coupon files,
+// downloading them first when URLs are configured
+func loadCouponValidator(ctx context.Context, cfg *config.Config, log *slog.Logger) (*coupon.Validator, error) {
+	expiries, err := loadCouponExpiries(cfg.Coupon)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("loading coupon file paths...")
+	couponValidator := coupon.NewValidatorWithOptions(coupon.ValidatorOptions{
+		CacheCapacity:            cfg.Coupon.CacheCapacity,
+		NegativeCacheCapacity:    cfg.Coupon.NegativeCacheCapacity,
+		CacheMemoryBudget:        uint64(cfg.Coupon.CacheMemoryBudgetMB) << 20,
+		CacheMemoryCheckInterval: time.Duration(cfg.Coupon.CacheMemoryCheckMs) * time.Millisecond,
+		DiskCachePath:            cfg.Coupon.DiskCachePath,
+		DiskCacheCapacity:        cfg.Coupon.DiskCacheCapacity,
+		ComputeOverlap:           cfg.Coupon.ComputeOverlap,
+		OverlapMaxDuration:       time.Duration(cfg.Coupon.OverlapMaxDuration) * time.Second,
+		MaxConcurrentSearches:    cfg.Coupon.MaxConcurrentSearches,
+		MaxGoroutines:            cfg.Coupon.MaxGoroutines,
+		MaxConcurrentDownloads:   cfg.Coupon.MaxConcurrentDownloads,
+		DownloadTimeout:          time.Duration(cfg.Coupon.DownloadTimeout) * time.Second,
+		UseMmap:                  cfg.Coupon.UseMmap,
+		BreakerThreshold:         cfg.Coupon.BreakerThreshold,
+		BreakerCooldown:          time.Duration(cfg.Coupon.BreakerCooldown) * time.Second,
+		FileWeights:              cfg.Coupon.FileWeights,
+		FilterBuildWorkers:       cfg.Coupon.FilterBuildWorkers,
+		ExpectedItems:            uint(cfg.Coupon.FilterExpectedItems),
+		FalsePositiveRate:        cfg.Coupon.FilterFPRate,
+		TrustBloomOnError:        cfg.Coupon.TrustBloomOnError,
+		Expiries:                 expiries,
+		Logger:                   log,
+	})
+	couponFilePaths := []string{
+		fmt.Sprintf("%s/couponbase1", cfg.Coupon.DataDir),
+		fmt.Sprintf("%s/couponbase2", cfg.Coupon.DataDir),
+		fmt.Sprintf("%s/couponbase3", cfg.Coupon.DataDir),
+	}
+
+	if len(cfg.Coupon.URLs) > 0 {
+		log.Info("downloading coupon files", "urls", cfg.Coupon.URLs, "dir", cfg.Coupon.DataDir)
+		err = couponValidator.LoadFromURLs(ctx, cfg.Coupon.URLs, cfg.Coupon.DataDir)
+	} else if cfg.Coupon.FileGlob != "" {
+		log.Info("loading coupon files from directory", "dir", cfg.Coupon.DataDir, "pattern", cfg.Coupon.FileGlob)
+		err = couponValidator.LoadFromDir(ctx, cfg.Coupon.DataDir, cfg.Coupon.FileGlob)
+	} else if cfg.Coupon.FilterCacheDir != "" {
+		err = loadCachedFilters(ctx, couponValidator, cfg.Coupon.FilterCacheDir, couponFilePaths, log)
+	} else {
+		err = couponValidator.LoadFromFiles(ctx, couponFilePaths)
+	}
+	if err != nil {
+		_ = couponValidator.Close()
+		return nil, fmt.Errorf("failed to load coupon files: %w", err)
+	}
+
+	if cfg.Coupon.WarmPageCache {
+		warmPageCache(ctx, couponValidator, log)
+	}
+
+	stats := couponValidator.GetStats()
+	log.Info("coupon files configured successfully",
+		"total_files", stats["totalFiles"],
+		"file_paths", stats["filePaths"],
+		"file_build_durations_ms", stats["fileBuildDurationsMs"],
+	)
+	if overlap, ok := stats["overlapDistribution"]; ok {
+		log.Info("coupon overlap statistics computed",
+			"overlap_distribution", overlap,
+			"truncated", stats["overlapTruncated"],
+		)
+	}
+
+	return couponValidator, nil
+}
+
+// loadCachedFilters loads the coupon files reusing the Bloom filters saved
+// in dir where the files haven't changed, then saves any rebuilt filters for
+// the next start. A cache that can't be used or saved only costs a rebuild
+func loadCachedFilters(ctx context.Context, validator *coupon.Validator, dir string, filePaths []string, log *slog.Logger) error {
+	loaded, err := validator.LoadFiltersFromCache(ctx, dir, filePaths)
+	if err != nil {
+		log.Warn("failed to load coupon filters from cache, rebuilding them", "dir", dir, "error", err)
+	}
+	if !loaded {
+		if err := validator.LoadFromFiles(ctx, filePaths); err != nil {
+			return err
+		}
+	}
+	built := validator.GetStats()["filterBuilds"]
+	log.Info("coupon filters loaded",
+		"from_cache", loaded,
+		"filters_built", built,
+	)
+
+	// Nothing to save when every filter came from the cache
+	if loaded && built == int64(0) {
+		return nil
+	}
+	if err := validator.SaveFilters(dir); err != nil {
+		log.Warn("failed to save coupon filters to cache", "dir", dir, "error", err)
+	}
+	return nil
+}
+
+// checkDiscountCodes validates every configured discount code against the
+// loaded coupon files and warns about any that fail, since such a promotion
+// is defined but can never be applied
+func checkDiscountCodes(ctx context.Context, validator *coupon.Validator, discounts service.DiscountRules, log *slog.Logger) {
+	codes := make([]string, 0, len(discounts))
+	for code := range discounts {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		result := validator.Evaluate(ctx, code, coupon.EvaluateOptions{})
+		if !result.Valid {
+			log.Warn("configured discount code does not validate against the coupon files",
+				"coupon", code,
+				"reason", result.Reason,
+			)
+		}
+	}
+}
+
+// warmPageCache reads the coupon files into the page cache; warming only
+// speeds up the first searches, so a failure is not fatal
+func warmPageCache(ctx context.Context, validator *coupon.Validator, log *slog.Logger) {
+	start := time.Now()
+	read, err := validator.WarmPageCache(ctx)
+	if err != nil {
+		log.Warn("failed to warm coupon file page cache", "error", err)
+		return
+	}
+	log.Info("coupon file page cache warmed",
+		"bytes", read,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+// reloadCoupons reloads the configured coupon sources into a clone of the
+// current validator and swaps it in, so requests are served by the current
+// one, without waiting on its locks, until the clone is fully loaded
+//
+// The clone keeps the filters, checksums and download validators of the
+// current one: only changed files are rebuilt, URLs answering 304 aren't
+// downloaded again, and nothing is swapped when no file changed.
+func reloadCoupons(ctx context.Context, provider *coupon.ValidatorProvider, cfg *config.Config, generation int, log *slog.Logger) {
+	log.Info("reloading coupon files", "generation", generation)
+
+	// The replaced validator removes its disk cache file once closed, so
+	// each generation gets a file of its own
+	reloadCfg := *cfg
+	if cfg.Coupon.DiskCachePath != "" {
+		reloadCfg.Coupon.DiskCachePath = fmt.Sprintf("%s.%d", cfg.Coupon.DiskCachePath, generation)
+	}
+
+	// A glob may match other files by now, which Reload can't pick up
+	if cfg.Coupon.FileGlob != "" {
+		validator, err := loadCouponValidator(ctx, &reloadCfg, log)
+		if err != nil {
+			log.Error("failed to reload coupon files", "error", err)
+			return
+		}
+		provider.SwapTo(validator)
+		log.Info("coupon files reloaded", "generation", generation)
+		return
+	}
+
+	validator, err := provider.Current().Clone(reloadCfg.Coupon.DiskCachePath)
+	if err != nil {
+		log.Error("failed to reload coupon files", "error", err)
+		return
+	}
+
+	var rebuilt int
+	if len(cfg.Coupon.URLs) > 0 {
+		rebuilt, err = validator.ReloadFromURLs(ctx)
+	} else {
+		rebuilt, err = validator.Reload(ctx)
+	}
+	if err != nil || rebuilt == 0 {
+		_ = validator.Close()
+		if err != nil {
+			log.Error("failed to reload coupon files", "error", err)
+		} else {
+			log.Info("coupon files unchanged", "generation", generation)
+		}
+		return
+	}
+
+	if cfg.Coupon.WarmPageCache {
+		warmPageCache(ctx, validator, log)
+	}
+	provider.SwapTo(validator)
+	log.Info("coupon files reloaded", "generation", generation, "rebuilt", rebuilt)
+}
+
+// openAuditLog opens the order audit sink, appending to path or using stderr
+// when path is empty, and returns a func that closes it
+func openAuditLog(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return os.Stderr, func() {}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return file, func() { _ = file.Close() }, nil
 }
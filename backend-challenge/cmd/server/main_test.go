@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+)
+
+// newTestConfig creates a configuration pointing at fixture coupon files and a free port
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	dataDir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dataDir, fmt.Sprintf("couponbase%d", i))
+		if err := os.WriteFile(path, []byte("HAPPYHRS\nFIFTYOFF\n"), 0644); err != nil {
+			t.Fatalf("failed to create coupon fixture: %v", err)
+		}
+	}
+
+	// Reserve a free port for the server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	port := fmt.Sprintf("%d", ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+
+	return &config.Config{
+		Server: config.ServerConfig{
+			Port:            port,
+			Host:            "127.0.0.1",
+			ReadTimeout:     5,
+			WriteTimeout:    5,
+			ShutdownTimeout: 5,
+		},
+		Auth: config.AuthConfig{
+			APIKeys: []string{"apitest"},
+		},
+		Coupon: config.CouponConfig{
+			Enabled: true,
+			DataDir: dataDir,
+		},
+		Pagination: config.PaginationConfig{
+			DefaultPageSize: 20,
+			MaxPageSize:     100,
+		},
+		LogLevel: "error",
+	}
+}
+
+func TestRun_HealthAndShutdown(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, nil)
+	}()
+
+	// Poll the health endpoint until the server is up
+	url := fmt.Sprintf("http://%s:%s/health", cfg.Server.Host, cfg.Server.Port)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("health status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			break
+		}
+
+		select {
+		case err := <-runErr:
+			t.Fatalf("run() returned before server was ready: %v", err)
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not become healthy: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Cancel the context to trigger graceful shutdown
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("run() error = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run() did not return after context cancellation")
+	}
+}
+
+func TestRun_CouponFilesMissing(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Coupon.DataDir = filepath.Join(t.TempDir(), "missing")
+
+	if err := run(context.Background(), cfg, nil); err == nil {
+		t.Error("expected error for missing coupon files, got nil")
+	}
+}
+
+func TestRun_CouponsDisabled(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Coupon.Enabled = false
+	cfg.Coupon.DataDir = filepath.Join(t.TempDir(), "missing")
+
+	// Coupon files are not needed when coupons are intentionally off
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(ctx, cfg, nil) }()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Errorf("run() error = %v, want nil", err)
+	}
+}
+
+func TestRun_ReloadOnSignal(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reload := make(chan os.Signal, 1)
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(ctx, cfg, reload) }()
+
+	// NEWCODE1 is only valid once the rewritten files are reloaded
+	base := fmt.Sprintf("http://%s:%s", cfg.Server.Host, cfg.Server.Port)
+	validate := func() (bool, error) {
+		req, _ := http.NewRequest(http.MethodGet, base+"/api/coupon/NEWCODE1/validate", nil)
+		req.Header.Set("api_key", "apitest")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Valid bool `json:"valid"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return false, err
+		}
+		return result.Valid, nil
+	}
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			valid, err := validate()
+			if err == nil && valid == want {
+				return
+			}
+
+			select {
+			case err := <-runErr:
+				t.Fatalf("run() returned while serving: %v", err)
+			default:
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("NEWCODE1 valid = %v (%v), want %v", valid, err, want)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	waitFor(false)
+
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(cfg.Coupon.DataDir, fmt.Sprintf("couponbase%d", i))
+		if err := os.WriteFile(path, []byte("HAPPYHRS\nFIFTYOFF\nNEWCODE1\n"), 0644); err != nil {
+			t.Fatalf("failed to rewrite coupon fixture: %v", err)
+		}
+	}
+	reload <- syscall.SIGHUP
+
+	// The server stays up and serves the reloaded files
+	waitFor(true)
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("run() error = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run() did not return after context cancellation")
+	}
+}
+
+func TestCheckDiscountCodes(t *testing.T) {
+	// The fixture holds every built-in discount code except BUYGETONE
+	dataDir := t.TempDir()
+	var paths []string
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dataDir, fmt.Sprintf("couponbase%d", i))
+		if err := os.WriteFile(path, []byte("HAPPYHOURS\nHAPPYHRS\n"), 0644); err != nil {
+			t.Fatalf("failed to create coupon fixture: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	validator := coupon.NewValidator()
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), paths); err != nil {
+		t.Fatalf("failed to load coupon files: %v", err)
+	}
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	checkDiscountCodes(context.Background(), validator, service.DefaultDiscountRules(), log)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want 1 warning:\n%s", len(lines), logs.String())
+	}
+	for _, want := range []string{"level=WARN", "coupon=BUYGETONE", "reason=not_found"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("warning %q does not contain %q", lines[0], want)
+		}
+	}
+}
+
+func TestReloadCoupons_SwapsValidator(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Coupon.CacheCapacity = 100
+	cfg.Coupon.DiskCachePath = filepath.Join(t.TempDir(), "coupons.cache")
+	log := slog.New(slog.DiscardHandler)
+	ctx := context.Background()
+
+	old, err := loadCouponValidator(ctx, cfg, log)
+	if err != nil {
+		t.Fatalf("loadCouponValidator() error = %v", err)
+	}
+	provider := coupon.NewValidatorProvider(old, time.Hour)
+	defer provider.Close()
+	defer old.Close()
+
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(cfg.Coupon.DataDir, fmt.Sprintf("couponbase%d", i))
+		if err := os.WriteFile(path, []byte("HAPPYHRS\nFIFTYOFF\nNEWCODE1\n"), 0644); err != nil {
+			t.Fatalf("failed to rewrite coupon fixture: %v", err)
+		}
+	}
+	reloadCoupons(ctx, provider, cfg, 1, log)
+
+	current := provider.Current()
+	if current == old {
+		t.Fatal("reload did not swap in a new validator")
+	}
+	if !current.IsValid(ctx, "NEWCODE1") {
+		t.Error("new validator doesn't accept NEWCODE1")
+	}
+	// The old validator is left as it was for requests still using it
+	if old.IsValid(ctx, "NEWCODE1") {
+		t.Error("old validator was changed in place")
+	}
+	if _, err := os.Stat(cfg.Coupon.DiskCachePath + ".1"); err != nil {
+		t.Errorf("new validator's disk cache: %v", err)
+	}
+}
+
+func TestReloadCoupons_UnchangedSources(t *testing.T) {
+	// Serves each coupon file with an ETag, answering 304 when it matches
+	var downloads atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		fmt.Fprint(w, "HAPPYHRS\nFIFTYOFF\n")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		urls          []string
+		wantDownloads int64
+	}{
+		{name: "files"},
+		{
+			name:          "urls",
+			urls:          []string{srv.URL + "/couponbase1", srv.URL + "/couponbase2", srv.URL + "/couponbase3"},
+			wantDownloads: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloads.Store(0)
+			cfg := newTestConfig(t)
+			cfg.Coupon.URLs = tt.urls
+			log := slog.New(slog.DiscardHandler)
+			ctx := context.Background()
+
+			validator, err := loadCouponValidator(ctx, cfg, log)
+			if err != nil {
+				t.Fatalf("loadCouponValidator() error = %v", err)
+			}
+			provider := coupon.NewValidatorProvider(validator, time.Hour)
+			defer provider.Close()
+
+			// Two SIGHUPs with nothing changed in between
+			reloadCoupons(ctx, provider, cfg, 1, log)
+			reloadCoupons(ctx, provider, cfg, 2, log)
+
+			if provider.Current() != validator {
+				t.Error("reload swapped in a new validator though no source changed")
+			}
+			if builds := provider.GetStats()["filterBuilds"]; builds != int64(3) {
+				t.Errorf("filterBuilds = %v, want 3 from the initial load only", builds)
+			}
+			if got := downloads.Load(); got != tt.wantDownloads {
+				t.Errorf("downloads = %d, want %d from the initial load only", got, tt.wantDownloads)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/handlers"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/middleware"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+)
+
+// routerDeps are the dependencies built in run and wired into the handlers
+type routerDeps struct {
+	couponValidator service.CouponValidator
+	discounts       service.DiscountRules
+	flags           service.FeatureFlags
+	auditor         service.OrderAuditor // optional, nil disables order auditing
+	metrics         http.Handler         // optional, served at /metrics
+
+	// products replaces the built-in catalog when set
+	products repository.ProductRepository
+}
+
+// newRouter builds the application router with all middleware and routes registered
+func newRouter(cfg *config.Config, log *slog.Logger, deps routerDeps) (http.Handler, error) {
+	couponValidator := deps.couponValidator
+
+	// Initialize repositories
+	productRepo := deps.products
+	if productRepo == nil {
+		productRepo = repository.NewInMemoryProductRepository()
+	}
+
+	// The configured catalog is the default store, next to the seeded ones
+	stores, err := repository.NewSeededStoreCatalog(context.Background(), productRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize services
+	productService := service.NewProductService(productRepo)
+	productService.SetStores(stores)
+	orderService := service.NewOrderServiceWithDiscounts(productRepo, couponValidator, deps.discounts)
+	orderService.SetStores(stores)
+	orderService.SetAuditor(deps.auditor)
+	orderService.SetLogger(log)
+	orderService.SetRejectUnmatchedCoupons(cfg.Coupon.UnmatchedMode == "reject")
+	orderService.SetFeatureFlags(deps.flags)
+	orderService.SetZeroQuantityPolicy(service.ZeroQuantityPolicy(cfg.ZeroQuantityPolicy))
+	orderService.SetOrderLimit(service.OrderLimit{
+		MaxTotal: cfg.OrderLimit.MaxTotal,
+		Flag:     cfg.OrderLimit.Mode == "flag",
+	})
+
+	// Initialize handlers
+	cachePinger, _ := couponValidator.(handlers.CachePinger)
+	healthHandler := handlers.NewHealthHandler(cachePinger, time.Duration(cfg.Coupon.HealthCacheBudgetMs)*time.Millisecond, log)
+	productHandler := handlers.NewProductHandler(productService, cfg.Pagination, log)
+	orderHandler := handlers.NewOrderHandler(orderService, cfg.Server.ServerTiming, log)
+	configHandler := handlers.NewConfigHandler(cfg, log)
+	couponAPI, hasCouponAPI := couponValidator.(handlers.CouponValidator)
+
+	// Create router
+	r := chi.NewRouter()
+
+	// Apply middleware
+	r.Use(chimiddleware.StripSlashes)
+	r.Use(middleware.CaseInsensitiveRoutes(r))
+	r.Use(chimiddleware.RequestID)
+	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.Logger(log))
+	r.Use(chimiddleware.Recoverer)
+	r.Use(middleware.RouteTimeouts(cfg.Server))
+
+	// CORS configuration
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Signature", "api_key", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "X-Total-Count", "X-Total-Pages", "X-Has-Next", "X-Has-Prev"},
+		AllowCredentials: false,
+		MaxAge:           300,
+	}))
+
+	// Register health check endpoint
+	r.Get("/health", healthHandler.ServeHTTP)
+
+	// Register Prometheus metrics endpoint
+	if deps.metrics != nil {
+		r.Method(http.MethodGet, "/metrics", deps.metrics)
+	}
+
+	// API routes
+	r.Route("/api", func(r chi.Router) {
+		// Rate limit API calls per client IP (health checks are exempt)
+		r.Use(middleware.RateLimit(cfg.RateLimit))
+
+		// One key check for every route, so keys verified against a hash are
+		// remembered across them
+		apiKeyAuth := middleware.APIKeyAuth(cfg.Auth)
+
+		// Product endpoints
+		r.Get("/product", productHandler.ListProducts)
+		r.Get("/product/{productId}", productHandler.GetProduct)
+		r.With(apiKeyAuth).Post("/product", productHandler.CreateProduct)
+		r.With(apiKeyAuth).Post("/product/reload", productHandler.ReloadProducts)
+		r.With(apiKeyAuth).Delete("/product/{productId}", productHandler.DeleteProduct)
+		r.Get("/category", productHandler.ListCategories)
+
+		// Effective configuration with secrets redacted, for debugging deployments
+		r.With(apiKeyAuth).Get("/config", configHandler.GetConfig)
+
+		// Order endpoints - requires API key authentication per OpenAPI spec
+		// Keys with a signing secret must also sign the body (opt-in per key)
+		r.With(apiKeyAuth, middleware.SignatureAuth(cfg.Auth)).Post("/order", orderHandler.CreateOrder)
+
+		// Coupon applicability for a cart, priced like an order but not placed
+		r.With(apiKeyAuth).Post("/coupon/check", orderHandler.CheckCoupon)
+
+		// Coupon endpoints - only when the validator supports them
+		if hasCouponAPI {
+			couponHandler := handlers.NewCouponHandler(couponAPI, time.Duration(cfg.Coupon.CacheTTL)*time.Second, cfg.Pagination.MaxBatchSize, log)
+			couponHandler.SetInvalidStatus(cfg.Coupon.InvalidStatus)
+			r.With(apiKeyAuth).Post("/coupon/validate", couponHandler.ValidateBatch)
+			r.With(apiKeyAuth).Get("/coupon/{couponCode}/validate", couponHandler.ValidateCoupon)
+			if _, ok := couponAPI.(handlers.CouponExistenceChecker); ok {
+				r.With(apiKeyAuth).Get("/coupon/{couponCode}/exists", couponHandler.CouponExists)
+			}
+
+			// Inspection reveals which files hold a code and searches all of
+			// them, and overrides change what every customer is charged, so
+			// both take an admin key
+			adminOnly := middleware.AdminOnly(cfg.Auth)
+			r.With(apiKeyAuth, adminOnly).Get("/coupon/{couponCode}/inspect", couponHandler.InspectCoupon)
+			r.With(apiKeyAuth, adminOnly).Put("/coupon/{couponCode}/override", couponHandler.SetOverride)
+			r.With(apiKeyAuth, adminOnly).Delete("/coupon/{couponCode}/override", couponHandler.DeleteOverride)
+
+			// Single-use redemption needs a validator that reports unchecked codes
+			if verifier, ok := couponAPI.(coupon.CodeVerifier); ok {
+				couponHandler.SetRedeemer(coupon.NewRedeemer(verifier))
+				r.With(apiKeyAuth).Post("/coupon/{couponCode}/redeem", couponHandler.RedeemCoupon)
+			}
+		}
+	})
+
+	return r, nil
+}
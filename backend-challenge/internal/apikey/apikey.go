@@ -0,0 +1,135 @@
+package apikey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash returns the bcrypt hash of key at the default cost, in the form
+// API_KEY_HASHES takes after the key ID
+func Hash(key string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// ValidateHash reports whether hash is a well-formed bcrypt hash
+func ValidateHash(hash string) error {
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return fmt.Errorf("not a bcrypt hash: %w", err)
+	}
+	return nil
+}
+
+// compareDigests compares two key digests in constant time, replaceable in
+// tests to count comparisons
+var compareDigests = subtle.ConstantTimeCompare
+
+// knownKey is an accepted key, held as its keyed digest, and its ID
+type knownKey struct {
+	digest []byte
+	id     string
+}
+
+// Store checks presented API keys against plaintext keys and bcrypt hashes,
+// identifying the key that matched
+// Keys are compared as HMAC-SHA256 digests under a random per-store secret,
+// so comparisons take the same time whatever the key's length, and every
+// known key is compared without stopping at a match, so how long a check
+// takes doesn't reveal which key, if any, matched
+type Store struct {
+	secret []byte
+	keys   []knownKey
+	hashes map[string][]byte // key ID -> bcrypt hash
+
+	// Hashing is deliberately slow, so keys that matched a hash are
+	// remembered by their digest. Only matches are kept, bounding the list
+	// to the configured hashes; failed attempts always pay the full cost,
+	// which is why hashed keys require rate limiting
+	mu       sync.Mutex
+	verified []knownKey
+}
+
+// NewStore creates a store accepting keys, identified by themselves, and
+// keys matching hashes, identified by the ID each hash is listed under
+func NewStore(keys []string, hashes map[string]string) *Store {
+	s := &Store{
+		secret: make([]byte, sha256.Size),
+		keys:   make([]knownKey, 0, len(keys)),
+		hashes: make(map[string][]byte, len(hashes)),
+	}
+	// rand.Read never returns an error
+	_, _ = rand.Read(s.secret)
+	for _, key := range keys {
+		s.keys = append(s.keys, knownKey{digest: s.digest(key), id: key})
+	}
+	for id, hash := range hashes {
+		s.hashes[id] = []byte(hash)
+	}
+	return s
+}
+
+// Lookup returns the ID of the key that key matches, reporting false when
+// it matches none
+func (s *Store) Lookup(key string) (string, bool) {
+	digest := s.digest(key)
+
+	s.mu.Lock()
+	verified := s.verified
+	s.mu.Unlock()
+
+	id, matched := "", false
+	for _, known := range [][]knownKey{s.keys, verified} {
+		for _, k := range known {
+			if compareDigests(digest, k.digest) == 1 {
+				id, matched = k.id, true
+			}
+		}
+	}
+	// Only a key matching none of them needs the slow hash comparisons
+	if matched || len(s.hashes) == 0 {
+		return id, matched
+	}
+
+	for hashID, hash := range s.hashes {
+		if bcrypt.CompareHashAndPassword(hash, []byte(key)) == nil {
+			id, matched = hashID, true
+		}
+	}
+	if matched {
+		s.remember(knownKey{digest: digest, id: id})
+	}
+	return id, matched
+}
+
+// remember adds a key that matched a hash to the verified keys, once per ID
+// even when concurrent lookups verified it together
+func (s *Store) remember(key knownKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.verified {
+		if k.id == key.id {
+			return
+		}
+	}
+	// Copy on write: lookups in flight keep iterating their own slice
+	verified := make([]knownKey, len(s.verified), len(s.verified)+1)
+	copy(verified, s.verified)
+	s.verified = append(verified, key)
+}
+
+// digest returns the keyed digest key is compared by
+func (s *Store) digest(key string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,131 @@
+package apikey
+
+import (
+	"crypto/subtle"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testHash returns a bcrypt hash of key at the minimum cost, to keep tests fast
+func testHash(t *testing.T, key string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	return string(hash)
+}
+
+func TestStore_Lookup(t *testing.T) {
+	hash := testHash(t, "hashedkey1")
+	store := NewStore([]string{"apitest"}, map[string]string{
+		"partner": hash,
+		"ci":      testHash(t, "hashedkey2"),
+	})
+
+	tests := []struct {
+		name   string
+		key    string
+		wantID string
+		wantOK bool
+	}{
+		{"plaintext key", "apitest", "apitest", true},
+		{"key matching its hash", "hashedkey1", "partner", true},
+		{"key matching another hash", "hashedkey2", "ci", true},
+		{"non-matching key", "hashedkey3", "", false},
+		{"the hash itself", hash, "", false},
+		{"key ID", "partner", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Twice, so the second check of a hashed key is a remembered match
+			for i := 0; i < 2; i++ {
+				id, ok := store.Lookup(tt.key)
+				if id != tt.wantID || ok != tt.wantOK {
+					t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.key, id, ok, tt.wantID, tt.wantOK)
+				}
+			}
+		})
+	}
+
+	if n := len(store.verified); n != 2 {
+		t.Errorf("%d remembered keys, want only the 2 hashed keys", n)
+	}
+}
+
+func TestStore_LookupComparesEveryKey(t *testing.T) {
+	store := NewStore([]string{"apitest", "testkey123", "otherkey"}, map[string]string{
+		"partner": testHash(t, "hashedkey1"),
+		"ci":      testHash(t, "hashedkey2"),
+	})
+	// Both hashed keys are remembered once verified
+	for _, key := range []string{"hashedkey1", "hashedkey2"} {
+		if _, ok := store.Lookup(key); !ok {
+			t.Fatalf("Lookup(%q) failed", key)
+		}
+	}
+
+	var compared int
+	compareDigests = func(x, y []byte) int {
+		compared++
+		return subtle.ConstantTimeCompare(x, y)
+	}
+	defer func() { compareDigests = subtle.ConstantTimeCompare }()
+
+	// Matching the first key, the last key or none takes the same comparisons
+	for _, key := range []string{"apitest", "otherkey", "hashedkey1", "hashedkey2", "unknown1"} {
+		compared = 0
+		store.Lookup(key)
+		if compared != 5 {
+			t.Errorf("Lookup(%q) compared %d keys, want all 5", key, compared)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	first, err := Hash("secret-key")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	second, err := Hash("secret-key")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if first == second {
+		t.Error("expected hashes of the same key to differ by salt")
+	}
+	if err := ValidateHash(first); err != nil {
+		t.Errorf("ValidateHash(Hash()) error = %v", err)
+	}
+
+	store := NewStore(nil, map[string]string{"partner": first})
+	if _, ok := store.Lookup("secret-key"); !ok {
+		t.Error("expected the hashed key to be valid")
+	}
+	if _, ok := store.Lookup("secret-kez"); ok {
+		t.Error("expected a different key to be invalid")
+	}
+}
+
+func TestValidateHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{"bcrypt hash", "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", false},
+		{"empty", "", true},
+		{"plaintext key", "apitest", true},
+		{"truncated hash", "$2a$10$N9qo8uLOickgx2ZMRZoMye", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHash(tt.hash); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHash(%q) error = %v, wantErr %v", tt.hash, err, tt.wantErr)
+			}
+		})
+	}
+}
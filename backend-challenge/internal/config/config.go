@@ -3,17 +3,36 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/apikey"
 )
 
 // Config holds all configuration for the application
 // Following 12-factor app principles, all config is loaded from environment variables
 type Config struct {
-	Server   ServerConfig
-	Auth     AuthConfig
-	Coupon   CouponConfig
-	LogLevel string
+	Server     ServerConfig
+	Auth       AuthConfig
+	Coupon     CouponConfig
+	Pagination PaginationConfig
+	RateLimit  RateLimitConfig
+	OrderLimit OrderLimitConfig
+	LogLevel   string
+
+	// AuditLogPath is the file order audit records are appended to; when
+	// empty they go to stderr, keeping them apart from access logs on stdout
+	AuditLogPath string
+
+	// ProductSeedPath is an optional JSON file of products replacing the
+	// built-in catalog; it is reread on POST /api/product/reload
+	ProductSeedPath string
+
+	// ZeroQuantityPolicy is "reject" to refuse orders with zero-quantity
+	// lines, or "drop" to ignore those lines
+	ZeroQuantityPolicy string
 }
 
 type ServerConfig struct {
@@ -22,18 +41,107 @@ type ServerConfig struct {
 	ReadTimeout     int
 	WriteTimeout    int
 	ShutdownTimeout int
+	ServerTiming    bool // Add Server-Timing diagnostics to order responses
+	RequestTimeout  int  // Seconds a request may run when no route timeout applies, 0 for the default
+
+	// RouteTimeouts overrides RequestTimeout per route pattern; a pattern
+	// ending in /* covers every path below it
+	RouteTimeouts map[string]time.Duration
 }
 
 type AuthConfig struct {
-	APIKeys []string // Valid API keys for authentication
+	APIKeys []string `secret:"true"` // Valid API keys for authentication
+
+	// APIKeyHashes maps key IDs to bcrypt hashes of further valid keys, so
+	// they needn't be stored in plaintext. APIKeys then has no default
+	APIKeyHashes map[string]string `secret:"true"`
+
+	// SigningSecrets maps key IDs to HMAC secrets; keys listed here must
+	// sign order requests with an X-Signature header. A hashed key's ID is
+	// the one it is listed under in APIKeyHashes, a plaintext key's is the
+	// key itself
+	SigningSecrets map[string]string `secret:"true"`
+
+	// AdminKeyIDs lists the IDs, as for SigningSecrets, of the keys allowed
+	// on admin routes such as coupon overrides. With none, admin routes
+	// refuse every key
+	AdminKeyIDs []string `secret:"true"`
 }
 
 type CouponConfig struct {
-	DataDir string // Directory containing coupon files
+	Enabled                bool           // Coupons can be switched off intentionally; codes are then ignored
+	DataDir                string         // Directory containing coupon files
+	URLs                   []string       `secret:"true"` // Optional URLs to download coupon files from into DataDir, possibly presigned
+	FileGlob               string         // Optional pattern to load every matching file in DataDir
+	ComputeOverlap         bool           // Compute code overlap statistics at load time (expensive)
+	OverlapMaxDuration     int            // Seconds allowed for the overlap computation, 0 for no limit
+	CacheCapacity          int            // Validation results held in the in-memory cache, 0 to disable it
+	NegativeCacheCapacity  int            // Separate in-memory cache for invalid results, 0 to share
+	CacheMemoryBudgetMB    int            // Heap size past which oversized caches are trimmed, 0 for strict capacity
+	CacheMemoryCheckMs     int            // Milliseconds between heap checks when CacheMemoryBudgetMB is set
+	DiskCachePath          string         // Optional on-disk overflow for the validation cache
+	DiskCacheCapacity      int            // Maximum entries in the on-disk cache
+	MaxConcurrentSearches  int            // Maximum concurrent coupon file searches
+	MaxGoroutines          int            // Goroutines the validator may run at once across all requests
+	MaxConcurrentDownloads int            // Maximum coupon files downloaded at once from URLs
+	DownloadTimeout        int            // Seconds allowed for each coupon file download
+	UseMmap                bool           // Memory-map coupon files for verification searches
+	BreakerThreshold       int            // Consecutive failed file searches before verification fails fast
+	BreakerCooldown        int            // Seconds verification fails fast before probing the files again
+	FileWeights            map[string]int // Optional weight per coupon file base name toward the 2-file rule
+	FilterBuildWorkers     int            // Goroutines hashing each file's lines during filter builds
+	TrustBloomOnError      bool           // Answer from the Bloom filters alone when the files can't be searched
+	MetricsInterval        int            // Seconds between coupon metrics updates
+	CacheTTL               int            // Seconds clients may privately cache a valid coupon GET result, 0 disables caching
+	HealthCacheBudgetMs    int            // Milliseconds the deep health check allows for a cache round trip
+	Discounts              string         // Optional JSON discount definitions replacing the built-ins
+	FeatureFlags           string         // Optional JSON mapping feature flags to the percentage of orders they are on for
+	UnmatchedMode          string         // "note" accepts valid coupons matching no discount with a note, "reject" refuses them
+	InvalidStatus          int            // Status GET coupon validation answers invalid codes with, 404 or 200
+	Expiries               string         // Optional JSON mapping coupon codes to RFC 3339 expiry times
+	ExpiriesFile           string         // Optional file holding the Expiries JSON instead
+	CheckDiscountCodes     bool           // Warn at startup about discount codes the coupon files don't validate
+	WarmPageCache          bool           // Read coupon files once after loading to prime the OS page cache
+	FilterCacheDir         string         // Optional directory Bloom filters are saved to and reused from at startup
+	FilterExpectedItems    int            // Codes per file each Bloom filter is sized for
+	FilterFPRate           float64        // Bloom filter false-positive rate at FilterExpectedItems codes
+}
+
+type PaginationConfig struct {
+	DefaultPageSize int // Page size used when the client doesn't specify a limit
+	MaxPageSize     int // Upper bound applied to client-supplied limits
+	MaxBatchSize    int // Most product IDs or coupon codes accepted by one batch request
+}
+
+type OrderLimitConfig struct {
+	MaxTotal float64 // Highest allowed order total after discounts, 0 disables the limit
+	Mode     string  // "reject" refuses over-limit orders, "flag" accepts them flagged for review
+}
+
+type RateLimitConfig struct {
+	Requests      int // Requests allowed per client per window, 0 disables rate limiting
+	WindowSeconds int // Length of the rate limit window
 }
 
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
+	routeTimeouts, err := parseRouteTimeouts(os.Getenv("ROUTE_TIMEOUTS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	fileWeights, err := parseFileWeights(os.Getenv("COUPON_FILE_WEIGHTS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// The development key is only a default while no hashed keys are set,
+	// so configuring hashes can't leave it accepted by accident
+	apiKeyHashes := getEnvAsMap("API_KEY_HASHES")
+	defaultAPIKeys := []string{"apitest"}
+	if len(apiKeyHashes) > 0 {
+		defaultAPIKeys = nil
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:            getEnv("PORT", "8080"),
@@ -41,14 +149,71 @@ func Load() (*Config, error) {
 			ReadTimeout:     getEnvAsInt("READ_TIMEOUT", 15),
 			WriteTimeout:    getEnvAsInt("WRITE_TIMEOUT", 15),
 			ShutdownTimeout: getEnvAsInt("SHUTDOWN_TIMEOUT", 30),
+			ServerTiming:    getEnvAsBool("SERVER_TIMING", false),
+			RequestTimeout:  getEnvAsInt("REQUEST_TIMEOUT", 60),
+			RouteTimeouts:   routeTimeouts,
 		},
 		Auth: AuthConfig{
-			APIKeys: getEnvAsSlice("API_KEYS", []string{"apitest"}),
+			APIKeys:        getEnvAsSlice("API_KEYS", defaultAPIKeys),
+			APIKeyHashes:   apiKeyHashes,
+			SigningSecrets: getEnvAsMap("API_KEY_SECRETS"),
+			AdminKeyIDs:    getEnvAsSlice("ADMIN_KEY_IDS", nil),
 		},
 		Coupon: CouponConfig{
-			DataDir: getEnv("COUPON_DATA_DIR", "data"),
+			Enabled:                getEnvAsBool("COUPON_ENABLED", true),
+			DataDir:                getEnv("COUPON_DATA_DIR", "data"),
+			URLs:                   getEnvAsSlice("COUPON_URLS", nil),
+			FileGlob:               getEnv("COUPON_FILE_GLOB", ""),
+			ComputeOverlap:         getEnvAsBool("COUPON_COMPUTE_OVERLAP", false),
+			OverlapMaxDuration:     getEnvAsInt("COUPON_OVERLAP_MAX_DURATION", 0),
+			CacheCapacity:          getEnvAsInt("COUPON_CACHE_CAPACITY", 10000),
+			NegativeCacheCapacity:  getEnvAsInt("COUPON_NEGATIVE_CACHE_CAPACITY", 0),
+			CacheMemoryBudgetMB:    getEnvAsInt("COUPON_CACHE_MEMORY_BUDGET_MB", 0),
+			CacheMemoryCheckMs:     getEnvAsInt("COUPON_CACHE_MEMORY_CHECK_MS", 1000),
+			DiskCachePath:          getEnv("COUPON_DISK_CACHE_PATH", ""),
+			DiskCacheCapacity:      getEnvAsInt("COUPON_DISK_CACHE_CAPACITY", 1000000),
+			MaxConcurrentSearches:  getEnvAsInt("COUPON_MAX_CONCURRENT_SEARCHES", 16),
+			MaxGoroutines:          getEnvAsInt("COUPON_MAX_GOROUTINES", 1024),
+			MaxConcurrentDownloads: getEnvAsInt("COUPON_MAX_CONCURRENT_DOWNLOADS", 4),
+			DownloadTimeout:        getEnvAsInt("COUPON_DOWNLOAD_TIMEOUT", 600),
+			UseMmap:                getEnvAsBool("COUPON_USE_MMAP", false),
+			BreakerThreshold:       getEnvAsInt("COUPON_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:        getEnvAsInt("COUPON_BREAKER_COOLDOWN", 30),
+			FileWeights:            fileWeights,
+			FilterBuildWorkers:     getEnvAsInt("COUPON_FILTER_BUILD_WORKERS", 1),
+			TrustBloomOnError:      getEnvAsBool("COUPON_TRUST_BLOOM_ON_ERROR", false),
+			MetricsInterval:        getEnvAsInt("COUPON_METRICS_INTERVAL", 15),
+			CacheTTL:               getEnvAsInt("COUPON_CACHE_TTL", 60),
+			HealthCacheBudgetMs:    getEnvAsInt("COUPON_HEALTH_CACHE_BUDGET_MS", 50),
+			Discounts:              getEnv("COUPON_DISCOUNTS", ""),
+			UnmatchedMode:          getEnv("COUPON_UNMATCHED_MODE", "note"),
+			InvalidStatus:          getEnvAsInt("COUPON_INVALID_STATUS", 404),
+			FeatureFlags:           getEnv("COUPON_FEATURE_FLAGS", ""),
+			Expiries:               getEnv("COUPON_EXPIRIES", ""),
+			ExpiriesFile:           getEnv("COUPON_EXPIRIES_FILE", ""),
+			CheckDiscountCodes:     getEnvAsBool("COUPON_CHECK_DISCOUNT_CODES", false),
+			WarmPageCache:          getEnvAsBool("COUPON_WARM_PAGE_CACHE", false),
+			FilterCacheDir:         getEnv("COUPON_FILTER_CACHE_DIR", ""),
+			FilterExpectedItems:    getEnvAsInt("COUPON_FILTER_EXPECTED_ITEMS", 100000000),
+			FilterFPRate:           getEnvAsFloat("COUPON_FILTER_FP_RATE", 0.01),
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: getEnvAsInt("DEFAULT_PAGE_SIZE", 20),
+			MaxPageSize:     getEnvAsInt("MAX_PAGE_SIZE", 100),
+			MaxBatchSize:    getEnvAsInt("MAX_BATCH_SIZE", 100),
 		},
-		LogLevel: getEnv("LOG_LEVEL", "info"),
+		RateLimit: RateLimitConfig{
+			Requests:      getEnvAsInt("RATE_LIMIT_REQUESTS", 0),
+			WindowSeconds: getEnvAsInt("RATE_LIMIT_WINDOW", 60),
+		},
+		OrderLimit: OrderLimitConfig{
+			MaxTotal: getEnvAsFloat("MAX_ORDER_TOTAL", 0),
+			Mode:     getEnv("MAX_ORDER_TOTAL_MODE", "reject"),
+		},
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		AuditLogPath:       getEnv("AUDIT_LOG_PATH", ""),
+		ProductSeedPath:    getEnv("PRODUCT_SEED_FILE", ""),
+		ZeroQuantityPolicy: getEnv("ZERO_QUANTITY_POLICY", "reject"),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -64,10 +229,133 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("PORT is required")
 	}
 
-	if len(c.Auth.APIKeys) == 0 {
+	if c.Server.RequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must not be negative")
+	}
+
+	for pattern, timeout := range c.Server.RouteTimeouts {
+		if !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("ROUTE_TIMEOUTS pattern %q must start with /", pattern)
+		}
+		if i := strings.Index(pattern, "*"); i >= 0 && (i != len(pattern)-1 || !strings.HasSuffix(pattern, "/*")) {
+			return fmt.Errorf("ROUTE_TIMEOUTS pattern %q may only end in /*", pattern)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("ROUTE_TIMEOUTS timeout for %q must be positive", pattern)
+		}
+	}
+
+	if len(c.Auth.APIKeys) == 0 && len(c.Auth.APIKeyHashes) == 0 {
 		return fmt.Errorf("at least one API key must be configured")
 	}
 
+	for id, hash := range c.Auth.APIKeyHashes {
+		if id == "" {
+			return fmt.Errorf("API_KEY_HASHES entries must be in id:hash form")
+		}
+		if slices.Contains(c.Auth.APIKeys, id) {
+			return fmt.Errorf("API_KEY_HASHES id %q is also a plaintext API key", id)
+		}
+		if err := apikey.ValidateHash(hash); err != nil {
+			return fmt.Errorf("API_KEY_HASHES entry %q: %w", id, err)
+		}
+	}
+
+	// Every request with a wrong key pays for bcrypt comparisons, so hashed
+	// keys would otherwise let anyone burn CPU
+	if len(c.Auth.APIKeyHashes) > 0 && c.RateLimit.Requests <= 0 {
+		return fmt.Errorf("RATE_LIMIT_REQUESTS must be set when API_KEY_HASHES is")
+	}
+
+	for id, secret := range c.Auth.SigningSecrets {
+		if secret == "" {
+			return fmt.Errorf("API_KEY_SECRETS entry %q must be in id:secret form", id)
+		}
+		if _, hashed := c.Auth.APIKeyHashes[id]; !hashed && !slices.Contains(c.Auth.APIKeys, id) {
+			return fmt.Errorf("API_KEY_SECRETS has a secret for an unknown API key")
+		}
+	}
+
+	for _, id := range c.Auth.AdminKeyIDs {
+		if _, hashed := c.Auth.APIKeyHashes[id]; !hashed && !slices.Contains(c.Auth.APIKeys, id) {
+			return fmt.Errorf("ADMIN_KEY_IDS lists an unknown API key")
+		}
+	}
+
+	for file, weight := range c.Coupon.FileWeights {
+		if weight <= 0 {
+			return fmt.Errorf("COUPON_FILE_WEIGHTS weight for %q must be positive", file)
+		}
+	}
+
+	if c.Coupon.FilterExpectedItems < 0 {
+		return fmt.Errorf("COUPON_FILTER_EXPECTED_ITEMS must not be negative")
+	}
+	if rate := c.Coupon.FilterFPRate; rate < 0 || rate >= 1 {
+		return fmt.Errorf("COUPON_FILTER_FP_RATE must be at least 0 and below 1, got %v", rate)
+	}
+
+	// Empty means the default, note
+	if mode := c.Coupon.UnmatchedMode; mode != "" && mode != "note" && mode != "reject" {
+		return fmt.Errorf("invalid COUPON_UNMATCHED_MODE: %s (must be note or reject)", c.Coupon.UnmatchedMode)
+	}
+
+	// Zero means the default, 404
+	if status := c.Coupon.InvalidStatus; status != 0 && status != 404 && status != 200 {
+		return fmt.Errorf("invalid COUPON_INVALID_STATUS: %d (must be 404 or 200)", status)
+	}
+
+	// Empty means the default, reject
+	if policy := c.ZeroQuantityPolicy; policy != "" && policy != "reject" && policy != "drop" {
+		return fmt.Errorf("invalid ZERO_QUANTITY_POLICY: %s (must be reject or drop)", policy)
+	}
+
+	if c.Coupon.Expiries != "" && c.Coupon.ExpiriesFile != "" {
+		return fmt.Errorf("COUPON_EXPIRIES and COUPON_EXPIRIES_FILE must not both be set")
+	}
+
+	// Saved filters are only reused for the default coupon files
+	if c.Coupon.FilterCacheDir != "" && (len(c.Coupon.URLs) > 0 || c.Coupon.FileGlob != "") {
+		return fmt.Errorf("COUPON_FILTER_CACHE_DIR cannot be used with COUPON_URLS or COUPON_FILE_GLOB")
+	}
+
+	// The deep health check only pings the coupon cache when coupons are on
+	if c.Coupon.Enabled && c.Coupon.HealthCacheBudgetMs <= 0 {
+		return fmt.Errorf("COUPON_HEALTH_CACHE_BUDGET_MS must be positive")
+	}
+
+	if c.Coupon.CacheCapacity < 0 {
+		return fmt.Errorf("COUPON_CACHE_CAPACITY must not be negative")
+	}
+
+	if c.Coupon.CacheMemoryBudgetMB < 0 {
+		return fmt.Errorf("COUPON_CACHE_MEMORY_BUDGET_MB must not be negative")
+	}
+
+	if c.Pagination.DefaultPageSize <= 0 || c.Pagination.MaxPageSize <= 0 {
+		return fmt.Errorf("DEFAULT_PAGE_SIZE and MAX_PAGE_SIZE must be positive")
+	}
+
+	if c.Pagination.DefaultPageSize > c.Pagination.MaxPageSize {
+		return fmt.Errorf("DEFAULT_PAGE_SIZE (%d) must not exceed MAX_PAGE_SIZE (%d)", c.Pagination.DefaultPageSize, c.Pagination.MaxPageSize)
+	}
+
+	if c.Pagination.MaxBatchSize < 0 {
+		return fmt.Errorf("MAX_BATCH_SIZE must not be negative")
+	}
+
+	if c.RateLimit.Requests > 0 && c.RateLimit.WindowSeconds <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW must be positive when rate limiting is enabled")
+	}
+
+	if c.OrderLimit.MaxTotal < 0 {
+		return fmt.Errorf("MAX_ORDER_TOTAL must not be negative")
+	}
+
+	if c.OrderLimit.MaxTotal > 0 && c.OrderLimit.Mode != "reject" && c.OrderLimit.Mode != "flag" {
+		return fmt.Errorf("invalid MAX_ORDER_TOTAL_MODE: %s (must be reject or flag)", c.OrderLimit.Mode)
+	}
+
 	validLogLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLogLevels[strings.ToLower(c.LogLevel)] {
 		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.LogLevel)
@@ -97,6 +385,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -104,3 +416,66 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	}
 	return strings.Split(valueStr, ",")
 }
+
+// getEnvAsMap parses comma-separated key:value pairs; a pair without a
+// colon maps to an empty value so validation can reject it
+func getEnvAsMap(key string) map[string]string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, pair := range strings.Split(valueStr, ",") {
+		k, v, _ := strings.Cut(pair, ":")
+		values[k] = v
+	}
+	return values
+}
+
+// parseRouteTimeouts parses comma-separated pattern:duration pairs such as
+// "/api/coupon/*:2s,/api/order:30s"
+func parseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	timeouts := make(map[string]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		pattern, durationStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("ROUTE_TIMEOUTS entry %q must be in pattern:duration form", pair)
+		}
+		timeout, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return nil, fmt.Errorf("ROUTE_TIMEOUTS entry %q: %w", pair, err)
+		}
+		if _, dup := timeouts[pattern]; dup {
+			return nil, fmt.Errorf("ROUTE_TIMEOUTS has more than one timeout for %q", pattern)
+		}
+		timeouts[pattern] = timeout
+	}
+	return timeouts, nil
+}
+
+// parseFileWeights parses comma-separated file:weight pairs such as
+// "couponbase1:2"
+func parseFileWeights(value string) (map[string]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	weights := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		file, weightStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("COUPON_FILE_WEIGHTS entry %q must be in file:weight form", pair)
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil {
+			return nil, fmt.Errorf("COUPON_FILE_WEIGHTS entry %q: %w", pair, err)
+		}
+		if _, dup := weights[file]; dup {
+			return nil, fmt.Errorf("COUPON_FILE_WEIGHTS has more than one weight for %q", file)
+		}
+		weights[file] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,507 @@
+package config
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestValidate_Pagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination PaginationConfig
+		wantErr    bool
+	}{
+		{"default below max", PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100}, false},
+		{"default equal to max", PaginationConfig{DefaultPageSize: 50, MaxPageSize: 50}, false},
+		{"default above max", PaginationConfig{DefaultPageSize: 200, MaxPageSize: 100}, true},
+		{"zero default", PaginationConfig{DefaultPageSize: 0, MaxPageSize: 100}, true},
+		{"negative max", PaginationConfig{DefaultPageSize: 20, MaxPageSize: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Pagination: tt.pagination,
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_SigningSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"secret for known key", map[string]string{"apitest": "s3cret"}, false},
+		{"missing secret", map[string]string{"apitest": ""}, true},
+		{"unknown key", map[string]string{"other": "s3cret"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}, SigningSecrets: tt.secrets},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_AdminKeyIDs(t *testing.T) {
+	const hash = "$2a$04$R3wqVQsidK.japteNjOPc..vqN0Irt7HyBBrcor6Rgvfqd3CoFewO"
+	tests := []struct {
+		name    string
+		ids     []string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"plaintext key", []string{"apitest"}, false},
+		{"hashed key ID", []string{"partner"}, false},
+		{"unknown key", []string{"other"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server: ServerConfig{Port: "8080"},
+				Auth: AuthConfig{
+					APIKeys:      []string{"apitest"},
+					APIKeyHashes: map[string]string{"partner": hash},
+					AdminKeyIDs:  tt.ids,
+				},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				RateLimit:  RateLimitConfig{Requests: 10, WindowSeconds: 60},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRouteTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{
+			"several routes",
+			"/api/coupon/*:2s, /api/order:30s",
+			map[string]time.Duration{"/api/coupon/*": 2 * time.Second, "/api/order": 30 * time.Second},
+			false,
+		},
+		{"missing duration", "/api/order", nil, true},
+		{"bad duration", "/api/order:soon", nil, true},
+		{"duplicate pattern", "/api/order:1s,/api/order:2s", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRouteTimeouts(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRouteTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseRouteTimeouts() = %v, want %v", got, tt.want)
+			}
+			for pattern, timeout := range tt.want {
+				if got[pattern] != timeout {
+					t.Errorf("timeout for %s = %v, want %v", pattern, got[pattern], timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFileWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"several files", "couponbase1:2, couponbase3:3", map[string]int{"couponbase1": 2, "couponbase3": 3}, false},
+		{"missing weight", "couponbase1", nil, true},
+		{"bad weight", "couponbase1:heavy", nil, true},
+		{"duplicate file", "couponbase1:2,couponbase1:3", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFileWeights(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFileWeights() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseFileWeights() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_FileWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"positive weights", map[string]int{"couponbase1": 2}, false},
+		{"zero weight", map[string]int{"couponbase1": 0}, true},
+		{"negative weight", map[string]int{"couponbase1": -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Coupon:     CouponConfig{FileWeights: tt.weights},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_RouteTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts map[string]time.Duration
+		wantErr  bool
+	}{
+		{"none", nil, false},
+		{"exact and prefix patterns", map[string]time.Duration{"/api/order": time.Second, "/api/coupon/*": time.Second}, false},
+		{"relative pattern", map[string]time.Duration{"api/order": time.Second}, true},
+		{"wildcard mid-pattern", map[string]time.Duration{"/api/*/validate": time.Second}, true},
+		{"wildcard without slash", map[string]time.Duration{"/api/coupon*": time.Second}, true},
+		{"zero timeout", map[string]time.Duration{"/api/order": 0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080", RouteTimeouts: tt.timeouts},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_OrderLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   OrderLimitConfig
+		wantErr bool
+	}{
+		{"disabled", OrderLimitConfig{}, false},
+		{"reject mode", OrderLimitConfig{MaxTotal: 10000, Mode: "reject"}, false},
+		{"flag mode", OrderLimitConfig{MaxTotal: 10000, Mode: "flag"}, false},
+		{"unknown mode", OrderLimitConfig{MaxTotal: 10000, Mode: "warn"}, true},
+		{"negative limit", OrderLimitConfig{MaxTotal: -1, Mode: "reject"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				OrderLimit: tt.limit,
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ZeroQuantityPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{"default", "", false},
+		{"reject", "reject", false},
+		{"drop", "drop", false},
+		{"unknown policy", "ignore", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:             ServerConfig{Port: "8080"},
+				Auth:               AuthConfig{APIKeys: []string{"apitest"}},
+				Pagination:         PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:           "info",
+				ZeroQuantityPolicy: tt.policy,
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_CacheCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		wantErr  bool
+	}{
+		{"disabled", 0, false},
+		{"positive", 50000, false},
+		{"negative", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Coupon:     CouponConfig{CacheCapacity: tt.capacity},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"default", 0, false},
+		{"not found", 404, false},
+		{"ok", 200, false},
+		{"other status", 410, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Coupon:     CouponConfig{InvalidStatus: tt.status},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_FilterCacheDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  CouponConfig
+		wantErr bool
+	}{
+		{"default files", CouponConfig{FilterCacheDir: "/var/cache/coupons"}, false},
+		{"URLs without a filter cache", CouponConfig{URLs: []string{"https://example.com/couponbase1"}}, false},
+		{"with URLs", CouponConfig{FilterCacheDir: "/var/cache/coupons", URLs: []string{"https://example.com/couponbase1"}}, true},
+		{"with a glob", CouponConfig{FilterCacheDir: "/var/cache/coupons", FileGlob: "couponbase*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: []string{"apitest"}},
+				Coupon:     tt.coupon,
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_FilterCacheDirWithGlob(t *testing.T) {
+	t.Setenv("COUPON_FILTER_CACHE_DIR", t.TempDir())
+	t.Setenv("COUPON_FILE_GLOB", "*.txt")
+
+	if _, err := Load(); err == nil {
+		t.Error("Load() error = nil, want an error for a filter cache with a glob")
+	}
+}
+
+func TestValidate_APIKeyHashes(t *testing.T) {
+	// The bcrypt hash of "hashedkey1" at the minimum cost
+	const hash = "$2a$04$R3wqVQsidK.japteNjOPc..vqN0Irt7HyBBrcor6Rgvfqd3CoFewO"
+	limited := RateLimitConfig{Requests: 10, WindowSeconds: 60}
+
+	tests := []struct {
+		name      string
+		keys      []string
+		hashes    map[string]string
+		secrets   map[string]string
+		rateLimit RateLimitConfig
+		wantErr   bool
+	}{
+		{"hashes without plaintext keys", nil, map[string]string{"partner": hash}, nil, limited, false},
+		{"no keys at all", nil, nil, nil, limited, true},
+		{"malformed hash", nil, map[string]string{"partner": "hashedkey1"}, nil, limited, true},
+		{"hashes without rate limiting", nil, map[string]string{"partner": hash}, nil, RateLimitConfig{}, true},
+		{"empty key ID", nil, map[string]string{"": hash}, nil, limited, true},
+		{"key ID shared with a plaintext key", []string{"partner"}, map[string]string{"partner": hash}, nil, limited, true},
+		{"secret for a hashed key's ID", nil, map[string]string{"partner": hash}, map[string]string{"partner": "s3cret"}, limited, false},
+		{"secret for a hashed key itself", nil, map[string]string{"partner": hash}, map[string]string{"hashedkey1": "s3cret"}, limited, true},
+		{"secret for a plaintext key", []string{"apitest"}, nil, map[string]string{"apitest": "s3cret"}, RateLimitConfig{}, false},
+		{"secret for an unknown key", []string{"apitest"}, map[string]string{"partner": hash}, map[string]string{"other": "s3cret"}, limited, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server:     ServerConfig{Port: "8080"},
+				Auth:       AuthConfig{APIKeys: tt.keys, APIKeyHashes: tt.hashes, SigningSecrets: tt.secrets},
+				Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+				RateLimit:  tt.rateLimit,
+				LogLevel:   "info",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_CouponCacheTTL(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"default", "", 60},
+		{"configured", "15", 15},
+		{"disabled", "0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COUPON_CACHE_TTL", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Coupon.CacheTTL != tt.want {
+				t.Errorf("CacheTTL = %d, want %d", cfg.Coupon.CacheTTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_MetricsInterval(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"default", "", 15},
+		{"configured", "5", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COUPON_METRICS_INTERVAL", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Coupon.MetricsInterval != tt.want {
+				t.Errorf("MetricsInterval = %d, want %d", cfg.Coupon.MetricsInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_HealthCacheBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"default", "", 50, false},
+		{"configured", "200", 200, false},
+		{"zero", "0", 0, true},
+		{"negative", "-5", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COUPON_HEALTH_CACHE_BUDGET_MS", tt.value)
+
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Coupon.HealthCacheBudgetMs != tt.want {
+				t.Errorf("HealthCacheBudgetMs = %d, want %d", cfg.Coupon.HealthCacheBudgetMs, tt.want)
+			}
+		})
+	}
+}
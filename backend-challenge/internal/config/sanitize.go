@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// redacted replaces the value of a secret field that is set
+const redacted = "***"
+
+// secretFieldName matches field names that are treated as secrets even
+// without a secret tag, so new credential fields are redacted by default
+var secretFieldName = regexp.MustCompile(`(?i)key|secret|token|password|credential`)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Sanitized returns the configuration as a JSON-friendly map with secrets
+// redacted. A field is secret when tagged `secret:"true"` or when its name
+// looks like a credential; set secret fields show as "***", unset ones as null
+func (c *Config) Sanitized() map[string]any {
+	sanitized, _ := sanitizeValue(reflect.ValueOf(*c)).(map[string]any)
+	return sanitized
+}
+
+// sanitizeValue converts v for display, redacting secret struct fields
+func sanitizeValue(v reflect.Value) any {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if isSecretField(field) {
+				fields[field.Name] = redactValue(v.Field(i))
+				continue
+			}
+			fields[field.Name] = sanitizeValue(v.Field(i))
+		}
+		return fields
+	case v.Kind() == reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(iter.Key().Interface())] = sanitizeValue(iter.Value())
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+// isSecretField reports whether a field's value must never be shown
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" || secretFieldName.MatchString(field.Name)
+}
+
+// redactValue hides a secret while still showing whether it is set
+func redactValue(v reflect.Value) any {
+	if v.IsZero() {
+		return nil
+	}
+	return redacted
+}
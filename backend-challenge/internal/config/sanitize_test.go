@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Sanitized(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:          "8080",
+			RouteTimeouts: map[string]time.Duration{"/api/order": 30 * time.Second},
+		},
+		Auth: AuthConfig{
+			APIKeys:        []string{"apitest", "prod-key-123"},
+			SigningSecrets: map[string]string{"apitest": "hmac-secret"},
+		},
+		Coupon: CouponConfig{
+			Enabled: true,
+			DataDir: "data",
+			URLs:    []string{"https://bucket.example/couponbase1.gz?X-Amz-Signature=abc"},
+		},
+		Pagination: PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100},
+		LogLevel:   "info",
+	}
+
+	data, err := json.Marshal(cfg.Sanitized())
+	if err != nil {
+		t.Fatalf("failed to marshal sanitized config: %v", err)
+	}
+	body := string(data)
+
+	for _, secret := range []string{"apitest", "prod-key-123", "hmac-secret", "X-Amz-Signature"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("sanitized config leaks %q: %s", secret, body)
+		}
+	}
+
+	sanitized := cfg.Sanitized()
+	auth := sanitized["Auth"].(map[string]any)
+	if auth["APIKeys"] != redacted || auth["SigningSecrets"] != redacted {
+		t.Errorf("expected auth secrets to be redacted, got %v", auth)
+	}
+
+	for path, want := range map[string]any{
+		"Server.Port":            "8080",
+		"Coupon.DataDir":         "data",
+		"Coupon.Enabled":         true,
+		"Pagination.MaxPageSize": 100,
+		"LogLevel":               "info",
+	} {
+		if got := lookup(sanitized, path); got != want {
+			t.Errorf("%s = %v, want %v", path, got, want)
+		}
+	}
+	if got := lookup(sanitized, "Server.RouteTimeouts").(map[string]any)["/api/order"]; got != "30s" {
+		t.Errorf("route timeout = %v, want 30s", got)
+	}
+}
+
+func TestSanitizeValue_SecretFields(t *testing.T) {
+	// Fields are secret by tag or by a credential-like name; unset secrets
+	// show as null so operators can tell they are missing
+	type example struct {
+		Host          string
+		WebhookToken  string
+		DBPassword    string
+		Passphrase    string `secret:"true"`
+		EmptySecret   string `secret:"true"`
+		internalToken string
+	}
+
+	got := sanitizeValue(reflect.ValueOf(example{
+		Host:          "db.internal",
+		WebhookToken:  "tok",
+		DBPassword:    "pw",
+		Passphrase:    "phrase",
+		internalToken: "hidden",
+	})).(map[string]any)
+
+	want := map[string]any{
+		"Host":         "db.internal",
+		"WebhookToken": redacted,
+		"DBPassword":   redacted,
+		"Passphrase":   redacted,
+		"EmptySecret":  nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sanitizeValue() = %v, want %v", got, want)
+	}
+}
+
+// lookup follows a dotted path through nested sanitized maps
+func lookup(m map[string]any, path string) any {
+	var current any = m
+	for _, key := range strings.Split(path, ".") {
+		current = current.(map[string]any)[key]
+	}
+	return current
+}
@@ -0,0 +1,117 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSearchUnavailable is returned while the file-search circuit breaker is
+// open: recent searches kept failing, so verification fails fast instead of
+// queueing more work on a struggling disk
+var ErrSearchUnavailable = errors.New("coupon file search unavailable")
+
+// Default circuit breaker settings for the file-search tier
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// Circuit breaker states, as reported in the validator stats
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// searchBreaker is a consecutive-failure circuit breaker around file searches
+// After threshold failures in a row it opens for cooldown; the first search
+// afterwards is let through as a probe, closing the breaker on success and
+// reopening it on failure
+type searchBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time // replaceable in tests
+
+	mu       sync.Mutex
+	failures int
+	state    string
+	openedAt time.Time
+}
+
+// newSearchBreaker creates a closed breaker
+func newSearchBreaker(threshold int, cooldown time.Duration) *searchBreaker {
+	return &searchBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     breakerClosed,
+	}
+}
+
+// allow reports whether a search may run. Once the cooldown has passed an
+// open breaker lets a single probe through and turns half-open
+func (b *searchBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// A probe is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an allowed search
+// Cancellation says nothing about the disk, so it only frees the probe slot;
+// deadlines and read errors count as failures. Searches cut short by their
+// caller's context are passed to release instead
+func (b *searchBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case err == nil:
+		b.failures = 0
+		b.state = breakerClosed
+	case errors.Is(err, context.Canceled):
+		b.releaseLocked()
+	default:
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.threshold {
+			b.state = breakerOpen
+			b.openedAt = b.now()
+		}
+	}
+}
+
+// release ends an allowed search without judging the disk, for searches
+// whose caller gave up or ran out of time; a probe's slot is freed
+func (b *searchBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.releaseLocked()
+}
+
+// releaseLocked frees the probe slot. Callers hold b.mu
+func (b *searchBreaker) releaseLocked() {
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// State returns the current breaker state
+func (b *searchBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,211 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for breaker cooldowns
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestSearchBreaker(t *testing.T) {
+	errRead := errors.New("read failed")
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := newSearchBreaker(2, time.Minute)
+	b.now = clock.Now
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		outcome   error // recorded when the search is allowed
+		wantAllow bool
+		wantState string
+	}{
+		{"first failure", 0, errRead, true, breakerClosed},
+		{"success resets the count", 0, nil, true, breakerClosed},
+		{"failure after reset", 0, errRead, true, breakerClosed},
+		{"threshold reached", 0, context.DeadlineExceeded, true, breakerOpen},
+		{"fails fast while open", 30 * time.Second, nil, false, breakerOpen},
+		{"cancelled probe frees the slot", 30 * time.Second, context.Canceled, true, breakerOpen},
+		{"failed probe reopens", 0, errRead, true, breakerOpen},
+		{"cooldown restarts after failed probe", 30 * time.Second, nil, false, breakerOpen},
+		{"successful probe closes", 30 * time.Second, nil, true, breakerClosed},
+	}
+
+	for _, step := range steps {
+		clock.now = clock.now.Add(step.advance)
+		allowed := b.allow()
+		if allowed != step.wantAllow {
+			t.Fatalf("%s: allow() = %v, want %v", step.name, allowed, step.wantAllow)
+		}
+		if allowed {
+			b.record(step.outcome)
+		}
+		if got := b.State(); got != step.wantState {
+			t.Fatalf("%s: state = %s, want %s", step.name, got, step.wantState)
+		}
+	}
+}
+
+func TestSearchBreaker_SingleProbe(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := newSearchBreaker(1, time.Minute)
+	b.now = clock.Now
+
+	b.record(errors.New("read failed"))
+	clock.now = clock.now.Add(time.Minute)
+
+	if !b.allow() {
+		t.Fatal("expected a probe after the cooldown")
+	}
+	if b.allow() {
+		t.Error("expected only one probe while half-open")
+	}
+}
+
+func TestValidator_SearchBreakerIgnoresCallerContext(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// Searches block until their caller gives up, like a slow disk meeting
+	// a short route timeout or a client disconnecting
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	// Searches outlive callers that gave up, so wait before restoring the hook
+	defer func() {
+		validator.goroutines.wait()
+		searchFile = searchFileForCoupon
+	}()
+
+	expired := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), time.Millisecond)
+	}
+	cancelled := func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx, cancel
+	}
+	for i, newCtx := range []func() (context.Context, context.CancelFunc){expired, cancelled, expired, cancelled, expired} {
+		ctx, cancel := newCtx()
+		if _, err := validator.Verify(ctx, "VALIDABC"); err == nil || errors.Is(err, ErrSearchUnavailable) {
+			t.Fatalf("Verify() %d error = %v, want the caller's context error", i, err)
+		}
+		cancel()
+	}
+
+	if got := validator.GetStats()["searchBreaker"]; got != breakerClosed {
+		t.Errorf("searchBreaker = %v after callers gave up, want %s", got, breakerClosed)
+	}
+}
+
+func TestValidator_SearchBreaker(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{BreakerThreshold: 3, BreakerCooldown: time.Minute, CacheCapacity: DefaultCacheCapacity})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	validator.breaker.now = clock.Now
+
+	var failing atomic.Bool
+	var searches atomic.Int64
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		searches.Add(1)
+		if failing.Load() {
+			return false, errors.New("disk unavailable")
+		}
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	// Drive failures with distinct codes, since failed results aren't cached
+	failing.Store(true)
+	for _, code := range []string{"VALIDABC", "TESTCODE", "SPECIAL9"} {
+		if _, err := validator.Verify(context.Background(), code); err == nil || errors.Is(err, ErrSearchUnavailable) {
+			t.Fatalf("Verify(%s) error = %v, want the search error", code, err)
+		}
+	}
+	if got := validator.GetStats()["searchBreaker"]; got != breakerOpen {
+		t.Fatalf("searchBreaker = %v, want %s", got, breakerOpen)
+	}
+
+	t.Run("fails fast while open", func(t *testing.T) {
+		before := searches.Load()
+		valid, err := validator.Verify(context.Background(), "VALIDABC")
+		if valid || !errors.Is(err, ErrSearchUnavailable) {
+			t.Errorf("Verify() = %v, %v, want false, %v", valid, err, ErrSearchUnavailable)
+		}
+		if searches.Load() != before {
+			t.Error("expected no file search while the breaker is open")
+		}
+	})
+
+	t.Run("Bloom negatives still resolve", func(t *testing.T) {
+		for _, code := range []string{"NOTFOUND", "ONLYONE1"} {
+			valid, err := validator.Verify(context.Background(), code)
+			if valid || err != nil {
+				t.Errorf("Verify(%s) = %v, %v, want false, nil", code, valid, err)
+			}
+		}
+	})
+
+	t.Run("recovers after cooldown", func(t *testing.T) {
+		failing.Store(false)
+		clock.now = clock.now.Add(time.Minute)
+
+		valid, err := validator.Verify(context.Background(), "VALIDABC")
+		if !valid || err != nil {
+			t.Errorf("Verify() = %v, %v, want true, nil", valid, err)
+		}
+		if got := validator.GetStats()["searchBreaker"]; got != breakerClosed {
+			t.Errorf("searchBreaker = %v, want %s", got, breakerClosed)
+		}
+		if !validator.IsValid(context.Background(), "TESTCODE") {
+			t.Error("expected TESTCODE to be valid once the breaker closed")
+		}
+	})
+}
+
+func TestValidator_InspectUsesSearchBreaker(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		return false, errors.New("disk failure")
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	// Failed inspections open the breaker like failed validations
+	for i := 0; i < 2; i++ {
+		if _, err := validator.Inspect(context.Background(), "VALIDABC"); err == nil || errors.Is(err, ErrSearchUnavailable) {
+			t.Fatalf("Inspect() %d error = %v, want the search error", i, err)
+		}
+	}
+	if _, err := validator.Inspect(context.Background(), "VALIDABC"); !errors.Is(err, ErrSearchUnavailable) {
+		t.Errorf("Inspect() with the breaker open error = %v, want ErrSearchUnavailable", err)
+	}
+	if _, err := validator.Verify(context.Background(), "TESTCODE"); !errors.Is(err, ErrSearchUnavailable) {
+		t.Errorf("Verify() after failed inspections error = %v, want ErrSearchUnavailable", err)
+	}
+}
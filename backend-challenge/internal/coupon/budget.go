@@ -0,0 +1,59 @@
+package coupon
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMaxGoroutines is the default budget of goroutines the validator
+// may have running at once across all requests
+const DefaultMaxGoroutines = 1024
+
+// goroutineBudget bounds the goroutines the validator spawns across all
+// requests, so a flood of validations can't turn into a goroutine explosion
+type goroutineBudget struct {
+	slots  chan struct{}
+	active atomic.Int64
+	done   sync.WaitGroup
+}
+
+// newGoroutineBudget creates a budget of max goroutines
+func newGoroutineBudget(max int) *goroutineBudget {
+	return &goroutineBudget{slots: make(chan struct{}, max)}
+}
+
+// goOrRun runs fn on a new goroutine when the budget has room and on the
+// calling goroutine otherwise. It never waits for a slot, so work spawned
+// from budgeted goroutines (a shared search fanning out per file) can't
+// deadlock on a budget its parents hold; callers must therefore not rely on
+// fn running concurrently with them
+func (b *goroutineBudget) goOrRun(fn func()) {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		fn()
+		return
+	}
+
+	b.active.Add(1)
+	b.done.Add(1)
+	go func() {
+		defer func() {
+			b.active.Add(-1)
+			<-b.slots
+			b.done.Done()
+		}()
+		fn()
+	}()
+}
+
+// Active returns how many budgeted goroutines are running
+func (b *goroutineBudget) Active() int64 {
+	return b.active.Load()
+}
+
+// wait blocks until every budgeted goroutine has returned, including ones
+// still finishing a search their caller gave up on
+func (b *goroutineBudget) wait() {
+	b.done.Wait()
+}
@@ -0,0 +1,112 @@
+package coupon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGoroutineBudget_RunsInlineWhenSpent(t *testing.T) {
+	budget := newGoroutineBudget(2)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(2)
+	for i := 0; i < 2; i++ {
+		budget.goOrRun(func() {
+			started.Done()
+			<-release
+		})
+	}
+	started.Wait()
+	if got := budget.Active(); got != 2 {
+		t.Fatalf("Active() = %d, want 2", got)
+	}
+
+	// With the budget spent, fn runs before goOrRun returns
+	ran := false
+	budget.goOrRun(func() { ran = true })
+	if !ran {
+		t.Error("expected fn to run on the calling goroutine")
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for budget.Active() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := budget.Active(); got != 0 {
+		t.Errorf("Active() after release = %d, want 0", got)
+	}
+}
+
+func TestValidator_MaxGoroutines(t *testing.T) {
+	const budget = 4
+
+	// Many distinct codes in every file, so each validation searches files
+	dir := t.TempDir()
+	var codes []string
+	for i := 0; i < 200; i++ {
+		codes = append(codes, fmt.Sprintf("FLOOD%04d", i))
+	}
+	content := []byte(strings.Join(codes, "\n") + "\n")
+	var paths []string
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("couponbase%d", i))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("failed to write coupon file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	validator := NewValidatorWithOptions(ValidatorOptions{MaxGoroutines: budget})
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), paths); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	var peak atomic.Int64
+	original := searchFile
+	searchFile = func(ctx context.Context, filePath, code string) (bool, error) {
+		for {
+			active, seen := validator.goroutines.Active(), peak.Load()
+			if active <= seen || peak.CompareAndSwap(seen, active) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return original(ctx, filePath, code)
+	}
+	defer func() { searchFile = original }()
+
+	// Every validation returns only after its searches finished, so the
+	// hook is no longer in use once they all have
+	var wg sync.WaitGroup
+	var invalid atomic.Int64
+	for _, code := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !validator.IsValid(context.Background(), code) {
+				invalid.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := invalid.Load(); n != 0 {
+		t.Errorf("%d valid codes reported invalid", n)
+	}
+	if got := peak.Load(); got == 0 || got > budget {
+		t.Errorf("peak validator goroutines = %d, want 1..%d", got, budget)
+	}
+	if got := validator.GetStats()["goroutineBudget"]; got != budget {
+		t.Errorf("goroutineBudget = %v, want %d", got, budget)
+	}
+}
@@ -0,0 +1,182 @@
+package coupon
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// diskCache is a bounded, append-only on-disk store of validation results
+// used as a second-level cache for entries evicted from the in-memory LRU
+//
+// Each record is a "CODE\t1\n" (valid) or "CODE\t0\n" (invalid) line. Only the
+// key -> offset index lives in memory; values are read back from disk. When the
+// log holds more than twice the live entries it is compacted in place.
+type diskCache struct {
+	path     string
+	file     *os.File
+	capacity int
+	index    map[string]int64 // key -> offset of its latest record
+	order    *list.List       // keys in insertion order for FIFO eviction
+	elems    map[string]*list.Element
+	records  int   // records in the log, including superseded ones
+	size     int64 // current end of the log
+	mu       sync.Mutex
+}
+
+// openDiskCache creates (or truncates) the cache file at path
+// Results are not reused across restarts since coupon data may have changed
+func openDiskCache(path string, capacity int) (*diskCache, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening disk cache: %w", err)
+	}
+
+	return &diskCache{
+		path:     path,
+		file:     file,
+		capacity: capacity,
+		index:    make(map[string]int64),
+		order:    list.New(),
+		elems:    make(map[string]*list.Element),
+	}, nil
+}
+
+// Get retrieves a value from the disk cache
+func (c *diskCache) Get(key string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	offset, exists := c.index[key]
+	if !exists {
+		return false, false
+	}
+
+	// Record is key + tab + flag + newline
+	buf := make([]byte, len(key)+3)
+	if _, err := c.file.ReadAt(buf, offset); err != nil {
+		return false, false
+	}
+
+	return buf[len(key)+1] == '1', true
+}
+
+// Set appends a value to the disk cache, evicting the oldest entry when full
+func (c *diskCache) Set(key string, valid bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.index[key]; !exists && len(c.index) >= c.capacity {
+		oldest := c.order.Front()
+		if oldest != nil {
+			c.remove(oldest.Value.(string))
+		}
+	}
+
+	offset, err := c.append(key, valid)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := c.index[key]; !exists {
+		c.elems[key] = c.order.PushBack(key)
+	}
+	c.index[key] = offset
+
+	if c.records > 2*c.capacity {
+		return c.compact()
+	}
+	return nil
+}
+
+// Len returns the number of live entries
+func (c *diskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.index)
+}
+
+// Clear removes all entries from the disk cache
+func (c *diskCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating disk cache: %w", err)
+	}
+
+	c.index = make(map[string]int64)
+	c.elems = make(map[string]*list.Element)
+	c.order.Init()
+	c.records = 0
+	c.size = 0
+	return nil
+}
+
+// Close closes and removes the cache file
+func (c *diskCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(c.path)
+}
+
+// remove drops a key from the index; its record is reclaimed on compaction
+func (c *diskCache) remove(key string) {
+	delete(c.index, key)
+	if elem, exists := c.elems[key]; exists {
+		c.order.Remove(elem)
+		delete(c.elems, key)
+	}
+}
+
+// append writes a record at the end of the log and returns its offset
+func (c *diskCache) append(key string, valid bool) (int64, error) {
+	flag := "0"
+	if valid {
+		flag = "1"
+	}
+
+	offset := c.size
+	n, err := c.file.WriteAt([]byte(key+"\t"+flag+"\n"), offset)
+	if err != nil {
+		return 0, fmt.Errorf("writing disk cache: %w", err)
+	}
+
+	c.size += int64(n)
+	c.records++
+	return offset, nil
+}
+
+// compact rewrites the log keeping only the latest record of each live key
+func (c *diskCache) compact() error {
+	live := make(map[string]bool, len(c.index))
+	for key, offset := range c.index {
+		buf := make([]byte, len(key)+3)
+		if _, err := c.file.ReadAt(buf, offset); err != nil {
+			return fmt.Errorf("reading disk cache: %w", err)
+		}
+		live[key] = buf[len(key)+1] == '1'
+	}
+
+	if err := c.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating disk cache: %w", err)
+	}
+	c.size = 0
+	c.records = 0
+
+	// Rewrite in insertion order so eviction order is preserved
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		key := elem.Value.(string)
+		offset, err := c.append(key, live[key])
+		if err != nil {
+			return err
+		}
+		c.index[key] = offset
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package coupon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskCache_SetGet(t *testing.T) {
+	cache, err := openDiskCache(filepath.Join(t.TempDir(), "cache.log"), 3)
+	if err != nil {
+		t.Fatalf("failed to open disk cache: %v", err)
+	}
+	defer cache.Close()
+
+	if err := cache.Set("VALIDABC", true); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := cache.Set("INVALID1", false); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if valid, found := cache.Get("VALIDABC"); !found || !valid {
+		t.Errorf("Get(VALIDABC) = %v, %v; want true, true", valid, found)
+	}
+	if valid, found := cache.Get("INVALID1"); !found || valid {
+		t.Errorf("Get(INVALID1) = %v, %v; want false, true", valid, found)
+	}
+	if _, found := cache.Get("MISSING1"); found {
+		t.Error("expected miss for unknown key")
+	}
+
+	// Updating a key keeps the latest value
+	if err := cache.Set("INVALID1", true); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if valid, _ := cache.Get("INVALID1"); !valid {
+		t.Error("expected updated value to be returned")
+	}
+}
+
+func TestDiskCache_CapacityAndCompaction(t *testing.T) {
+	cache, err := openDiskCache(filepath.Join(t.TempDir(), "cache.log"), 3)
+	if err != nil {
+		t.Fatalf("failed to open disk cache: %v", err)
+	}
+	defer cache.Close()
+
+	// Write enough records to force eviction and several compactions
+	for i := 0; i < 20; i++ {
+		if err := cache.Set(fmt.Sprintf("CODE%04d", i), i%2 == 0); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	if cache.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", cache.Len())
+	}
+	if cache.records > 2*cache.capacity {
+		t.Errorf("log holds %d records, expected compaction to keep it <= %d", cache.records, 2*cache.capacity)
+	}
+
+	// Oldest entries are evicted, newest survive compaction with their values
+	if _, found := cache.Get("CODE0000"); found {
+		t.Error("expected oldest entry to be evicted")
+	}
+	for i := 17; i < 20; i++ {
+		valid, found := cache.Get(fmt.Sprintf("CODE%04d", i))
+		if !found || valid != (i%2 == 0) {
+			t.Errorf("Get(CODE%04d) = %v, %v; want %v, true", i, valid, found, i%2 == 0)
+		}
+	}
+}
+
+func TestValidator_DiskCacheSpillAndReload(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	cachePath := filepath.Join(t.TempDir(), "coupon-cache.log")
+	validator := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity, DiskCachePath: cachePath})
+	defer validator.Close()
+
+	// Shrink the in-memory LRU so entries spill quickly
+	validator.cache.capacity = 2
+
+	ctx := context.Background()
+	if err := validator.LoadFromFiles(ctx, []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// Third lookup evicts VALIDABC from memory to disk
+	for _, code := range []string{"VALIDABC", "TESTCODE", "SPECIAL9"} {
+		if !validator.IsValid(ctx, code) {
+			t.Fatalf("expected %s to be valid", code)
+		}
+	}
+
+	stats := validator.GetStats()
+	if stats["diskCacheSize"] != 1 {
+		t.Fatalf("diskCacheSize = %v, want 1", stats["diskCacheSize"])
+	}
+
+	// Remove the coupon files so only the disk cache can answer
+	for _, f := range []string{file1, file2, file3} {
+		if err := os.Remove(f); err != nil {
+			t.Fatalf("failed to remove %s: %v", f, err)
+		}
+	}
+
+	if !validator.IsValid(ctx, "VALIDABC") {
+		t.Error("expected VALIDABC to be reloaded from the disk cache")
+	}
+
+	// The disk hit is promoted back into the in-memory cache
+	if _, found := validator.cache.Get("VALIDABC"); !found {
+		t.Error("expected disk cache hit to be promoted to the LRU cache")
+	}
+}
+
+func TestValidator_DiskCacheDisabledByDefault(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	if _, ok := validator.GetStats()["diskCacheSize"]; ok {
+		t.Error("expected no disk cache stats when disabled")
+	}
+	if err := validator.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package coupon
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// urlSource tracks a downloaded coupon file and the validators needed for
+// conditional re-downloads
+type urlSource struct {
+	url          string
+	localPath    string
+	etag         string
+	lastModified string
+}
+
+// LoadFromURLs downloads coupon files into dir and builds Bloom filters from them
+// Gzipped sources (*.gz) are decompressed while downloading, since the
+// verification search needs plain text files on disk. At most
+// MaxConcurrentDownloads files are downloaded at once
+func (v *Validator) LoadFromURLs(ctx context.Context, urls []string, dir string) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("no URLs provided")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating download dir: %w", err)
+	}
+
+	sources := make([]urlSource, len(urls))
+	filePaths := make([]string, len(urls))
+	for i, rawURL := range urls {
+		localPath, err := localPathForURL(dir, rawURL)
+		if err != nil {
+			return fmt.Errorf("URL %d: %w", i+1, err)
+		}
+		// Concurrent downloads must never write the same file
+		if j := slices.Index(filePaths[:i], localPath); j >= 0 {
+			return fmt.Errorf("URLs %d and %d both download to %s", j+1, i+1, localPath)
+		}
+
+		sources[i] = urlSource{url: rawURL, localPath: localPath}
+		filePaths[i] = localPath
+	}
+
+	if _, err := v.downloadSources(ctx, sources); err != nil {
+		return err
+	}
+
+	files, err := openFiles(filePaths)
+	if err != nil {
+		return err
+	}
+	return v.load(ctx, files, sources, v.buildFilters)
+}
+
+// ReloadFromURLs re-downloads coupon files using conditional requests and
+// rebuilds the filters of files that changed, returning how many were rebuilt
+// Sources answering 304 Not Modified are neither downloaded nor rebuilt
+func (v *Validator) ReloadFromURLs(ctx context.Context) (int, error) {
+	v.mu.RLock()
+	sources := make([]urlSource, len(v.sources))
+	copy(sources, v.sources)
+	v.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("no coupon URLs loaded")
+	}
+
+	downloaded, err := v.downloadSources(ctx, sources)
+	if err != nil {
+		return 0, err
+	}
+
+	v.mu.Lock()
+	v.sources = sources
+	v.publishStatsLocked()
+	v.mu.Unlock()
+
+	if downloaded == 0 {
+		return 0, nil
+	}
+
+	// Checksums decide which downloads actually changed
+	return v.Reload(ctx)
+}
+
+// downloadSources downloads every source, at most MaxConcurrentDownloads at
+// once, and returns how many had new content. The first failure cancels the
+// downloads still running
+func (v *Validator) downloadSources(ctx context.Context, sources []urlSource) (int, error) {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(v.options.MaxConcurrentDownloads)
+
+	var downloaded atomic.Int64
+	for i := range sources {
+		g.Go(func() error {
+			modified, err := downloadSource(gctx, v.options.HTTPClient, &sources[i])
+			if err != nil {
+				return fmt.Errorf("downloading URL %d: %w", i+1, err)
+			}
+			if modified {
+				downloaded.Add(1)
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+	return int(downloaded.Load()), nil
+}
+
+// downloadSource fetches src into its local path, sending the validators from
+// the previous download, and reports whether new content was written
+func downloadSource(ctx context.Context, client *http.Client, src *urlSource) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+
+	// Only send validators if the local copy still exists
+	if _, err := os.Stat(src.localPath); err == nil {
+		if src.etag != "" {
+			req.Header.Set("If-None-Match", src.etag)
+		}
+		if src.lastModified != "" {
+			req.Header.Set("If-Modified-Since", src.lastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("requesting %s: %w", src.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, src.url)
+	}
+
+	var body io.Reader = resp.Body
+	if isGzipURL(src.url) {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("opening gzip stream: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	if err := writeFileAtomic(src.localPath, body); err != nil {
+		return false, err
+	}
+
+	src.etag = resp.Header.Get("ETag")
+	src.lastModified = resp.Header.Get("Last-Modified")
+	return true, nil
+}
+
+// writeFileAtomic streams r into a temp file and renames it over path, so
+// concurrent verification searches never see a partially written file
+func writeFileAtomic(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".download-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing download: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing download: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+// localPathForURL returns where a URL's decompressed contents are stored
+func localPathForURL(dir, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	name := strings.TrimSuffix(path.Base(u.Path), ".gz")
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("cannot derive file name from URL %q", rawURL)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// isGzipURL reports whether a URL points at a gzipped file
+func isGzipURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(u.Path, ".gz")
+}
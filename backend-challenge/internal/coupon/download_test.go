@@ -0,0 +1,305 @@
+package coupon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// couponFileServer serves coupon files with ETag support and counts requests
+type couponFileServer struct {
+	mu          sync.Mutex
+	files       map[string][]byte
+	requests    int
+	notModified int
+}
+
+func (s *couponFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	content, ok := s.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(content))
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write(content)
+}
+
+func (s *couponFileServer) setFile(path string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = content
+}
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to gzip fixture: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to gzip fixture: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidator_LoadFromURLs_ConditionalReload(t *testing.T) {
+	fileServer := &couponFileServer{files: map[string][]byte{
+		"/couponbase1.gz": gzipBytes(t, "VALIDABC\nTESTCODE\nCOUPON01\n"),
+		"/couponbase2":    []byte("VALIDABC\nTESTCODE\nSPECIAL9\n"),
+		"/couponbase3":    []byte("VALIDABC\nSPECIAL9\nONLYONE1\n"),
+	}}
+	srv := httptest.NewServer(fileServer)
+	defer srv.Close()
+
+	urls := []string{
+		srv.URL + "/couponbase1.gz",
+		srv.URL + "/couponbase2",
+		srv.URL + "/couponbase3",
+	}
+
+	ctx := context.Background()
+	validator := NewValidator()
+	if err := validator.LoadFromURLs(ctx, urls, t.TempDir()); err != nil {
+		t.Fatalf("LoadFromURLs() error = %v", err)
+	}
+
+	// Gzipped and plain sources are both searchable
+	if !validator.IsValid(ctx, "TESTCODE") {
+		t.Error("expected TESTCODE (in gzipped file 1 and file 2) to be valid")
+	}
+	if validator.IsValid(ctx, "ONLYONE1") {
+		t.Error("expected ONLYONE1 to be invalid")
+	}
+
+	// Unchanged sources answer 304 and their filters are reused
+	rebuilt, err := validator.ReloadFromURLs(ctx)
+	if err != nil {
+		t.Fatalf("ReloadFromURLs() error = %v", err)
+	}
+	if rebuilt != 0 {
+		t.Errorf("ReloadFromURLs() rebuilt %d filters, want 0", rebuilt)
+	}
+	if fileServer.notModified != 3 {
+		t.Errorf("expected 3 conditional 304 responses, got %d", fileServer.notModified)
+	}
+	if builds := validator.GetStats()["filterBuilds"]; builds != int64(3) {
+		t.Errorf("filterBuilds = %v, want 3 (no rebuild on 304)", builds)
+	}
+
+	// A changed source is downloaded and only its filter rebuilt
+	fileServer.setFile("/couponbase2", []byte("VALIDABC\nTESTCODE\nSPECIAL9\nONLYONE1\n"))
+
+	rebuilt, err = validator.ReloadFromURLs(ctx)
+	if err != nil {
+		t.Fatalf("ReloadFromURLs() error = %v", err)
+	}
+	if rebuilt != 1 {
+		t.Errorf("ReloadFromURLs() rebuilt %d filters, want 1", rebuilt)
+	}
+	if builds := validator.GetStats()["filterBuilds"]; builds != int64(4) {
+		t.Errorf("filterBuilds = %v, want 4", builds)
+	}
+	if !validator.IsValid(ctx, "ONLYONE1") {
+		t.Error("expected ONLYONE1 to be valid after reload")
+	}
+}
+
+func TestValidator_LoadFromURLs_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	validator := NewValidator()
+
+	if err := validator.LoadFromURLs(context.Background(), nil, t.TempDir()); err == nil {
+		t.Error("expected error for empty URL list, got nil")
+	}
+
+	if err := validator.LoadFromURLs(context.Background(), []string{srv.URL + "/missing"}, t.TempDir()); err == nil {
+		t.Error("expected error for 404 response, got nil")
+	}
+
+	if _, err := validator.ReloadFromURLs(context.Background()); err == nil {
+		t.Error("expected error when reloading without URLs, got nil")
+	}
+}
+
+// concurrencyCounter wraps a handler and records the peak number of
+// requests it served at once
+type concurrencyCounter struct {
+	next   http.Handler
+	active atomic.Int64
+	peak   atomic.Int64
+}
+
+func (c *concurrencyCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	// Hold the request long enough for concurrent downloads to overlap
+	time.Sleep(20 * time.Millisecond)
+	c.next.ServeHTTP(w, r)
+}
+
+func TestValidator_LoadFromURLs_MaxConcurrentDownloads(t *testing.T) {
+	files := map[string][]byte{}
+	urls := make([]string, 0, 5)
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("/couponbase%d", i)
+		files[name] = []byte("VALIDABC\nTESTCODE\n")
+		urls = append(urls, name)
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		wantPeak int64
+	}{
+		{"one at a time", 1, 1},
+		{"bounded", 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := &concurrencyCounter{next: &couponFileServer{files: files}}
+			srv := httptest.NewServer(counter)
+			defer srv.Close()
+
+			fullURLs := make([]string, len(urls))
+			for i, u := range urls {
+				fullURLs[i] = srv.URL + u
+			}
+
+			validator := NewValidatorWithOptions(ValidatorOptions{MaxConcurrentDownloads: tt.limit})
+			if err := validator.LoadFromURLs(context.Background(), fullURLs, t.TempDir()); err != nil {
+				t.Fatalf("LoadFromURLs() error = %v", err)
+			}
+
+			if peak := counter.peak.Load(); peak > tt.wantPeak {
+				t.Errorf("peak concurrent downloads = %d, want <= %d", peak, tt.wantPeak)
+			}
+			if !validator.IsValid(context.Background(), "VALIDABC") {
+				t.Error("expected VALIDABC to be valid after loading")
+			}
+		})
+	}
+}
+
+func TestValidator_LoadFromURLs_DuplicateLocalPath(t *testing.T) {
+	validator := NewValidator()
+	urls := []string{"http://a.example/couponbase1.gz", "http://b.example/couponbase1"}
+
+	if err := validator.LoadFromURLs(context.Background(), urls, t.TempDir()); err == nil {
+		t.Error("expected error for URLs sharing a local file, got nil")
+	}
+}
+
+func TestValidator_LoadFromURLs_HTTPClient(t *testing.T) {
+	files := map[string][]byte{
+		"/couponbase1": []byte("VALIDABC\nTESTCODE\n"),
+		"/couponbase2": []byte("VALIDABC\n"),
+	}
+	// A self-signed TLS server only the server's own client trusts
+	srv := httptest.NewUnstartedServer(&couponFileServer{files: files})
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	srv.StartTLS()
+	defer srv.Close()
+	urls := []string{srv.URL + "/couponbase1", srv.URL + "/couponbase2"}
+
+	tests := []struct {
+		name    string
+		client  *http.Client
+		wantErr bool
+	}{
+		{"default client rejects the certificate", nil, true},
+		{"injected client is used", srv.Client(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidatorWithOptions(ValidatorOptions{HTTPClient: tt.client})
+			defer validator.Close()
+
+			err := validator.LoadFromURLs(context.Background(), urls, t.TempDir())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFromURLs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !validator.IsValid(context.Background(), "VALIDABC") {
+				t.Error("expected VALIDABC to be valid after loading")
+			}
+		})
+	}
+}
+
+func TestNewValidatorWithOptions_DownloadTimeout(t *testing.T) {
+	validator := NewValidatorWithOptions(ValidatorOptions{DownloadTimeout: time.Minute})
+	if got := validator.options.HTTPClient.Timeout; got != time.Minute {
+		t.Errorf("default client timeout = %v, want %v", got, time.Minute)
+	}
+
+	validator = NewValidator()
+	if got := validator.options.HTTPClient.Timeout; got != DefaultDownloadTimeout {
+		t.Errorf("default client timeout = %v, want %v", got, DefaultDownloadTimeout)
+	}
+}
+
+func TestValidator_GetStats_FileLastModified(t *testing.T) {
+	newest := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	oldest := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+	modified := map[string]time.Time{"/couponbase1": newest, "/couponbase2": oldest}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified[r.URL.Path].Format(http.TimeFormat))
+		_, _ = w.Write([]byte("VALIDABC\n"))
+	}))
+	defer srv.Close()
+
+	validator := NewValidator()
+	defer validator.Close()
+	urls := []string{srv.URL + "/couponbase1", srv.URL + "/couponbase2"}
+	if err := validator.LoadFromURLs(context.Background(), urls, t.TempDir()); err != nil {
+		t.Fatalf("LoadFromURLs() error = %v", err)
+	}
+
+	stats := validator.GetStats()
+	got, _ := stats["fileLastModified"].([]string)
+	want := []string{"2026-03-02T10:00:00Z", "2026-03-01T08:30:00Z"}
+	if !slices.Equal(got, want) {
+		t.Errorf("fileLastModified = %v, want %v", got, want)
+	}
+
+	age, _ := stats["dataAge"].(int64)
+	if wantAge := int64(time.Since(oldest).Seconds()); age < wantAge-5 || age > wantAge+5 {
+		t.Errorf("dataAge = %d, want about %d", age, wantAge)
+	}
+}
@@ -0,0 +1,52 @@
+package coupon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ParseExpiries parses a JSON object mapping coupon codes to RFC 3339
+// expiry times, e.g. {"HAPPYHRS":"2026-12-31T23:59:59Z"}, for
+// ValidatorOptions.Expiries. Empty input means no code expires
+func ParseExpiries(data []byte) (map[string]time.Time, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var expiries map[string]time.Time
+	if err := json.Unmarshal(data, &expiries); err != nil {
+		return nil, fmt.Errorf("invalid coupon expiries: %w", err)
+	}
+	for code := range expiries {
+		if code == "" {
+			return nil, fmt.Errorf("coupon expiry has an empty coupon code")
+		}
+	}
+	return expiries, nil
+}
+
+// canonicalExpiries keys expiries by canonical code, so lookups match
+// however the code was written in the configuration. Spellings of the same
+// code keep the earliest expiry
+func canonicalExpiries(expiries map[string]time.Time, canonicalize func(string) string) map[string]time.Time {
+	if len(expiries) == 0 {
+		return nil
+	}
+	canonical := make(map[string]time.Time, len(expiries))
+	for code, expiry := range expiries {
+		code = canonicalize(code)
+		if earlier, ok := canonical[code]; ok && earlier.Before(expiry) {
+			continue
+		}
+		canonical[code] = expiry
+	}
+	return canonical
+}
+
+// expired reports whether a canonical code has reached its expiry; a code
+// stops being valid at the expiry instant itself
+func (v *Validator) expired(code string) bool {
+	expiry, ok := v.expiries[code]
+	return ok && !v.options.Now().Before(expiry)
+}
@@ -0,0 +1,86 @@
+package coupon
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidator_Expiry(t *testing.T) {
+	expiry := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+	now := expiry.Add(-time.Hour)
+
+	validator := NewValidatorWithOptions(ValidatorOptions{
+		Expiries:      map[string]time.Time{"happy-hrs": expiry},
+		Now:           func() time.Time { return now },
+		CacheCapacity: DefaultCacheCapacity,
+	})
+	defer validator.Close()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\nFIFTYOFF\n"),
+		strings.NewReader("HAPPYHRS\nFIFTYOFF\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+
+	// Runs in order against the same validator, so the later cases also
+	// check a cached valid result doesn't outlive the expiry
+	tests := []struct {
+		name string
+		now  time.Time
+		code string
+		want Evaluation
+	}{
+		{"before expiry", expiry.Add(-time.Second), "HAPPYHRS", Evaluation{Valid: true, Reason: ReasonValid}},
+		{"at expiry", expiry, "HAPPYHRS", Evaluation{Reason: ReasonExpired}},
+		{"after expiry", expiry.Add(24 * time.Hour), "HAPPYHRS", Evaluation{Reason: ReasonExpired}},
+		{"code without expiry", expiry.Add(24 * time.Hour), "FIFTYOFF", Evaluation{Valid: true, Reason: ReasonValid}},
+		{"unknown code is not found", expiry.Add(24 * time.Hour), "NOTACODE", Evaluation{Reason: ReasonNotFound}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = tt.now
+			if got := validator.Evaluate(context.Background(), tt.code, EvaluateOptions{}); got != tt.want {
+				t.Errorf("Evaluate(%q) = %+v, want %+v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpiries(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]time.Time
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"valid", `{"HAPPYHRS":"2026-12-31T23:59:59Z"}`, map[string]time.Time{
+			"HAPPYHRS": time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC),
+		}, false},
+		{"malformed JSON", `{"HAPPYHRS":`, nil, true},
+		{"bad time", `{"HAPPYHRS":"tomorrow"}`, nil, true},
+		{"empty code", `{"":"2026-12-31T23:59:59Z"}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpiries([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExpiries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseExpiries() = %v, want %v", got, tt.want)
+			}
+			for code, expiry := range tt.want {
+				if !got[code].Equal(expiry) {
+					t.Errorf("expiry of %s = %v, want %v", code, got[code], expiry)
+				}
+			}
+		})
+	}
+}
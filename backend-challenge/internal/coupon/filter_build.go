@@ -0,0 +1,135 @@
+package coupon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// filterBatchLines is how many lines the parallel filter build hands a
+// worker at a time, amortising the channel hand-off over many additions
+const filterBatchLines = 4096
+
+// lineBatch holds trimmed lines back to back in data, line i ending at ends[i]
+type lineBatch struct {
+	data []byte
+	ends []int
+}
+
+// newLineScanner returns a scanner over coupon file lines
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+	return scanner
+}
+
+// addLines adds every non-empty trimmed line of r to filter, one at a time
+func addLines(ctx context.Context, r io.Reader, filter *bloom.BloomFilter) error {
+	scanner := newLineScanner(r)
+
+	count := 0
+	for scanner.Scan() {
+		// Check context cancellation periodically
+		if count%10000 == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+
+		// Bytes rather than Text so scanning doesn't allocate per line
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) > 0 {
+			filter.Add(line)
+			count++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanning file: %w", err)
+	}
+	return nil
+}
+
+// addLinesParallel adds the same lines as addLines, hashing them on workers
+// goroutines. Reading stays sequential; batches of lines are handed to the
+// workers, which set the filter bits with atomic ORs on the filter's own
+// words. Bits are only ever set, so the result is bit-for-bit the filter
+// addLines builds, with no per-worker copies of a 120MB filter
+// bloom.Locations is the only exported way to get an item's bit positions,
+// and it allocates a small slice per line
+func addLinesParallel(ctx context.Context, r io.Reader, filter *bloom.BloomFilter, workers int) error {
+	words := filter.BitSet().Words()
+	m, k := uint64(filter.Cap()), filter.K()
+
+	batches := make(chan *lineBatch, workers)
+	free := make(chan *lineBatch, 2*workers)
+	for i := 0; i < cap(free); i++ {
+		free <- &lineBatch{ends: make([]int, 0, filterBatchLines)}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				start := 0
+				for _, end := range batch.ends {
+					for _, loc := range bloom.Locations(batch.data[start:end], k) {
+						bit := loc % m
+						atomic.OrUint64(&words[bit>>6], 1<<(bit&63))
+					}
+					start = end
+				}
+				free <- batch
+			}
+		}()
+	}
+
+	err := feedLineBatches(ctx, r, batches, free)
+	close(batches)
+	wg.Wait()
+	return err
+}
+
+// feedLineBatches reads the lines of r into batches taken from free and
+// sends each full batch, and the final partial one, to batches
+func feedLineBatches(ctx context.Context, r io.Reader, batches chan<- *lineBatch, free <-chan *lineBatch) error {
+	scanner := newLineScanner(r)
+	batch := <-free
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		batch.data = append(batch.data, line...)
+		batch.ends = append(batch.ends, len(batch.data))
+		if len(batch.ends) < filterBatchLines {
+			continue
+		}
+
+		// Workers always drain batches, so only cancellation needs checking
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batches <- batch
+		batch = <-free
+		batch.data, batch.ends = batch.data[:0], batch.ends[:0]
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanning file: %w", err)
+	}
+	if len(batch.ends) > 0 {
+		batches <- batch
+	}
+	return nil
+}
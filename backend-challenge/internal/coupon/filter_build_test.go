@@ -0,0 +1,110 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+func TestAddLinesParallel_MatchesSerial(t *testing.T) {
+	path := writeLargeFixture(t, 3*filterBatchLines+17)
+
+	build := func(workers int) *bloom.BloomFilter {
+		t.Helper()
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open fixture: %v", err)
+		}
+		defer file.Close()
+
+		filter := bloom.NewWithEstimates(100000, 0.01)
+		if workers > 1 {
+			err = addLinesParallel(context.Background(), file, filter, workers)
+		} else {
+			err = addLines(context.Background(), file, filter)
+		}
+		if err != nil {
+			t.Fatalf("building with %d workers: %v", workers, err)
+		}
+		return filter
+	}
+
+	serial := build(1)
+	for _, workers := range []int{2, 4, 8} {
+		if !build(workers).Equal(serial) {
+			t.Errorf("filter built with %d workers differs from the serial filter", workers)
+		}
+	}
+	for _, code := range []string{"CODE000000", "CODE000001", "LASTCODE9"} {
+		if !serial.TestString(code) {
+			t.Errorf("expected %s in the filter", code)
+		}
+	}
+}
+
+func TestAddLinesParallel_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	lines := strings.Repeat("CODE0001\n", 2*filterBatchLines)
+	err := addLinesParallel(ctx, strings.NewReader(lines), bloom.NewWithEstimates(1000, 0.01), 4)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("addLinesParallel() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestValidator_FilterBuildWorkers(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{FilterBuildWorkers: 4})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	if !validator.IsValid(context.Background(), "VALIDABC") {
+		t.Error("expected VALIDABC to be valid")
+	}
+	if validator.IsValid(context.Background(), "ONLYONE1") {
+		t.Error("expected ONLYONE1 to be invalid")
+	}
+}
+
+// BenchmarkAddLines compares serial and parallel filter builds of one file
+// The parallel build only pays off with spare cores (see GOMAXPROCS)
+func BenchmarkAddLines(b *testing.B) {
+	path := writeLargeFixture(b, 1000000)
+
+	for _, bench := range []struct {
+		name    string
+		workers int
+	}{
+		{"serial", 1},
+		{"parallel-2", 2},
+		{"parallel-4", 4},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				file, err := os.Open(path)
+				if err != nil {
+					b.Fatalf("failed to open fixture: %v", err)
+				}
+				filter := bloom.NewWithEstimates(1000000, 0.01)
+				if bench.workers > 1 {
+					err = addLinesParallel(context.Background(), file, filter, bench.workers)
+				} else {
+					err = addLines(context.Background(), file, filter)
+				}
+				file.Close()
+				if err != nil {
+					b.Fatalf("building filter: %v", err)
+				}
+			}
+		})
+	}
+}
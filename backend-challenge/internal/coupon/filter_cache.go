@@ -0,0 +1,228 @@
+package coupon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// filterManifestName is the manifest file in a filter cache directory
+const filterManifestName = "manifest.json"
+
+// filterManifestVersion is bumped when cached filters become incompatible,
+// e.g. when the filter hashing changes, so old caches are rebuilt
+const filterManifestVersion = 1
+
+// filterManifest describes the Bloom filters saved in a cache directory
+type filterManifest struct {
+	Version int                  `json:"version"`
+	Files   []filterManifestFile `json:"files"`
+}
+
+// filterManifestFile records the coupon file a cached filter was built from
+// A filter is reused only while its file's size and modification time match
+type filterManifestFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Checksum string    `json:"checksum"`
+	Filter   string    `json:"filter"` // filter file name within the cache directory
+}
+
+// SaveFilters writes the loaded Bloom filters to dir with a manifest of the
+// files they were built from, for LoadFiltersFromCache on the next start
+// Each file is replaced atomically, the manifest last, so an interrupted
+// save leaves at worst filters that the old manifest no longer matches
+func (v *Validator) SaveFilters(dir string) error {
+	v.mu.RLock()
+	loaded := v.loaded
+	fromReaders := v.fromReaders
+	filePaths := v.filePaths
+	bloomFilters := v.bloomFilters
+	checksums := v.checksums
+	modTimes := v.modTimes
+	v.mu.RUnlock()
+
+	if !loaded {
+		return ErrNotReady
+	}
+	if fromReaders {
+		return fmt.Errorf("coupon data loaded from readers has no files to cache filters for")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating filter cache directory: %w", err)
+	}
+
+	manifest := filterManifest{Version: filterManifestVersion}
+	for i, path := range filePaths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("cannot access file %d: %w", i+1, err)
+		}
+
+		// The build-time modification time, so a file changed since then
+		// no longer matches its filter
+		entry := filterManifestFile{
+			Path:     path,
+			Size:     info.Size(),
+			ModTime:  modTimes[i],
+			Checksum: checksums[i],
+			Filter:   fmt.Sprintf("filter-%d.bloom", i),
+		}
+		err = saveAtomic(filepath.Join(dir, entry.Filter), func(w io.Writer) error {
+			_, err := bloomFilters[i].WriteTo(w)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("saving filter for file %d: %w", i+1, err)
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	return saveAtomic(filepath.Join(dir, filterManifestName), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(manifest)
+	})
+}
+
+// LoadFiltersFromCache loads filePaths like LoadFromFiles, reusing the Bloom
+// filters SaveFilters wrote to dir for every file whose size and
+// modification time still match; only the others are rebuilt. It reports
+// false without loading anything when dir holds no usable cache for
+// exactly these files, so the caller can fall back to LoadFromFiles
+func (v *Validator) LoadFiltersFromCache(ctx context.Context, dir string, filePaths []string) (bool, error) {
+	manifest, err := readFilterManifest(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if manifest.Version != filterManifestVersion || !slices.EqualFunc(manifest.Files, filePaths, func(f filterManifestFile, path string) bool {
+		return f.Path == path
+	}) {
+		return false, nil
+	}
+
+	sources, err := openFiles(filePaths)
+	if err != nil {
+		return false, err
+	}
+	err = v.load(ctx, sources, nil, func(ctx context.Context, sources []couponSource) ([]filterBuild, error) {
+		return v.cachedFilters(ctx, dir, manifest, sources)
+	})
+	return err == nil, err
+}
+
+// cachedFilters returns a filter build per path, read from the cache when
+// the manifest still matches the file and the filter was sized with the
+// current options, and built from the file otherwise
+func (v *Validator) cachedFilters(ctx context.Context, dir string, manifest *filterManifest, sources []couponSource) ([]filterBuild, error) {
+	m, k := bloom.EstimateParameters(v.options.ExpectedItems, v.options.FalsePositiveRate)
+	builds := make([]filterBuild, len(sources))
+	var stale []int
+	for i, src := range sources {
+		path := src.path
+		entry := manifest.Files[i]
+		start := time.Now()
+		info, err := os.Stat(path)
+		if err != nil || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+			stale = append(stale, i)
+			continue
+		}
+
+		filter, err := readFilter(filepath.Join(dir, entry.Filter))
+		if err != nil {
+			v.options.Logger.WarnContext(ctx, "cached coupon filter unreadable, rebuilding it",
+				"file", path,
+				"error", err,
+			)
+			stale = append(stale, i)
+			continue
+		}
+		if filter.Cap() != m || filter.K() != k {
+			stale = append(stale, i)
+			continue
+		}
+		builds[i] = filterBuild{filter: filter, checksum: entry.Checksum, duration: time.Since(start), modTime: entry.ModTime}
+	}
+
+	if len(stale) == 0 {
+		return builds, nil
+	}
+	staleSources := make([]couponSource, len(stale))
+	for j, i := range stale {
+		staleSources[j] = sources[i]
+	}
+	rebuilt, err := v.buildFilters(ctx, staleSources)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range stale {
+		builds[i] = rebuilt[j]
+	}
+	return builds, nil
+}
+
+// readFilterManifest reads the manifest of a filter cache directory
+func readFilterManifest(dir string) (*filterManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filterManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest filterManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid filter cache manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// readFilter reads a Bloom filter saved by SaveFilters
+func readFilter(path string) (*bloom.BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	filter := &bloom.BloomFilter{}
+	if _, err := filter.ReadFrom(bufio.NewReader(file)); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// saveAtomic writes a file through write into a temp file and renames it
+// over path, so readers never see it half written
+func saveAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,121 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCacheTestFiles writes two coupon files sharing VALIDABC and returns their paths
+func writeCacheTestFiles(t *testing.T) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "couponbase1"), filepath.Join(dir, "couponbase2")}
+	if err := os.WriteFile(paths[0], []byte("VALIDABC\nTESTCODE\n"), 0644); err != nil {
+		t.Fatalf("failed to write coupon file: %v", err)
+	}
+	if err := os.WriteFile(paths[1], []byte("VALIDABC\nSPECIAL9\n"), 0644); err != nil {
+		t.Fatalf("failed to write coupon file: %v", err)
+	}
+	return paths
+}
+
+func TestValidator_FilterCache(t *testing.T) {
+	ctx := context.Background()
+	paths := writeCacheTestFiles(t)
+	cacheDir := filepath.Join(t.TempDir(), "filters")
+
+	original := NewValidator()
+	if err := original.LoadFromFiles(ctx, paths); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	if err := original.SaveFilters(cacheDir); err != nil {
+		t.Fatalf("SaveFilters() error = %v", err)
+	}
+	original.Close()
+
+	t.Run("unchanged files reuse every filter", func(t *testing.T) {
+		validator := NewValidator()
+		defer validator.Close()
+
+		loaded, err := validator.LoadFiltersFromCache(ctx, cacheDir, paths)
+		if err != nil || !loaded {
+			t.Fatalf("LoadFiltersFromCache() = %v, %v, want true, nil", loaded, err)
+		}
+		if builds := validator.GetStats()["filterBuilds"]; builds != int64(0) {
+			t.Errorf("filterBuilds = %v, want 0", builds)
+		}
+		if !validator.IsValid(ctx, "VALIDABC") || validator.IsValid(ctx, "TESTCODE") {
+			t.Error("cached filters give different results from the files")
+		}
+	})
+
+	t.Run("other files are not served from the cache", func(t *testing.T) {
+		validator := NewValidator()
+		defer validator.Close()
+
+		loaded, err := validator.LoadFiltersFromCache(ctx, cacheDir, paths[:1])
+		if err != nil || loaded {
+			t.Errorf("LoadFiltersFromCache() = %v, %v, want false, nil", loaded, err)
+		}
+	})
+
+	t.Run("missing cache", func(t *testing.T) {
+		validator := NewValidator()
+		defer validator.Close()
+
+		loaded, err := validator.LoadFiltersFromCache(ctx, t.TempDir(), paths)
+		if err != nil || loaded {
+			t.Errorf("LoadFiltersFromCache() = %v, %v, want false, nil", loaded, err)
+		}
+	})
+
+	t.Run("filters sized for other options are rebuilt", func(t *testing.T) {
+		validator := NewValidatorWithOptions(ValidatorOptions{ExpectedItems: 1000})
+		defer validator.Close()
+
+		loaded, err := validator.LoadFiltersFromCache(ctx, cacheDir, paths)
+		if err != nil || !loaded {
+			t.Fatalf("LoadFiltersFromCache() = %v, %v, want true, nil", loaded, err)
+		}
+		if builds := validator.GetStats()["filterBuilds"]; builds != int64(len(paths)) {
+			t.Errorf("filterBuilds = %v, want %d", builds, len(paths))
+		}
+	})
+
+	t.Run("a changed file is rebuilt", func(t *testing.T) {
+		if err := os.WriteFile(paths[1], []byte("VALIDABC\nSPECIAL9\nTESTCODE\n"), 0644); err != nil {
+			t.Fatalf("failed to rewrite coupon file: %v", err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(paths[1], later, later); err != nil {
+			t.Fatalf("failed to touch coupon file: %v", err)
+		}
+
+		validator := NewValidator()
+		defer validator.Close()
+
+		loaded, err := validator.LoadFiltersFromCache(ctx, cacheDir, paths)
+		if err != nil || !loaded {
+			t.Fatalf("LoadFiltersFromCache() = %v, %v, want true, nil", loaded, err)
+		}
+		if builds := validator.GetStats()["filterBuilds"]; builds != int64(1) {
+			t.Errorf("filterBuilds = %v, want 1", builds)
+		}
+		if !validator.IsValid(ctx, "TESTCODE") {
+			t.Error("expected TESTCODE to be valid after its file changed")
+		}
+	})
+}
+
+func TestValidator_SaveFilters_NotLoaded(t *testing.T) {
+	validator := NewValidator()
+	defer validator.Close()
+
+	if err := validator.SaveFilters(t.TempDir()); !errors.Is(err, ErrNotReady) {
+		t.Errorf("SaveFilters() error = %v, want ErrNotReady", err)
+	}
+}
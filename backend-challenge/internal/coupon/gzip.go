@@ -0,0 +1,59 @@
+package coupon
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// maybeGunzip returns a reader over the decompressed contents of r when r is
+// a gzip stream, and over r unchanged otherwise. Decompression is streamed,
+// so memory stays flat no matter how large the decompressed file is
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(header, gzipMagic) {
+		return br, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("opening gzip stream: %w", err)
+	}
+	return gz, nil
+}
+
+// gunzipBytes returns data decompressed when it is a gzip stream, and data
+// itself otherwise
+func gunzipBytes(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("opening gzip stream: %w", err)
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// isGzipFile reports whether a file starts with the gzip header
+func isGzipFile(file *os.File) (bool, error) {
+	header := make([]byte, len(gzipMagic))
+	n, err := file.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return bytes.Equal(header[:n], gzipMagic), nil
+}
@@ -0,0 +1,133 @@
+package coupon
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeGzipFixture streams n generated codes into a gzipped coupon file and
+// returns its path along with the decompressed size
+func writeGzipFixture(t *testing.T, n int) (string, int64) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "couponbase.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create fixture: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	w := bufio.NewWriter(gz)
+	var size int64
+	for i := 0; i < n; i++ {
+		written, _ := fmt.Fprintf(w, "GZ%08d\n", i)
+		size += int64(written)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path, size
+}
+
+func TestBuildBloomFilter_GzipMemoryBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a large synthetic fixture")
+	}
+
+	path, decompressed := writeGzipFixture(t, 1000000)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	filter, checksum, err := NewValidator().buildBloomFilter(context.Background(), couponSource{path: path})
+	if err != nil {
+		t.Fatalf("buildBloomFilter() error = %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	// Everything allocated beyond the filter itself is streaming overhead:
+	// scanner and gzip buffers, independent of the decompressed size
+	overhead := int64(after.TotalAlloc-before.TotalAlloc) - int64(filter.Cap()/8)
+	const ceiling = 4 << 20
+	if overhead > ceiling {
+		t.Errorf("build allocated %d bytes beyond the filter for %d decompressed bytes, want <= %d", overhead, decompressed, ceiling)
+	}
+
+	for _, code := range []string{"GZ00000000", "GZ00500000", "GZ00999999"} {
+		if !filter.TestString(code) {
+			t.Errorf("filter is missing %s", code)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	sum := sha256.Sum256(raw)
+	if want := hex.EncodeToString(sum[:]); checksum != want {
+		t.Errorf("checksum = %s, want the checksum of the compressed file %s", checksum, want)
+	}
+}
+
+func TestValidator_GzippedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"coupons1.gz":  "VALIDABC\nTESTCODE\nCOUPON01\n",
+		"coupons2.gz":  "VALIDABC\nSPECIAL9\nCOUPON02\n",
+		"coupons3.txt": "TESTCODE\nSPECIAL9\nCOUPON03\n",
+	}
+	paths := make([]string, 0, len(files))
+	for name, content := range files {
+		data := []byte(content)
+		if filepath.Ext(name) == ".gz" {
+			data = gzipBytes(t, content)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"VALIDABC", true},
+		{"TESTCODE", true},
+		{"SPECIAL9", true},
+		{"COUPON01", false},
+		{"COUPON03", false},
+	}
+
+	// Mapped validators fall back to streaming for the gzipped files
+	for _, useMmap := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mmap=%v", useMmap), func(t *testing.T) {
+			validator := NewValidatorWithOptions(ValidatorOptions{UseMmap: useMmap})
+			if err := validator.LoadFromFiles(context.Background(), paths); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+			defer validator.Close()
+
+			for _, tt := range tests {
+				if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+					t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+				}
+			}
+		})
+	}
+}
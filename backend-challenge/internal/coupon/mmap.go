@@ -0,0 +1,142 @@
+package coupon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// errMappingClosed is returned by a search on a mapping released by Close or
+// Reload; callers fall back to streaming the file
+var errMappingClosed = errors.New("coupon file mapping closed")
+
+// mmapCheckInterval is how many lines a mapped search scans between
+// cancellation checks, keeping the per-line loop free of channel operations
+const mmapCheckInterval = 4096
+
+// mappedFile is a read-only memory mapping of a coupon file, or coupon data
+// already in memory (inMemory) for sources loaded from readers
+// Searches hold the read lock so the mapping is never unmapped under them
+type mappedFile struct {
+	data     []byte
+	inMemory bool
+	closed   bool
+	mu       sync.RWMutex
+}
+
+// mapCouponFile memory-maps a coupon file for searching
+// It returns nil for gzipped files, which can't be searched in place
+func mapCouponFile(filePath string) (*mappedFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Empty files can't be mapped, but searching them needs no data anyway
+	if info.Size() == 0 {
+		return &mappedFile{}, nil
+	}
+
+	// Compressed bytes can't be searched in place; gzipped files keep
+	// using the streaming search
+	if gzipped, err := isGzipFile(file); err != nil || gzipped {
+		return nil, err
+	}
+
+	data, err := mmapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %s: %w", filePath, err)
+	}
+	return &mappedFile{data: data}, nil
+}
+
+// search reports whether the mapped file contains the code on a line of its
+// own, matching searchFileForCoupon line for line
+func (m *mappedFile) search(ctx context.Context, couponCode string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return false, errMappingClosed
+	}
+	return searchMapped(ctx, m.data, couponCode)
+}
+
+// close unmaps the file once in-flight searches have finished
+func (m *mappedFile) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if m.data == nil || m.inMemory {
+		m.data = nil
+		return nil
+	}
+
+	err := munmapFile(m.data)
+	m.data = nil
+	return err
+}
+
+// searchMapped scans newline-separated data for a line equal to couponCode
+// once surrounding whitespace (including a trailing \r) is trimmed
+func searchMapped(ctx context.Context, data []byte, couponCode string) (bool, error) {
+	for lines := 0; len(data) > 0; lines++ {
+		if lines%mmapCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+		}
+
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+
+		if string(bytes.TrimSpace(line)) == couponCode {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mapCouponFiles maps each file, releasing the ones already mapped on failure
+func mapCouponFiles(filePaths []string) (map[string]*mappedFile, error) {
+	mapped := make(map[string]*mappedFile, len(filePaths))
+	for i, filePath := range filePaths {
+		m, err := mapCouponFile(filePath)
+		if err != nil {
+			closeMappings(mapped)
+			return nil, fmt.Errorf("mapping file %d: %w", i+1, err)
+		}
+		if m != nil {
+			mapped[filePath] = m
+		}
+	}
+	return mapped, nil
+}
+
+// closeMappings unmaps every mapping, returning the first error
+func closeMappings(mapped map[string]*mappedFile) error {
+	var firstErr error
+	for _, m := range mapped {
+		if err := m.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
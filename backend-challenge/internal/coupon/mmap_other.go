@@ -0,0 +1,19 @@
+//go:build !unix
+
+package coupon
+
+import (
+	"errors"
+	"os"
+)
+
+// errMmapUnsupported is returned when UseMmap is set on a platform without mmap
+var errMmapUnsupported = errors.New("memory-mapped coupon files are not supported on this platform")
+
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmapFile(data []byte) error {
+	return errMmapUnsupported
+}
@@ -0,0 +1,171 @@
+package coupon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLargeFixture writes a coupon file of n generated codes with the
+// irregular whitespace, blank lines and CRLF endings real files may carry
+func writeLargeFixture(tb testing.TB, n int) string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "couponbase-large")
+	file, err := os.Create(path)
+	if err != nil {
+		tb.Fatalf("failed to create fixture: %v", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for i := 0; i < n; i++ {
+		code := fmt.Sprintf("CODE%06d", i)
+		switch i % 7 {
+		case 0:
+			fmt.Fprintf(w, "%s\r\n", code)
+		case 1:
+			fmt.Fprintf(w, "  %s\t\n", code)
+		case 2:
+			fmt.Fprintf(w, "%s\n\n", code)
+		default:
+			fmt.Fprintf(w, "%s\n", code)
+		}
+	}
+	// Last line without a trailing newline
+	fmt.Fprint(w, "LASTCODE9")
+	if err := w.Flush(); err != nil {
+		tb.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestSearchMapped_MatchesScanner(t *testing.T) {
+	path := writeLargeFixture(t, 200000)
+
+	mapped, err := mapCouponFile(path)
+	if err != nil {
+		t.Skipf("mmap unavailable: %v", err)
+	}
+	defer mapped.close()
+
+	codes := []string{
+		"CODE000000", // CRLF line
+		"CODE000001", // padded with whitespace
+		"CODE000002", // followed by a blank line
+		"CODE123456",
+		"CODE199999",
+		"LASTCODE9", // no trailing newline
+		"CODE200000",
+		"CODE00000", // prefix of a real code
+		"ODE000003", // suffix of a real code
+		"",          // blank lines
+	}
+
+	for _, code := range codes {
+		t.Run(code, func(t *testing.T) {
+			want, err := scanFileForCoupon(context.Background(), path, code)
+			if err != nil {
+				t.Fatalf("scanner search failed: %v", err)
+			}
+			got, err := mapped.search(context.Background(), code)
+			if err != nil {
+				t.Fatalf("mapped search failed: %v", err)
+			}
+			if got != want {
+				t.Errorf("mapped search = %v, scanner search = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSearchMapped_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := searchMapped(ctx, []byte("CODE000001\n"), "CODE000001"); !errors.Is(err, context.Canceled) {
+		t.Errorf("searchMapped() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestValidator_UseMmap(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{UseMmap: true})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Skipf("mmap unavailable: %v", err)
+	}
+
+	mapped := validator.mapped
+	if len(mapped) != 3 {
+		t.Fatalf("expected 3 mapped files, got %d", len(mapped))
+	}
+
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"VALIDABC", true},
+		{"TESTCODE", true},
+		{"SPECIAL9", true},
+		{"COUPON01", false},
+		{"ONLYONE1", false},
+	}
+
+	for _, tt := range tests {
+		if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+			t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+		}
+	}
+
+	if err := validator.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for path, m := range mapped {
+		if !m.closed || m.data != nil {
+			t.Errorf("mapping for %s was not released", path)
+		}
+	}
+
+	// Searches after Close fall back to streaming the files
+	found, err := validator.searchCouponFile(context.Background(), file1, "COUPON01")
+	if err != nil || !found {
+		t.Errorf("searchCouponFile() after Close = %v, %v; want true, nil", found, err)
+	}
+}
+
+func BenchmarkSearchFile(b *testing.B) {
+	path := writeLargeFixture(b, 1000000)
+	ctx := context.Background()
+
+	// A missing code is the worst case: every line is compared
+	const code = "NOTPRESENT"
+
+	b.Run("scanner", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := scanFileForCoupon(ctx, path, code); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("mmap", func(b *testing.B) {
+		mapped, err := mapCouponFile(path)
+		if err != nil {
+			b.Skipf("mmap unavailable: %v", err)
+		}
+		defer mapped.close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := mapped.search(ctx, code); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package coupon
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps size bytes of file read-only and shared, so repeated
+// searches are served from the OS page cache
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile releases a mapping created by mmapFile
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
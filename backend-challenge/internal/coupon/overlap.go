@@ -0,0 +1,261 @@
+package coupon
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// overlapChunkSize is the number of codes sorted in memory at once when
+// computing overlap statistics (~30MB of strings for 1M codes)
+var overlapChunkSize = 1000000
+
+// computeOverlap returns, for each number of files k, how many distinct codes
+// appear in exactly k of the given files
+//
+// The computation stops when maxDuration (if positive) or the ctx deadline
+// is reached, returning the counts gathered so far with truncated set
+// Cancellation of ctx is still returned as an error
+//
+// Memory stays bounded regardless of file size:
+// 1. Each file is split into sorted, de-duplicated chunks on disk
+// 2. The chunks are merged into one sorted unique stream per file
+// 3. The per-file streams are merged, counting how many files hold each code
+func computeOverlap(ctx context.Context, filePaths []string, maxDuration time.Duration) (distribution map[int]int64, truncated bool, err error) {
+	if maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxDuration)
+		defer cancel()
+	}
+
+	tmpDir, err := os.MkdirTemp("", "coupon-overlap-*")
+	if err != nil {
+		return nil, false, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	distribution = make(map[int]int64)
+
+	sortedPaths := make([]string, len(filePaths))
+	for i, path := range filePaths {
+		sorted, err := sortFileUnique(ctx, path, filepath.Join(tmpDir, fmt.Sprintf("file%d", i)))
+		if errors.Is(err, context.DeadlineExceeded) {
+			return distribution, true, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("sorting file %d: %w", i+1, err)
+		}
+		sortedPaths[i] = sorted
+	}
+
+	err = mergeSortedFiles(ctx, sortedPaths, func(code string, count int) error {
+		distribution[count]++
+		return nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return distribution, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return distribution, false, nil
+}
+
+// sortFileUnique writes the distinct codes of a file to a sorted file and returns its path
+func sortFileUnique(ctx context.Context, srcPath, prefix string) (string, error) {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	contents, err := maybeGunzip(file)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(contents)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var chunkPaths []string
+	chunk := make([]string, 0, overlapChunkSize)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		path := fmt.Sprintf("%s.chunk%d", prefix, len(chunkPaths))
+		if err := writeSortedChunk(path, chunk); err != nil {
+			return err
+		}
+		chunkPaths = append(chunkPaths, path)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	lines := 0
+	for scanner.Scan() {
+		// Check context cancellation periodically
+		if lines%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		lines++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		chunk = append(chunk, line)
+		if len(chunk) >= overlapChunkSize {
+			if err := flush(); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("scanning file: %w", err)
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	if len(chunkPaths) == 1 {
+		return chunkPaths[0], nil
+	}
+
+	// Merge chunks into a single sorted, de-duplicated file
+	out, err := os.Create(prefix + ".sorted")
+	if err != nil {
+		return "", fmt.Errorf("creating sorted file: %w", err)
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	err = mergeSortedFiles(ctx, chunkPaths, func(code string, _ int) error {
+		_, err := w.WriteString(code + "\n")
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("writing sorted file: %w", err)
+	}
+
+	return out.Name(), nil
+}
+
+// writeSortedChunk sorts codes and writes the distinct ones to path
+func writeSortedChunk(path string, codes []string) error {
+	sort.Strings(codes)
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating chunk file: %w", err)
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	for i, code := range codes {
+		if i > 0 && code == codes[i-1] {
+			continue
+		}
+		if _, err := w.WriteString(code + "\n"); err != nil {
+			return fmt.Errorf("writing chunk file: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing chunk file: %w", err)
+	}
+	return nil
+}
+
+// mergeSortedFiles performs a k-way merge of sorted, de-duplicated files,
+// calling fn once per distinct code with the number of files containing it
+func mergeSortedFiles(ctx context.Context, paths []string, fn func(code string, count int) error) error {
+	h := make(mergeHeap, 0, len(paths))
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening sorted file: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		if scanner.Scan() {
+			h = append(h, &mergeCursor{code: scanner.Text(), scanner: scanner})
+		} else if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading sorted file: %w", err)
+		}
+	}
+	heap.Init(&h)
+
+	processed := 0
+	for h.Len() > 0 {
+		// Check context cancellation periodically
+		if processed%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		processed++
+
+		code := h[0].code
+		count := 0
+		for h.Len() > 0 && h[0].code == code {
+			count++
+			cursor := h[0]
+			if cursor.scanner.Scan() {
+				cursor.code = cursor.scanner.Text()
+				heap.Fix(&h, 0)
+			} else {
+				if err := cursor.scanner.Err(); err != nil {
+					return fmt.Errorf("reading sorted file: %w", err)
+				}
+				heap.Pop(&h)
+			}
+		}
+
+		if err := fn(code, count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeCursor tracks the current code of one sorted input
+type mergeCursor struct {
+	code    string
+	scanner *bufio.Scanner
+}
+
+// mergeHeap is a min-heap of cursors ordered by their current code
+type mergeHeap []*mergeCursor
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].code < h[j].code }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeCursor)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
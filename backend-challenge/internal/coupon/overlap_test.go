@@ -0,0 +1,136 @@
+package coupon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidator_OverlapDistribution(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	t.Run("computed when enabled", func(t *testing.T) {
+		validator := NewValidatorWithOptions(ValidatorOptions{ComputeOverlap: true})
+		if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+
+		// Fixture layout: VALIDABC in all 3 files, TESTCODE and SPECIAL9 in 2,
+		// and the remaining 8 codes in exactly 1 file
+		expected := map[int]int64{1: 8, 2: 2, 3: 1}
+
+		stats := validator.GetStats()
+		got, ok := stats["overlapDistribution"].(map[int]int64)
+		if !ok {
+			t.Fatalf("expected overlapDistribution in stats, got %v", stats["overlapDistribution"])
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("overlapDistribution = %v, want %v", got, expected)
+		}
+	})
+
+	t.Run("skipped by default", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+
+		if _, ok := validator.GetStats()["overlapDistribution"]; ok {
+			t.Error("expected overlapDistribution to be absent by default")
+		}
+	})
+}
+
+func TestComputeOverlap_MultipleChunks(t *testing.T) {
+	// Force several on-disk chunks per file so the chunk merge path is exercised
+	original := overlapChunkSize
+	overlapChunkSize = 2
+	defer func() { overlapChunkSize = original }()
+
+	tmpDir := t.TempDir()
+	fileA := filepath.Join(tmpDir, "a.txt")
+	fileB := filepath.Join(tmpDir, "b.txt")
+
+	// Duplicates within a file must only be counted once
+	if err := os.WriteFile(fileA, []byte("DDDD4444\nAAAA1111\nCCCC3333\nAAAA1111\nBBBB2222\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(fileB, []byte("EEEE5555\nCCCC3333\nAAAA1111\n\nCCCC3333\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	got, truncated, err := computeOverlap(context.Background(), []string{fileA, fileB}, 0)
+	if err != nil {
+		t.Fatalf("computeOverlap() error = %v", err)
+	}
+	if truncated {
+		t.Error("expected complete result without a time budget")
+	}
+
+	// AAAA1111 and CCCC3333 in both; BBBB2222, DDDD4444, EEEE5555 in one
+	expected := map[int]int64{1: 3, 2: 2}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("computeOverlap() = %v, want %v", got, expected)
+	}
+}
+
+func TestComputeOverlap_ContextCancelled(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := computeOverlap(ctx, []string{file1, file2, file3}, 0); err == nil {
+		t.Error("expected error for cancelled context, got nil")
+	}
+}
+
+func TestComputeOverlap_TimeBudget(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	t.Run("max duration exceeded", func(t *testing.T) {
+		start := time.Now()
+		got, truncated, err := computeOverlap(context.Background(), []string{file1, file2, file3}, time.Nanosecond)
+		if err != nil {
+			t.Fatalf("computeOverlap() error = %v, want partial result", err)
+		}
+		if !truncated {
+			t.Error("expected truncated result")
+		}
+		if got == nil {
+			t.Error("expected a (possibly empty) partial distribution, got nil")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("computeOverlap() took %v after its budget expired", elapsed)
+		}
+	})
+
+	t.Run("context deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		if _, truncated, err := computeOverlap(ctx, []string{file1, file2, file3}, 0); err != nil || !truncated {
+			t.Errorf("computeOverlap() = truncated %v, err %v; want truncated, nil", truncated, err)
+		}
+	})
+
+	t.Run("reported in stats", func(t *testing.T) {
+		validator := NewValidatorWithOptions(ValidatorOptions{
+			ComputeOverlap:     true,
+			OverlapMaxDuration: time.Nanosecond,
+		})
+		if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+
+		if truncated, _ := validator.GetStats()["overlapTruncated"].(bool); !truncated {
+			t.Error("expected overlapTruncated in stats")
+		}
+	})
+}
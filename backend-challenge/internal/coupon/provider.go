@@ -0,0 +1,160 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCloseGrace is how long a swapped-out validator stays open so that
+// requests which loaded it just before the swap can finish
+const DefaultCloseGrace = 30 * time.Second
+
+// ValidatorProvider holds the validator currently serving requests
+//
+// For zero-downtime reloads a replacement Validator, typically a reloaded
+// Clone of the current one, is built in the background and swapped in
+// atomically with SwapTo. Requests read the
+// current pointer once and keep using that validator, so the hot path never
+// waits on a lock held for the swap. The old validator is closed after a
+// grace period. Validators swapped in must not share a DiskCachePath with
+// the one they replace, since closing the old one removes its cache file.
+type ValidatorProvider struct {
+	current    atomic.Pointer[Validator]
+	closeGrace time.Duration
+
+	// mu orders swaps against override changes, so an override set while a
+	// swap copies them can't be left behind on the old validator
+	mu      sync.Mutex
+	retired map[*Validator]*time.Timer // swapped out, closed when the timer fires
+}
+
+// NewValidatorProvider creates a provider serving the given loaded validator
+// A non-positive closeGrace falls back to DefaultCloseGrace
+func NewValidatorProvider(v *Validator, closeGrace time.Duration) *ValidatorProvider {
+	if closeGrace <= 0 {
+		closeGrace = DefaultCloseGrace
+	}
+
+	p := &ValidatorProvider{
+		closeGrace: closeGrace,
+		retired:    make(map[*Validator]*time.Timer),
+	}
+	p.current.Store(v)
+	return p
+}
+
+// Current returns the validator currently serving requests
+func (p *ValidatorProvider) Current() *Validator {
+	return p.current.Load()
+}
+
+// SwapTo atomically replaces the current validator with a fully loaded one
+// Overrides are carried over to the new validator, and the previous
+// validator is closed once the grace period has passed
+func (p *ValidatorProvider) SwapTo(v *Validator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	old := p.Current()
+	if old == v {
+		return
+	}
+	if old != nil {
+		for code, valid := range old.Overrides() {
+			v.SetOverride(code, &valid)
+		}
+	}
+	p.current.Store(v)
+	if old == nil {
+		return
+	}
+
+	p.retired[old] = time.AfterFunc(p.closeGrace, func() {
+		p.mu.Lock()
+		delete(p.retired, old)
+		p.mu.Unlock()
+
+		// Best effort: the old validator is no longer reachable
+		_ = old.Close()
+	})
+}
+
+// IsValid validates a code against the current validator
+func (p *ValidatorProvider) IsValid(ctx context.Context, code string) bool {
+	return p.Current().IsValid(ctx, code)
+}
+
+// Verify validates a code against the current validator, reporting when the
+// answer is unknown
+func (p *ValidatorProvider) Verify(ctx context.Context, code string) (bool, error) {
+	return p.Current().Verify(ctx, code)
+}
+
+// Evaluate validates a code against the current validator with per-call options
+func (p *ValidatorProvider) Evaluate(ctx context.Context, code string, opts EvaluateOptions) Evaluation {
+	return p.Current().Evaluate(ctx, code, opts)
+}
+
+// Inspect inspects a code against the current validator
+func (p *ValidatorProvider) Inspect(ctx context.Context, code string) ([]FileInspection, error) {
+	return p.Current().Inspect(ctx, code)
+}
+
+// Locate finds the files containing a code in the current validator
+func (p *ValidatorProvider) Locate(ctx context.Context, code string) ([]int, error) {
+	return p.Current().Locate(ctx, code)
+}
+
+// Exists reports whether a code is in any file of the current validator
+func (p *ValidatorProvider) Exists(ctx context.Context, code string) bool {
+	return p.Current().Exists(ctx, code)
+}
+
+// SetOverride sets or removes an override on the current validator
+func (p *ValidatorProvider) SetOverride(code string, valid *bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Current().SetOverride(code, valid)
+}
+
+// Canonicalize canonicalizes a code as the current validator does
+func (p *ValidatorProvider) Canonicalize(code string) string {
+	return p.Current().Canonicalize(code)
+}
+
+// PingCache pings the current validator's cache
+func (p *ValidatorProvider) PingCache() {
+	p.Current().PingCache()
+}
+
+// MaxConcurrentSearches returns the current validator's search limit
+func (p *ValidatorProvider) MaxConcurrentSearches() int {
+	return p.Current().MaxConcurrentSearches()
+}
+
+// GetStats returns the current validator's statistics
+func (p *ValidatorProvider) GetStats() map[string]interface{} {
+	return p.Current().GetStats()
+}
+
+// Close closes the current validator and the swapped-out validators still
+// in their grace period
+func (p *ValidatorProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for old, timer := range p.retired {
+		// A timer that already fired is closing its validator itself
+		if timer.Stop() {
+			errs = append(errs, old.Close())
+		}
+		delete(p.retired, old)
+	}
+	errs = append(errs, p.Current().Close())
+	return errors.Join(errs...)
+}
@@ -0,0 +1,178 @@
+package coupon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidatorProvider_SwapDuringReads(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	files := []string{file1, file2, file3}
+
+	newLoaded := func() *Validator {
+		v := NewValidator()
+		if err := v.LoadFromFiles(context.Background(), files); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+		return v
+	}
+
+	provider := NewValidatorProvider(newLoaded(), time.Millisecond)
+
+	var (
+		wg      sync.WaitGroup
+		stop    atomic.Bool
+		reads   atomic.Int64
+		failure atomic.Int64
+	)
+
+	// Readers must always see a fully built validator, never an empty one
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if !provider.IsValid(context.Background(), "TESTCODE") {
+					failure.Add(1)
+				}
+				if provider.IsValid(context.Background(), "ONLYONE1") {
+					failure.Add(1)
+				}
+				reads.Add(1)
+			}
+		}()
+	}
+
+	for i := 0; i < 5; i++ {
+		provider.SwapTo(newLoaded())
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if reads.Load() == 0 {
+		t.Fatal("expected reads during swaps")
+	}
+	if n := failure.Load(); n > 0 {
+		t.Errorf("%d reads saw a wrong result during swaps", n)
+	}
+}
+
+func TestValidatorProvider_ClosesOldValidatorAfterGrace(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	oldCachePath := filepath.Join(t.TempDir(), "old.cache")
+	old := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity, DiskCachePath: oldCachePath})
+	if err := old.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	provider := NewValidatorProvider(old, 20*time.Millisecond)
+	deny := false
+	provider.SetOverride("VALIDABC", &deny)
+
+	replacement := NewValidator()
+	if err := replacement.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	provider.SwapTo(replacement)
+
+	if provider.Current() != replacement {
+		t.Fatal("expected the replacement to be current after SwapTo")
+	}
+	if provider.IsValid(context.Background(), "VALIDABC") {
+		t.Error("expected override to carry over to the replacement")
+	}
+
+	// Still open during the grace period
+	if _, err := os.Stat(oldCachePath); err != nil {
+		t.Fatalf("old validator closed before grace period: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(oldCachePath); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("old validator was not closed after the grace period")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestValidatorProvider_OverridesSurviveConcurrentSwaps(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	files := []string{file1, file2, file3}
+
+	newLoaded := func() *Validator {
+		v := NewValidator()
+		if err := v.LoadFromFiles(context.Background(), files); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+		return v
+	}
+
+	provider := NewValidatorProvider(newLoaded(), time.Millisecond)
+	defer provider.Close()
+
+	const overrides = 200
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		deny := false
+		for i := 0; i < overrides; i++ {
+			provider.SetOverride(fmt.Sprintf("CODE%04d", i), &deny)
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		provider.SwapTo(newLoaded())
+	}
+	wg.Wait()
+
+	// Every override must reach the validator left serving, whichever
+	// validator was current when it was set
+	if got := len(provider.Current().Overrides()); got != overrides {
+		t.Errorf("current validator has %d overrides, want %d", got, overrides)
+	}
+}
+
+func TestValidatorProvider_CloseClosesRetiredValidators(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	files := []string{file1, file2, file3}
+
+	dir := t.TempDir()
+	newLoaded := func(cachePath string) *Validator {
+		v := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity, DiskCachePath: cachePath})
+		if err := v.LoadFromFiles(context.Background(), files); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+		return v
+	}
+
+	// The grace period outlasts the test, so only Close can release them
+	cachePaths := []string{filepath.Join(dir, "0.cache"), filepath.Join(dir, "1.cache"), filepath.Join(dir, "2.cache")}
+	provider := NewValidatorProvider(newLoaded(cachePaths[0]), time.Hour)
+	provider.SwapTo(newLoaded(cachePaths[1]))
+	provider.SwapTo(newLoaded(cachePaths[2]))
+
+	if err := provider.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for _, path := range cachePaths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("validator with disk cache %s was not closed: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package coupon
+
+import (
+	"context"
+	"sync"
+)
+
+// CodeVerifier is the validator functionality a Redeemer needs; Validator
+// and ValidatorProvider both provide it
+type CodeVerifier interface {
+	Verify(ctx context.Context, code string) (bool, error)
+	Canonicalize(code string) string
+}
+
+// Redeemer reserves single-use coupon codes so concurrent checkouts can't
+// spend the same code twice. Redeemed codes are tracked in memory only, so
+// they are forgotten on restart
+type Redeemer struct {
+	verifier CodeVerifier
+
+	mu       sync.Mutex
+	redeemed map[string]string // canonical code -> idempotency key it was redeemed with
+}
+
+// NewRedeemer creates a redeemer validating codes with verifier
+func NewRedeemer(verifier CodeVerifier) *Redeemer {
+	return &Redeemer{
+		verifier: verifier,
+		redeemed: make(map[string]string),
+	}
+}
+
+// Redeem validates a code and marks it used, reporting false if the code is
+// invalid or was already redeemed. Codes are tracked in canonical form, so
+// "happy-hrs" and "HAPPYHRS" are the same coupon. Validation errors are
+// returned without redeeming the code
+func (r *Redeemer) Redeem(ctx context.Context, code string) (bool, error) {
+	return r.RedeemWithKey(ctx, code, "")
+}
+
+// RedeemWithKey redeems a code like Redeem, remembering the idempotency key
+// it was redeemed with. Retrying with the same non-empty key reports the
+// original success instead of "already redeemed", so a client retrying a
+// request whose response it lost doesn't see its own redemption refused
+// Keys are tied to the code: the same key on another code is a new redemption
+func (r *Redeemer) RedeemWithKey(ctx context.Context, code, key string) (bool, error) {
+	code = r.verifier.Canonicalize(code)
+
+	if key != "" {
+		r.mu.Lock()
+		usedKey, used := r.redeemed[code]
+		r.mu.Unlock()
+		if used {
+			return usedKey == key, nil
+		}
+	}
+
+	// Validate outside the lock so a slow file search doesn't hold up
+	// redemptions of other codes; the check-and-mark below is what's atomic
+	valid, err := r.verifier.Verify(ctx, code)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if usedKey, used := r.redeemed[code]; used {
+		return key != "" && usedKey == key, nil
+	}
+	r.redeemed[code] = key
+	return true, nil
+}
+
+// Redeemed reports whether a code has been redeemed
+func (r *Redeemer) Redeemed(code string) bool {
+	code = r.verifier.Canonicalize(code)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, used := r.redeemed[code]
+	return used
+}
@@ -0,0 +1,145 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// barrierVerifier holds every Verify call until want calls have arrived, so
+// the redemptions reach the check-and-mark together
+type barrierVerifier struct {
+	CodeVerifier
+	arrived sync.WaitGroup
+}
+
+func (b *barrierVerifier) Verify(ctx context.Context, code string) (bool, error) {
+	b.arrived.Done()
+	b.arrived.Wait()
+	return b.CodeVerifier.Verify(ctx, code)
+}
+
+func newRedeemTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	validator := NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\nFIFTYOFF\nONEFILE1\n"),
+		strings.NewReader("HAPPYHRS\nFIFTYOFF\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	t.Cleanup(func() { _ = validator.Close() })
+	return validator
+}
+
+func TestRedeemer_ConcurrentRedemptions(t *testing.T) {
+	verifier := &barrierVerifier{CodeVerifier: newRedeemTestValidator(t)}
+	verifier.arrived.Add(2)
+	redeemer := NewRedeemer(verifier)
+
+	var wg sync.WaitGroup
+	results := make([]bool, 2)
+	errs := make([]error, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = redeemer.Redeem(context.Background(), "HAPPYHRS")
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for i, ok := range results {
+		if errs[i] != nil {
+			t.Fatalf("Redeem() error = %v", errs[i])
+		}
+		if ok {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent redemptions succeeded, want exactly 1", succeeded)
+	}
+	if !redeemer.Redeemed("HAPPYHRS") {
+		t.Error("expected HAPPYHRS to be marked redeemed")
+	}
+}
+
+func TestRedeemer_Redeem(t *testing.T) {
+	redeemer := NewRedeemer(newRedeemTestValidator(t))
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"valid code redeemed", "FIFTYOFF", true},
+		{"second redemption refused", "FIFTYOFF", false},
+		{"canonical form is the same coupon", "fifty-off", false},
+		{"code in one file is invalid", "ONEFILE1", false},
+		{"unknown code is invalid", "NOTACODE", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := redeemer.Redeem(context.Background(), tt.code)
+			if err != nil {
+				t.Fatalf("Redeem() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Redeem(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+
+	if redeemer.Redeemed("ONEFILE1") {
+		t.Error("invalid codes must not be marked redeemed")
+	}
+}
+
+func TestRedeemer_NotReady(t *testing.T) {
+	redeemer := NewRedeemer(NewValidator())
+
+	if _, err := redeemer.Redeem(context.Background(), "HAPPYHRS"); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Redeem() error = %v, want ErrNotReady", err)
+	}
+	if redeemer.Redeemed("HAPPYHRS") {
+		t.Error("a code that couldn't be validated must not be marked redeemed")
+	}
+}
+
+func TestRedeemer_RedeemWithKey(t *testing.T) {
+	redeemer := NewRedeemer(newRedeemTestValidator(t))
+
+	tests := []struct {
+		name string
+		code string
+		key  string
+		want bool
+	}{
+		{"first redemption", "HAPPYHRS", "order-1", true},
+		{"retry with the same key", "HAPPYHRS", "order-1", true},
+		{"retry of a canonical form", "happy-hrs", "order-1", true},
+		{"second redemption with another key", "HAPPYHRS", "order-2", false},
+		{"second redemption without a key", "HAPPYHRS", "", false},
+		{"same key on another code", "FIFTYOFF", "order-1", true},
+		{"invalid code with a key", "NOTACODE", "order-3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := redeemer.RedeemWithKey(context.Background(), tt.code, tt.key)
+			if err != nil {
+				t.Fatalf("RedeemWithKey() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RedeemWithKey(%q, %q) = %v, want %v", tt.code, tt.key, got, tt.want)
+			}
+		})
+	}
+}
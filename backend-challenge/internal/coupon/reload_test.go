@@ -0,0 +1,186 @@
+package coupon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+func TestValidator_Reload_OnlyChangedFiles(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(ctx, []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	if builds := validator.GetStats()["filterBuilds"]; builds != int64(3) {
+		t.Fatalf("filterBuilds after load = %v, want 3", builds)
+	}
+
+	// ONLYONE1 is only in file3, and the negative result gets cached
+	if validator.IsValid(ctx, "ONLYONE1") {
+		t.Fatal("expected ONLYONE1 to be invalid before reload")
+	}
+
+	original := loadedFilters(validator)
+
+	// Nothing changed: no rebuilds
+	rebuilt, err := validator.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if rebuilt != 0 {
+		t.Errorf("Reload() rebuilt %d filters, want 0", rebuilt)
+	}
+
+	// Add ONLYONE1 to file2 so it appears in two files
+	if err := os.WriteFile(file2, []byte("VALIDABC\nTESTCODE\nSPECIAL9\nCOUPON02\nBBBB2222\nONLYONE1\n"), 0644); err != nil {
+		t.Fatalf("failed to update file2: %v", err)
+	}
+
+	rebuilt, err = validator.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if rebuilt != 1 {
+		t.Errorf("Reload() rebuilt %d filters, want 1", rebuilt)
+	}
+	if builds := validator.GetStats()["filterBuilds"]; builds != int64(4) {
+		t.Errorf("filterBuilds after reload = %v, want 4", builds)
+	}
+
+	// Unchanged filters are reused in place, only file2's filter is new
+	current := loadedFilters(validator)
+	if current[0] != original[0] || current[2] != original[2] {
+		t.Error("expected unchanged filters to be reused")
+	}
+	if current[1] == original[1] {
+		t.Error("expected changed file's filter to be rebuilt")
+	}
+
+	// The stale cached result is cleared by the reload
+	if !validator.IsValid(ctx, "ONLYONE1") {
+		t.Error("expected ONLYONE1 to be valid after reload")
+	}
+}
+
+func TestValidator_FailedReloadKeepsLoadedFiles(t *testing.T) {
+	// A truncated gzip stream fails the filter build partway through
+	corrupt := []byte("\x1f\x8b\x08\x00garbage")
+
+	tests := []struct {
+		name   string
+		reload func(v *Validator, files []string) error
+	}{
+		{"load of other files", func(v *Validator, files []string) error {
+			bad := filepath.Join(t.TempDir(), "corrupt.gz")
+			if err := os.WriteFile(bad, corrupt, 0644); err != nil {
+				t.Fatalf("failed to write corrupt file: %v", err)
+			}
+			return v.LoadFromFiles(context.Background(), []string{files[0], bad})
+		}},
+		{"reload of a corrupted file", func(v *Validator, files []string) error {
+			if err := os.WriteFile(files[1], corrupt, 0644); err != nil {
+				t.Fatalf("failed to corrupt file2: %v", err)
+			}
+			_, err := v.Reload(context.Background())
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file1, file2, file3, cleanup := setupTestFiles(t)
+			defer cleanup()
+			files := []string{file1, file2, file3}
+
+			ctx := context.Background()
+			validator := NewValidatorWithOptions(ValidatorOptions{})
+			defer validator.Close()
+			if err := validator.LoadFromFiles(ctx, files); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+			original := loadedFilters(validator)
+
+			if err := tt.reload(validator, files); err == nil {
+				t.Fatal("expected the reload to fail")
+			}
+
+			if got := loadedFilters(validator); !slices.Equal(got, original) {
+				t.Error("failed reload replaced the loaded filters")
+			}
+			// VALIDABC is in file1 and file3, which are intact
+			if valid, err := validator.Verify(ctx, "VALIDABC"); err != nil || !valid {
+				t.Errorf("Verify(VALIDABC) = %v, %v, want true", valid, err)
+			}
+		})
+	}
+}
+
+func TestValidator_CloneReloadsOffServingValidator(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	validator := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity, UseMmap: true})
+	if err := validator.LoadFromFiles(ctx, []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	original := loadedFilters(validator)
+
+	if err := os.WriteFile(file2, []byte("VALIDABC\nTESTCODE\nSPECIAL9\nCOUPON02\nBBBB2222\nONLYONE1\n"), 0644); err != nil {
+		t.Fatalf("failed to update file2: %v", err)
+	}
+
+	clone, err := validator.Clone("")
+	if err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	defer clone.Close()
+
+	rebuilt, err := clone.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if rebuilt != 1 {
+		t.Errorf("Reload() rebuilt %d filters, want 1", rebuilt)
+	}
+
+	// The clone shares the unchanged filters and leaves the original alone
+	cloned := loadedFilters(clone)
+	if cloned[0] != original[0] || cloned[2] != original[2] || cloned[1] == original[1] {
+		t.Error("expected the clone to rebuild only file2's filter")
+	}
+	if !slices.Equal(loadedFilters(validator), original) {
+		t.Error("reloading the clone changed the original's filters")
+	}
+
+	// The clone keeps searching its own mappings once the original is closed
+	if err := validator.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if valid, err := clone.Verify(ctx, "ONLYONE1"); err != nil || !valid {
+		t.Errorf("clone Verify(ONLYONE1) = %v, %v, want true", valid, err)
+	}
+}
+
+func TestValidator_Reload_NotLoaded(t *testing.T) {
+	validator := NewValidator()
+	if _, err := validator.Reload(context.Background()); err == nil {
+		t.Error("expected error when reloading before load, got nil")
+	}
+}
+
+// loadedFilters returns a snapshot of the validator's current Bloom filters
+func loadedFilters(v *Validator) []*bloom.BloomFilter {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return slices.Clone(v.bloomFilters)
+}
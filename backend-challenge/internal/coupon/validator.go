@@ -2,14 +2,33 @@ package coupon
 
 import (
 	"bufio"
+	"bytes"
 	"container/list"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/timing"
 	"github.com/bits-and-blooms/bloom/v3"
+	"golang.org/x/sync/singleflight"
 )
 
 // Validator validates coupon codes against multiple coupon files
@@ -17,6 +36,7 @@ import (
 // The Problem:
 // We have ~320 million coupon codes across 3 files (1GB each).
 // A code is valid only if it appears in at least 2 files.
+// (Files can be weighted, see ValidatorOptions.FileWeights.)
 //
 // Why We Chose This Architecture:
 //
@@ -58,18 +78,294 @@ import (
 type Validator struct {
 	filePaths    []string
 	bloomFilters []*bloom.BloomFilter
-	cache        *lruCache
+	checksums    []string        // SHA-256 of each file when its filter was built
+	buildTimes   []time.Duration // how long each file's filter took to build
+	modTimes     []time.Time     // each file's mtime when its filter was built, zero for readers
+	sources      []urlSource     // download state when loaded via LoadFromURLs
+	filterBuilds atomic.Int64
+	cacheHits    atomic.Int64
+	cacheMisses  atomic.Int64
+	bloomExits   atomic.Int64 // validations the Bloom filters answered without a file search
+	fileSearches atomic.Int64 // coupon file scans, including retries, Exists and Inspect
+	fallbacks    atomic.Int64 // validations answered by Bloom filters alone, see TrustBloomOnError
+	snapshot     atomic.Pointer[statsSnapshot]
+	cache        *lruCache  // nil, like existCache, when caching is disabled
+	negCache     *lruCache  // optional cache for invalid results, nil when they share cache
+	existCache   *lruCache  // Exists results, kept apart from validity
+	diskCache    *diskCache // optional second-level cache, nil when disabled
+	options      ValidatorOptions
+	allowedChars map[rune]bool
+	overlap      map[int]int64          // files-containing-code -> number of codes
+	overlapCut   bool                   // overlap computation hit its time budget
+	overrides    map[string]bool        // forced results that bypass cache, Bloom and files
+	expiries     map[string]time.Time   // canonical code -> when it stops being valid
+	searchSem    chan struct{}          // bounds concurrent file searches across requests
+	goroutines   *goroutineBudget       // bounds the goroutines spawned across requests
+	searches     singleflight.Group     // shares in-flight file searches per code
+	mapped       map[string]*mappedFile // memory-mapped files by path when UseMmap is set
+	breaker      *searchBreaker         // fails verification fast while file searches keep failing
+	loaded       bool                   // a load completed and the filters are usable
+	fromReaders  bool                   // loaded by LoadFromReaders, so there are no files to reread
 	mu           sync.RWMutex
 }
 
+// Default coupon length rule
+const (
+	DefaultMinLength = 8
+	DefaultMaxLength = 10
+)
+
+// DefaultAllowedChars is the default set of characters a coupon code may contain
+// Codes are canonicalized (upper case, no formatting) before the check
+const DefaultAllowedChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// CanonicalizeCode is the default code canonicalization: upper case with
+// dashes and whitespace removed, so "happy-hrs" and "Happy Hrs" both become
+// HAPPYHRS
+func CanonicalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// DefaultExpectedItems is the default number of codes per file the Bloom
+// filters are sized for
+const DefaultExpectedItems = 100000000
+
+// DefaultFalsePositiveRate is the default Bloom filter false-positive rate;
+// with DefaultExpectedItems each filter takes about 120MB
+const DefaultFalsePositiveRate = 0.01
+
+// DefaultCacheCapacity is the number of in-memory cached validations
+// NewValidator uses
+const DefaultCacheCapacity = 10000
+
+// DefaultCacheMemoryCheckInterval is the default least time between heap
+// checks when the caches evict under memory pressure
+const DefaultCacheMemoryCheckInterval = time.Second
+
+// DefaultDiskCacheCapacity is the default number of on-disk cache entries
+const DefaultDiskCacheCapacity = 1000000
+
+// DefaultMaxConcurrentSearches is the default limit on concurrent file searches
+const DefaultMaxConcurrentSearches = 16
+
+// DefaultMaxConcurrentDownloads is the default limit on concurrent coupon file downloads
+const DefaultMaxConcurrentDownloads = 4
+
+// DefaultDownloadTimeout is the default limit on a single coupon file
+// download; generous since the files run to gigabytes
+const DefaultDownloadTimeout = 10 * time.Minute
+
+// requiredWeight is the total file weight a code must reach to be valid;
+// with every file weighing 1 that is "in at least 2 files"
+const requiredWeight = 2
+
+// ErrNotReady is returned by Verify before a load has completed, when the
+// validator can't yet tell a valid code from an invalid one
+var ErrNotReady = errors.New("coupon validator is not ready")
+
+// searchFile is the file search used by the validator, replaceable in tests
+var searchFile = searchFileForCoupon
+
+// ValidatorOptions configures optional validator behaviour
+// Zero values fall back to the defaults
+type ValidatorOptions struct {
+	// MinLength and MaxLength bound the accepted coupon code length in
+	// characters (runes), not bytes. Matching itself compares raw bytes
+	MinLength int
+	MaxLength int
+
+	// AllowedChars lists every character a normalized code may contain
+	// Codes with any other character are rejected before the Bloom check
+	// Defaults to DefaultAllowedChars
+	AllowedChars string
+
+	// MaxCacheKeyLength is the longest code stored in the LRU cache, bounding
+	// cache memory to capacity × MaxCacheKeyLength even if the length rule is
+	// relaxed. Longer codes are still validated, just not cached
+	// Measured in bytes since it bounds memory. Defaults to MaxLength
+	MaxCacheKeyLength int
+
+	// CacheCapacity is how many validation results the in-memory LRU cache
+	// holds, typically DefaultCacheCapacity. Unlike the other options, zero
+	// doesn't mean the default: it turns caching off, so every validation
+	// goes to the Bloom filters and files and the negative and disk caches
+	// are unused too
+	CacheCapacity int
+
+	// NegativeCacheCapacity gives invalid results an LRU cache of their own
+	// with this capacity, so a stream of bad codes can't evict hot valid
+	// codes. Zero keeps invalid results in the shared cache
+	NegativeCacheCapacity int
+
+	// CacheMemoryBudget switches the in-memory caches to memory-pressure
+	// eviction: they may grow past their capacity during bursts, keeping more
+	// hot entries, and are trimmed back to capacity once the heap exceeds this
+	// many bytes. Zero keeps strict capacity eviction
+	CacheMemoryBudget uint64
+
+	// CacheMemoryCheckInterval is the least time between heap checks in
+	// memory-pressure mode, since reading memory stats briefly stops the
+	// world. Defaults to DefaultCacheMemoryCheckInterval
+	CacheMemoryCheckInterval time.Duration
+
+	// DiskCachePath enables a bounded on-disk second-level cache at this path
+	// Entries evicted from the in-memory LRU spill to it and are consulted on
+	// a cache miss before the Bloom/file path. Disabled when empty
+	DiskCachePath string
+
+	// DiskCacheCapacity is the maximum number of on-disk cache entries
+	// Defaults to DefaultDiskCacheCapacity
+	DiskCacheCapacity int
+
+	// ComputeOverlap counts at load time how many distinct codes appear in
+	// exactly 1, 2, ... N files. This streams every file through an external
+	// sort so memory stays bounded, but adds significant load time
+	ComputeOverlap bool
+
+	// OverlapMaxDuration bounds the overlap computation; when exceeded the
+	// counts gathered so far are kept and flagged as truncated in the stats
+	// Zero means no limit beyond the load context's deadline
+	OverlapMaxDuration time.Duration
+
+	// MaxConcurrentSearches caps how many file searches run at once across
+	// all requests, so a burst of cache misses can't saturate the disk
+	// Defaults to DefaultMaxConcurrentSearches
+	MaxConcurrentSearches int
+
+	// MaxGoroutines caps how many goroutines the validator runs at once for
+	// searches and filter builds, across all requests. Work that finds the
+	// budget spent runs on the calling goroutine instead of spawning one
+	// Defaults to DefaultMaxGoroutines
+	MaxGoroutines int
+
+	// MaxConcurrentDownloads caps how many coupon files LoadFromURLs and
+	// ReloadFromURLs download at once, so many large sources don't saturate
+	// bandwidth. Independent of the Bloom filter builds, which run per file
+	// Defaults to DefaultMaxConcurrentDownloads
+	MaxConcurrentDownloads int
+
+	// HTTPClient downloads coupon files for LoadFromURLs and ReloadFromURLs,
+	// e.g. one with a custom TLS config or proxy. Defaults to a client using
+	// the default transport (proxy from the environment) with DownloadTimeout
+	HTTPClient *http.Client
+
+	// DownloadTimeout bounds each coupon file download, including reading
+	// the body, when HTTPClient is not set. Defaults to DefaultDownloadTimeout
+	DownloadTimeout time.Duration
+
+	// Canonicalize maps a submitted code to the form stored in the coupon
+	// files, before the length check and every other rule. File lines are
+	// only trimmed, so files holding formatted codes (e.g. HAPPY-HRS) must
+	// be canonicalized the same way before loading. Defaults to CanonicalizeCode
+	Canonicalize func(string) string
+
+	// UseMmap memory-maps the coupon files at load time and verifies codes
+	// against the mapped bytes instead of streaming each file, letting the OS
+	// page cache serve repeated searches. Files must be replaced atomically
+	// (as downloads are), never truncated in place, while they are mapped
+	UseMmap bool
+
+	// BreakerThreshold is how many file verifications in a row may fail (read
+	// errors or deadlines) before the circuit breaker opens and verification
+	// fails fast with ErrSearchUnavailable. Defaults to DefaultBreakerThreshold
+	BreakerThreshold int
+
+	// BreakerCooldown is how long the breaker stays open before a single
+	// verification is let through to probe the disk again
+	// Defaults to DefaultBreakerCooldown
+	BreakerCooldown time.Duration
+
+	// FileWeights maps coupon file base names (e.g. "couponbase1") to how
+	// much a match in that file counts toward the 2 a valid code needs, so
+	// an authoritative file of weight 2 validates a code on its own. Files
+	// not listed, and non-positive weights, count 1
+	FileWeights map[string]int
+
+	// TrustBloomOnError answers from the Bloom filters alone when verifying
+	// a code against the files fails, e.g. after the files were deleted or
+	// unmounted, accepting the ~1% false-positive rate instead of reporting
+	// every such code unverifiable. Each fallback is logged as an error.
+	// Off by default
+	TrustBloomOnError bool
+
+	// Logger receives validator events such as TrustBloomOnError fallbacks
+	// Defaults to discarding them
+	Logger *slog.Logger
+
+	// FilterBuildWorkers is how many goroutines hash the lines of each file
+	// while its Bloom filter is built; reading stays sequential. Files
+	// already build concurrently, so this only helps with more cores than
+	// files, and each line costs a small allocation. Defaults to 1 (serial)
+	FilterBuildWorkers int
+
+	// Expiries maps coupon codes to the instant they stop being valid. An
+	// expired code found in the files is reported invalid with ReasonExpired;
+	// codes not listed never expire. Keys are canonicalized like submitted codes
+	Expiries map[string]time.Time
+
+	// Now is the clock expiry is checked against. Defaults to time.Now
+	Now func() time.Time
+
+	// ExpectedItems and FalsePositiveRate size each file's Bloom filter: it
+	// takes about 1.2 bytes per expected item at 1%, more for lower rates
+	// Files with more codes than expected get a higher false-positive rate,
+	// costing extra file searches. Default to DefaultExpectedItems and
+	// DefaultFalsePositiveRate; rates outside (0, 1) use the default
+	ExpectedItems     uint
+	FalsePositiveRate float64
+}
+
 // lruCache implements a simple LRU cache for validated coupons
 type lruCache struct {
 	capacity int
+	pressure *memoryPressure // when set, capacity is only enforced under memory pressure
 	items    map[string]*list.Element
 	order    *list.List
+	onEvict  func(key string, valid bool) // called with evicted entries, may be nil
+	size     atomic.Int64                 // entry count, readable without mu
 	mu       sync.RWMutex
 }
 
+// memoryPressure tells an LRU cache when the heap has outgrown its budget
+type memoryPressure struct {
+	budget    uint64
+	interval  time.Duration
+	heapAlloc func() uint64    // replaceable in tests
+	now       func() time.Time // replaceable in tests
+	lastCheck time.Time
+}
+
+// newMemoryPressure creates a check of the heap against budget, reading
+// memory stats at most once per interval
+func newMemoryPressure(budget uint64, interval time.Duration) *memoryPressure {
+	return &memoryPressure{
+		budget:   budget,
+		interval: interval,
+		heapAlloc: func() uint64 {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return stats.HeapAlloc
+		},
+		now: time.Now,
+	}
+}
+
+// exceeded reports whether the heap is over budget, answering false between
+// checks. Callers serialize calls
+func (p *memoryPressure) exceeded() bool {
+	now := p.now()
+	if now.Sub(p.lastCheck) < p.interval {
+		return false
+	}
+	p.lastCheck = now
+	return p.heapAlloc() > p.budget
+}
+
 type cacheEntry struct {
 	key   string
 	valid bool
@@ -110,160 +406,814 @@ func (c *lruCache) Set(key string, valid bool) {
 		return
 	}
 
-	if c.order.Len() >= c.capacity {
-		// Remove least recently used
-		oldest := c.order.Back()
-		if oldest != nil {
-			c.order.Remove(oldest)
-			delete(c.items, oldest.Value.(*cacheEntry).key)
+	// Under memory pressure an oversized cache is trimmed in one go, making
+	// room for the new entry like strict eviction does
+	if c.pressure == nil || c.pressure.exceeded() {
+		for c.order.Len() >= c.capacity {
+			c.evictOldestLocked()
 		}
 	}
 
 	entry := &cacheEntry{key: key, valid: valid}
 	elem := c.order.PushFront(entry)
 	c.items[key] = elem
+	c.size.Store(int64(c.order.Len()))
 }
 
-// NewValidator creates a new coupon validator
+// evictOldestLocked removes the least recently used entry
+func (c *lruCache) evictOldestLocked() {
+	oldest := c.order.Back()
+	if oldest == nil {
+		return
+	}
+	c.order.Remove(oldest)
+	c.size.Store(int64(c.order.Len()))
+	evicted := oldest.Value.(*cacheEntry)
+	delete(c.items, evicted.key)
+	if c.onEvict != nil {
+		c.onEvict(evicted.key, evicted.valid)
+	}
+}
+
+// Clear removes all entries from the cache
+func (c *lruCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.size.Store(0)
+}
+
+// Len returns the number of cached entries without taking the cache lock
+func (c *lruCache) Len() int {
+	return int(c.size.Load())
+}
+
+// NewValidator creates a new coupon validator with default options
 func NewValidator() *Validator {
-	return &Validator{
-		filePaths: make([]string, 0),
-		cache:     newLRUCache(10000), // Cache last 10,000 validations
+	return NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity})
+}
+
+// NewValidatorWithOptions creates a new coupon validator with the given options
+func NewValidatorWithOptions(opts ValidatorOptions) *Validator {
+	if opts.MinLength <= 0 {
+		opts.MinLength = DefaultMinLength
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = DefaultMaxLength
 	}
+	if opts.MaxCacheKeyLength <= 0 {
+		opts.MaxCacheKeyLength = opts.MaxLength
+	}
+	if opts.AllowedChars == "" {
+		opts.AllowedChars = DefaultAllowedChars
+	}
+	if opts.CacheCapacity < 0 {
+		opts.CacheCapacity = 0
+	}
+	if opts.CacheMemoryCheckInterval <= 0 {
+		opts.CacheMemoryCheckInterval = DefaultCacheMemoryCheckInterval
+	}
+	if opts.DiskCacheCapacity <= 0 {
+		opts.DiskCacheCapacity = DefaultDiskCacheCapacity
+	}
+	if opts.MaxConcurrentSearches <= 0 {
+		opts.MaxConcurrentSearches = DefaultMaxConcurrentSearches
+	}
+	if opts.MaxConcurrentDownloads <= 0 {
+		opts.MaxConcurrentDownloads = DefaultMaxConcurrentDownloads
+	}
+	if opts.MaxGoroutines <= 0 {
+		opts.MaxGoroutines = DefaultMaxGoroutines
+	}
+	if opts.DownloadTimeout <= 0 {
+		opts.DownloadTimeout = DefaultDownloadTimeout
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: opts.DownloadTimeout}
+	}
+	if opts.Canonicalize == nil {
+		opts.Canonicalize = CanonicalizeCode
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if opts.FilterBuildWorkers <= 0 {
+		opts.FilterBuildWorkers = 1
+	}
+	if opts.ExpectedItems == 0 {
+		opts.ExpectedItems = DefaultExpectedItems
+	}
+	if opts.FalsePositiveRate <= 0 || opts.FalsePositiveRate >= 1 {
+		opts.FalsePositiveRate = DefaultFalsePositiveRate
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	allowedChars := make(map[rune]bool, len(opts.AllowedChars))
+	for _, c := range opts.AllowedChars {
+		allowedChars[c] = true
+	}
+
+	v := &Validator{
+		filePaths:    make([]string, 0),
+		options:      opts,
+		allowedChars: allowedChars,
+		searchSem:    make(chan struct{}, opts.MaxConcurrentSearches),
+		goroutines:   newGoroutineBudget(opts.MaxGoroutines),
+		breaker:      newSearchBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		expiries:     canonicalExpiries(opts.Expiries, opts.Canonicalize),
+	}
+	// A disabled cache stays nil rather than having capacity zero, which
+	// would evict on every insert
+	if opts.CacheCapacity > 0 {
+		v.cache = newLRUCache(opts.CacheCapacity)
+		v.existCache = newLRUCache(opts.CacheCapacity)
+		if opts.NegativeCacheCapacity > 0 {
+			v.negCache = newLRUCache(opts.NegativeCacheCapacity)
+		}
+	}
+	if opts.CacheMemoryBudget > 0 {
+		for _, cache := range []*lruCache{v.cache, v.negCache} {
+			if cache != nil {
+				cache.pressure = newMemoryPressure(opts.CacheMemoryBudget, opts.CacheMemoryCheckInterval)
+			}
+		}
+	}
+	return v
 }
 
-// LoadFromFiles loads coupon file paths and builds Bloom filters
-// Bloom filters provide memory-efficient probabilistic data structure
-func (v *Validator) LoadFromFiles(ctx context.Context, filePaths []string) error {
+// couponSource is one coupon file being loaded: the path searches know it
+// by, and its raw contents when they are held in memory instead of on disk
+type couponSource struct {
+	path string
+	data []byte // nil for files read from path
+}
+
+// open returns the source's raw contents for a filter build
+func (s couponSource) open() (io.ReadCloser, error) {
+	if s.data != nil {
+		return io.NopCloser(bytes.NewReader(s.data)), nil
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	return file, nil
+}
+
+// openFiles returns a source per coupon file, checking each is accessible
+func openFiles(filePaths []string) ([]couponSource, error) {
 	if len(filePaths) == 0 {
-		return fmt.Errorf("no file paths provided")
+		return nil, fmt.Errorf("no file paths provided")
 	}
 
-	// Verify all files exist and are readable
+	sources := make([]couponSource, len(filePaths))
 	for i, path := range filePaths {
 		if _, err := os.Stat(path); err != nil {
 			if os.IsNotExist(err) {
-				return fmt.Errorf("file %d does not exist: %s", i+1, path)
+				return nil, fmt.Errorf("file %d does not exist: %s", i+1, path)
+			}
+			return nil, fmt.Errorf("cannot access file %d: %w", i+1, err)
+		}
+		sources[i] = couponSource{path: path}
+	}
+	return sources, nil
+}
+
+// sourcePaths returns the paths of sources
+func sourcePaths(sources []couponSource) []string {
+	paths := make([]string, len(sources))
+	for i, src := range sources {
+		paths[i] = src.path
+	}
+	return paths
+}
+
+// LoadFromFiles loads coupon file paths and builds Bloom filters
+// Bloom filters provide memory-efficient probabilistic data structure
+func (v *Validator) LoadFromFiles(ctx context.Context, filePaths []string) error {
+	sources, err := openFiles(filePaths)
+	if err != nil {
+		return err
+	}
+	return v.load(ctx, sources, nil, v.buildFilters)
+}
+
+// LoadFromReaders loads coupon data from readers instead of files, e.g. stdin
+// or in-memory fixtures; each reader is one coupon file and may be gzipped
+//
+// Readers can't be searched again, so their contents are held in memory for
+// the verification tier: this is meant for tests and small piped sources,
+// not the 1GB production files. Overlap statistics aren't computed and
+// Reload has nothing to reread
+func (v *Validator) LoadFromReaders(ctx context.Context, readers []io.Reader) error {
+	if len(readers) == 0 {
+		return fmt.Errorf("no readers provided")
+	}
+
+	sources := make([]couponSource, len(readers))
+	for i, r := range readers {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read reader %d: %w", i, err)
+		}
+		// Readers have no path; name them so stats and searches can tell them apart
+		sources[i] = couponSource{path: fmt.Sprintf("reader:%d", i), data: data}
+	}
+	return v.load(ctx, sources, nil, v.buildFilters)
+}
+
+// load builds the Bloom filters of sources with build and replaces the
+// loaded coupon data with them, along with downloads when they came from
+// URLs. Everything is built before the validator lock is taken, and the
+// loaded data is only replaced once all of it succeeded, so a failed load
+// leaves the previous data serving
+func (v *Validator) load(ctx context.Context, sources []couponSource, downloads []urlSource, build func(context.Context, []couponSource) ([]filterBuild, error)) error {
+	builds, err := build(ctx, sources)
+	if err != nil {
+		return err
+	}
+
+	paths := sourcePaths(sources)
+	inMemory := sources[0].data != nil
+
+	// Optionally compute how codes are distributed across files
+	var overlap map[int]int64
+	var overlapCut bool
+	if v.options.ComputeOverlap && !inMemory {
+		if overlap, overlapCut, err = computeOverlap(ctx, paths, v.options.OverlapMaxDuration); err != nil {
+			return fmt.Errorf("failed to compute overlap statistics: %w", err)
+		}
+	}
+
+	// Searches read in-memory sources from their mappings
+	var mapped map[string]*mappedFile
+	switch {
+	case inMemory:
+		mapped = make(map[string]*mappedFile, len(sources))
+		for i, src := range sources {
+			data, err := gunzipBytes(src.data)
+			if err != nil {
+				return fmt.Errorf("failed to read reader %d: %w", i, err)
 			}
-			return fmt.Errorf("cannot access file %d: %w", i+1, err)
+			mapped[src.path] = &mappedFile{data: data, inMemory: true}
+		}
+	case v.options.UseMmap:
+		if mapped, err = mapCouponFiles(paths); err != nil {
+			return err
 		}
 	}
 
+	// Mappings being replaced are released once the lock is dropped, after
+	// in-flight searches on them finish
+	var stale map[string]*mappedFile
+	defer func() { closeMappings(stale) }()
+
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	defer v.publishStatsLocked()
+
+	if err := v.openDiskCacheLocked(); err != nil {
+		stale = mapped
+		return err
+	}
+
+	v.fromReaders = inMemory
+	v.filePaths = paths
+	v.bloomFilters = make([]*bloom.BloomFilter, len(builds))
+	v.checksums = make([]string, len(builds))
+	v.buildTimes = make([]time.Duration, len(builds))
+	v.modTimes = make([]time.Time, len(builds))
+	v.sources = downloads
+	stale, v.mapped = v.mapped, mapped
+	for i, build := range builds {
+		v.bloomFilters[i] = build.filter
+		v.checksums[i] = build.checksum
+		v.buildTimes[i] = build.duration
+		v.modTimes[i] = build.modTime
+	}
+	v.overlap = overlap
+	v.overlapCut = overlapCut
+	v.loaded = true
+
+	return nil
+}
+
+// openDiskCacheLocked opens the optional disk cache on first load and
+// spills LRU evictions to it. Callers hold v.mu
+func (v *Validator) openDiskCacheLocked() error {
+	if v.options.DiskCachePath == "" || v.diskCache != nil || v.cache == nil {
+		return nil
+	}
 
-	v.filePaths = filePaths
-	v.bloomFilters = make([]*bloom.BloomFilter, len(filePaths))
+	diskCache, err := openDiskCache(v.options.DiskCachePath, v.options.DiskCacheCapacity)
+	if err != nil {
+		return err
+	}
+	v.diskCache = diskCache
+
+	spill := func(key string, valid bool) {
+		// Best effort: a failed spill just means a future cache miss
+		_ = diskCache.Set(key, valid)
+	}
+	for _, cache := range []*lruCache{v.cache, v.negCache} {
+		if cache == nil {
+			continue
+		}
+		cache.mu.Lock()
+		cache.onEvict = spill
+		cache.mu.Unlock()
+	}
+	return nil
+}
 
-	// Build Bloom filter for each file concurrently
+// LoadFromDir loads every file in dir whose name matches glob (e.g. "*.txt")
+// Files are loaded in lexical order so file indexes are stable between runs;
+// an empty glob matches all files and subdirectories are skipped
+func (v *Validator) LoadFromDir(ctx context.Context, dir string, glob string) error {
+	if glob == "" {
+		glob = "*"
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, glob))
+	if err != nil {
+		return fmt.Errorf("invalid coupon file pattern %q: %w", glob, err)
+	}
+
+	filePaths := make([]string, 0, len(matches))
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("cannot access coupon file %s: %w", path, err)
+		}
+		if info.Mode().IsRegular() {
+			filePaths = append(filePaths, path)
+		}
+	}
+	if len(filePaths) == 0 {
+		return fmt.Errorf("no coupon files matching %q in %s", glob, dir)
+	}
+	slices.Sort(filePaths)
+
+	return v.LoadFromFiles(ctx, filePaths)
+}
+
+// Reload rebuilds the Bloom filters of coupon files whose checksum changed
+// since they were last loaded and returns how many filters were rebuilt
+//
+// Unchanged filters are kept in place, so a change to one 1GB file doesn't
+// cost a rebuild of all three. Validations keep using the previous filters
+// until the rebuilt ones are swapped in.
+func (v *Validator) Reload(ctx context.Context) (int, error) {
+	v.mu.RLock()
+	filePaths := v.filePaths
+	checksums := v.checksums
+	fromReaders := v.fromReaders
+	v.mu.RUnlock()
+
+	if len(filePaths) == 0 {
+		return 0, fmt.Errorf("no coupon files loaded")
+	}
+	if fromReaders {
+		return 0, fmt.Errorf("coupon data loaded from readers cannot be reloaded")
+	}
+
+	// Find files whose contents changed
+	var changed []int
+	for i, path := range filePaths {
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			return 0, fmt.Errorf("checksumming file %d: %w", i+1, err)
+		}
+		if checksum != checksums[i] {
+			changed = append(changed, i)
+		}
+	}
+
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	changedSources := make([]couponSource, len(changed))
+	for j, i := range changed {
+		changedSources[j] = couponSource{path: filePaths[i]}
+	}
+	changedPaths := sourcePaths(changedSources)
+
+	builds, err := v.buildFilters(ctx, changedSources)
+	if err != nil {
+		return 0, err
+	}
+
+	var overlap map[int]int64
+	var overlapCut bool
+	if v.options.ComputeOverlap {
+		if overlap, overlapCut, err = computeOverlap(ctx, filePaths, v.options.OverlapMaxDuration); err != nil {
+			return 0, fmt.Errorf("failed to compute overlap statistics: %w", err)
+		}
+	}
+
+	var remapped map[string]*mappedFile
+	if v.options.UseMmap {
+		if remapped, err = mapCouponFiles(changedPaths); err != nil {
+			return 0, err
+		}
+	}
+
+	// Replaced mappings are released once the lock is dropped
+	stale := remapped
+	defer func() { closeMappings(stale) }()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	defer v.publishStatsLocked()
+
+	if !slices.Equal(v.filePaths, filePaths) {
+		return 0, fmt.Errorf("coupon files were replaced during reload")
+	}
+
+	if remapped != nil {
+		mapped := maps.Clone(v.mapped)
+		stale = make(map[string]*mappedFile, len(remapped))
+		for path, m := range remapped {
+			if old, ok := mapped[path]; ok {
+				stale[path] = old
+			}
+			mapped[path] = m
+		}
+		v.mapped = mapped
+	}
+
+	// Copy on write: in-flight validations keep their snapshot of the slices
+	bloomFilters := slices.Clone(v.bloomFilters)
+	newChecksums := slices.Clone(v.checksums)
+	buildTimes := slices.Clone(v.buildTimes)
+	modTimes := slices.Clone(v.modTimes)
+	for j, i := range changed {
+		bloomFilters[i] = builds[j].filter
+		newChecksums[i] = builds[j].checksum
+		buildTimes[i] = builds[j].duration
+		modTimes[i] = builds[j].modTime
+	}
+	v.bloomFilters = bloomFilters
+	v.checksums = newChecksums
+	v.buildTimes = buildTimes
+	v.modTimes = modTimes
+	if v.options.ComputeOverlap {
+		v.overlap = overlap
+		v.overlapCut = overlapCut
+	}
+
+	// Cached results may no longer hold for the new data
+	if v.cache != nil {
+		v.cache.Clear()
+		v.existCache.Clear()
+	}
+	if v.negCache != nil {
+		v.negCache.Clear()
+	}
+	if v.diskCache != nil {
+		if err := v.diskCache.Clear(); err != nil {
+			return 0, fmt.Errorf("clearing disk cache: %w", err)
+		}
+	}
+
+	return len(changed), nil
+}
+
+// Clone returns a new validator with v's options and loaded coupon data but
+// empty caches, so a reload can run off the serving path: Reload or
+// ReloadFromURLs the clone, which keeps the filters, checksums and download
+// validators of unchanged files, then swap it in with
+// ValidatorProvider.SwapTo. diskCachePath replaces the DiskCachePath option,
+// since closing v removes its disk cache file
+func (v *Validator) Clone(diskCachePath string) (*Validator, error) {
+	opts := v.options
+	opts.DiskCachePath = diskCachePath
+	clone := NewValidatorWithOptions(opts)
+
+	v.mu.RLock()
+	clone.filePaths = slices.Clone(v.filePaths)
+	clone.bloomFilters = slices.Clone(v.bloomFilters)
+	clone.checksums = slices.Clone(v.checksums)
+	clone.buildTimes = slices.Clone(v.buildTimes)
+	clone.modTimes = slices.Clone(v.modTimes)
+	clone.sources = slices.Clone(v.sources)
+	clone.overlap = v.overlap
+	clone.overlapCut = v.overlapCut
+	clone.loaded = v.loaded
+	clone.fromReaders = v.fromReaders
+	mapped := v.mapped
+	v.mu.RUnlock()
+	clone.filterBuilds.Store(v.filterBuilds.Load())
+
+	// v releases its mappings when closed, so the clone needs its own
+	var err error
+	switch {
+	case clone.fromReaders:
+		clone.mapped = make(map[string]*mappedFile, len(mapped))
+		for path, m := range mapped {
+			m.mu.RLock()
+			clone.mapped[path] = &mappedFile{data: m.data, inMemory: true}
+			m.mu.RUnlock()
+		}
+	case opts.UseMmap && len(clone.filePaths) > 0:
+		if clone.mapped, err = mapCouponFiles(clone.filePaths); err != nil {
+			return nil, err
+		}
+	}
+
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+	defer clone.publishStatsLocked()
+
+	if err := clone.openDiskCacheLocked(); err != nil {
+		closeMappings(clone.mapped)
+		return nil, err
+	}
+	return clone, nil
+}
+
+// filterBuild is the result of building a Bloom filter from one file
+type filterBuild struct {
+	filter   *bloom.BloomFilter
+	checksum string
+	duration time.Duration
+	modTime  time.Time
+}
+
+// buildFilters builds a Bloom filter for each source concurrently
+func (v *Validator) buildFilters(ctx context.Context, sources []couponSource) ([]filterBuild, error) {
 	type result struct {
-		index  int
-		filter *bloom.BloomFilter
-		err    error
+		index int
+		build filterBuild
+		err   error
 	}
 
-	resultsCh := make(chan result, len(filePaths))
+	resultsCh := make(chan result, len(sources))
 	var wg sync.WaitGroup
 
-	for i, path := range filePaths {
+	for index, src := range sources {
 		wg.Add(1)
-		go func(index int, filePath string) {
+		v.goroutines.goOrRun(func() {
 			defer wg.Done()
 
-			filter, err := v.buildBloomFilter(ctx, filePath)
+			// Taken before reading, so the data is at least this fresh
+			var modTime time.Time
+			if src.data == nil {
+				if info, err := os.Stat(src.path); err == nil {
+					modTime = info.ModTime()
+				}
+			}
+
+			start := time.Now()
+			filter, checksum, err := v.buildBloomFilter(ctx, src)
 			resultsCh <- result{
-				index:  index,
-				filter: filter,
-				err:    err,
+				index: index,
+				build: filterBuild{filter: filter, checksum: checksum, duration: time.Since(start), modTime: modTime},
+				err:   err,
 			}
-		}(i, path)
+		})
 	}
 
-	go func() {
+	v.goroutines.goOrRun(func() {
 		wg.Wait()
 		close(resultsCh)
-	}()
+	})
 
 	// Collect results
+	builds := make([]filterBuild, len(sources))
+	var firstErr error
 	for res := range resultsCh {
 		if res.err != nil {
-			return fmt.Errorf("failed to build Bloom filter for file %d: %w", res.index, res.err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to build Bloom filter for file %d: %w", res.index, res.err)
+			}
+			continue
 		}
-		v.bloomFilters[res.index] = res.filter
+		builds[res.index] = res.build
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return builds, nil
+}
+
+// buildBloomFilter creates a Bloom filter from a coupon source and returns
+// it along with the source's SHA-256 checksum, computed in the same pass
+// Gzipped files are decompressed as they are scanned, never held in memory
+// whole; the checksum covers the file as stored on disk
+// The filter is sized by ExpectedItems and FalsePositiveRate
+func (v *Validator) buildBloomFilter(ctx context.Context, src couponSource) (*bloom.BloomFilter, string, error) {
+	r, err := src.open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	return v.buildBloomFilterFrom(ctx, r)
+}
+
+// buildBloomFilterFrom builds a Bloom filter from the coupon data read from
+// r, gzipped or not, and returns it with the SHA-256 of r's raw bytes
+func (v *Validator) buildBloomFilterFrom(ctx context.Context, r io.Reader) (*bloom.BloomFilter, string, error) {
+	v.filterBuilds.Add(1)
+	hasher := sha256.New()
+
+	filter := bloom.NewWithEstimates(v.options.ExpectedItems, v.options.FalsePositiveRate)
+
+	contents, err := maybeGunzip(io.TeeReader(r, hasher))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if workers := v.options.FilterBuildWorkers; workers > 1 {
+		err = addLinesParallel(ctx, contents, filter, workers)
+	} else {
+		err = addLines(ctx, contents, filter)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Hash anything left unread after the end of a gzip stream too
+	if _, err := io.Copy(hasher, r); err != nil {
+		return nil, "", fmt.Errorf("checksumming data: %w", err)
+	}
+
+	return filter, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// fileChecksum returns the hex SHA-256 checksum of a file
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
 	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// IsValid checks if a coupon code is valid
+// A coupon is valid if:
+// 1. It has 8-10 characters (configurable via MinLength/MaxLength)
+// 2. It only contains characters from AllowedChars (A-Z, 0-9 by default)
+// 3. It appears in at least 2 of the loaded files, summing FileWeights
+// Uses LRU cache + Bloom filters + streaming for optimal performance
+func (v *Validator) IsValid(ctx context.Context, code string) bool {
+	return v.Evaluate(ctx, code, EvaluateOptions{}).Valid
+}
+
+// EvaluateOptions adjusts a single validation, e.g. for analytics tooling
+// The zero value applies every rule, exactly like IsValid
+type EvaluateOptions struct {
+	// SkipLengthCheck validates codes outside MinLength..MaxLength against the files
+	SkipLengthCheck bool
+}
 
-	return nil
+// Reason explains a validation verdict
+type Reason string
+
+// Validation reasons reported by Evaluate
+const (
+	ReasonValid        Reason = "valid"
+	ReasonOverride     Reason = "override"
+	ReasonTooShort     Reason = "too_short"
+	ReasonTooLong      Reason = "too_long"
+	ReasonInvalidChars Reason = "invalid_characters"
+	ReasonNotFound     Reason = "not_found"
+	ReasonUnavailable  Reason = "unavailable"
+	ReasonBloomOnly    Reason = "bloom_only" // valid per the Bloom filters only, see TrustBloomOnError
+	ReasonExpired      Reason = "expired"    // in the files but past its expiry, see ValidatorOptions.Expiries
+)
+
+// Evaluation is a validation verdict and the reason for it
+type Evaluation struct {
+	Valid  bool   `json:"valid"`
+	Reason Reason `json:"reason"`
 }
 
-// buildBloomFilter creates a Bloom filter from a coupon file
-// Using optimal parameters: n=100M items, p=0.01 false positive rate
-func (v *Validator) buildBloomFilter(ctx context.Context, filePath string) (*bloom.BloomFilter, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("opening file: %w", err)
+// fileVerdict is the verdict of looking a code up in the coupon files
+func fileVerdict(valid bool) Evaluation {
+	if valid {
+		return Evaluation{Valid: true, Reason: ReasonValid}
 	}
-	defer file.Close()
+	return Evaluation{Reason: ReasonNotFound}
+}
 
-	// Configure for 100M items with 1% false positive rate
-	// This gives us the best balance of memory usage and accuracy
-	filter := bloom.NewWithEstimates(100000000, 0.01)
+// Evaluate checks a coupon code like IsValid, adjusted by opts, and says why
+// it passed or failed. Codes that could not be checked are reported invalid
+// with ReasonUnavailable
+func (v *Validator) Evaluate(ctx context.Context, code string, opts EvaluateOptions) Evaluation {
+	result, _ := v.evaluate(ctx, code, opts)
+	return result
+}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+// Verify checks a coupon code like IsValid but reports when the answer is
+// unknown: ErrNotReady before the files are loaded, ctx's error if it ended
+// first, ErrSearchUnavailable while the circuit breaker is open, or the file
+// search error. A false with a nil
+// error means the code is definitely invalid
+func (v *Validator) Verify(ctx context.Context, code string) (bool, error) {
+	result, err := v.evaluate(ctx, code, EvaluateOptions{})
+	return result.Valid, err
+}
 
-	count := 0
-	for scanner.Scan() {
-		// Check context cancellation periodically
-		if count%10000 == 0 {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			default:
-			}
-		}
+// evaluate implements Evaluate and Verify
+// A non-nil error always comes with ReasonUnavailable
+func (v *Validator) evaluate(ctx context.Context, code string, opts EvaluateOptions) (Evaluation, error) {
+	// Canonicalize input before any rule sees it
+	code = v.options.Canonicalize(code)
 
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			filter.AddString(line)
-			count++
-		}
+	// Tier 0: Business overrides win over everything, including the cache
+	v.mu.RLock()
+	override, overridden := v.overrides[code]
+	v.mu.RUnlock()
+	if overridden {
+		return Evaluation{Valid: override, Reason: ReasonOverride}, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning file: %w", err)
+	result, err := v.evaluateFiles(ctx, code, opts)
+	if result.Valid && v.expired(code) {
+		return Evaluation{Reason: ReasonExpired}, nil
 	}
-
-	return filter, nil
+	return result, err
 }
 
-// IsValid checks if a coupon code is valid
-// A coupon is valid if:
-// 1. It has 8-10 characters
-// 2. It appears in at least 2 of the loaded files
-// Uses LRU cache + Bloom filters + streaming for optimal performance
-func (v *Validator) IsValid(ctx context.Context, code string) bool {
-	// Normalize input
-	code = strings.ToUpper(strings.TrimSpace(code))
+// evaluateFiles runs the length, charset, cache, Bloom and file tiers for a
+// canonical code. Expiry is applied afterwards so cached results stay
+// independent of the clock
+func (v *Validator) evaluateFiles(ctx context.Context, code string, opts EvaluateOptions) (Evaluation, error) {
+	unavailable := Evaluation{Reason: ReasonUnavailable}
 
-	// Validate length (8-10 characters)
-	if len(code) < 8 || len(code) > 10 {
-		return false
+	// Validate length (8-10 characters by default), counting runes so that
+	// multi-byte UTF-8 characters count once. Matching is byte-for-byte
+	// Codes outside the range bypass the caches when the check is skipped, so
+	// analytics runs don't evict results the default path would use
+	cacheable := v.cache != nil
+	if n := utf8.RuneCountInString(code); n < v.options.MinLength || n > v.options.MaxLength {
+		if !opts.SkipLengthCheck {
+			if n < v.options.MinLength {
+				return Evaluation{Reason: ReasonTooShort}, nil
+			}
+			return Evaluation{Reason: ReasonTooLong}, nil
+		}
+		cacheable = false
+	}
+	cacheResult := func(valid bool) {
+		if cacheable {
+			v.cacheResult(code, valid)
+		}
+	}
+
+	// Coupon files only hold codes from the allowed charset, so anything
+	// else can never match and is rejected before touching the filters
+	if !v.hasAllowedChars(code) {
+		return Evaluation{Reason: ReasonInvalidChars}, nil
 	}
 
 	// Tier 1: Check cache (instant for repeated codes)
-	if cachedResult, found := v.cache.Get(code); found {
-		return cachedResult
+	if cacheable {
+		if cachedResult, found := v.getCached(code); found {
+			v.cacheHits.Add(1)
+			return fileVerdict(cachedResult), nil
+		}
+		v.cacheMisses.Add(1)
 	}
 
 	v.mu.RLock()
 	bloomFilters := v.bloomFilters
 	filePaths := v.filePaths
+	diskCache := v.diskCache
+	loaded := v.loaded
 	v.mu.RUnlock()
 
-	// If no filters loaded, invalid
-	if len(bloomFilters) == 0 {
-		return false
+	// Without filters there is nothing to check the code against yet
+	if !loaded {
+		return unavailable, ErrNotReady
+	}
+
+	// Tier 1b: Check the optional disk cache and promote hits back into memory
+	if diskCache != nil && cacheable {
+		if cachedResult, found := diskCache.Get(code); found {
+			v.cacheFor(cachedResult).Set(code, cachedResult)
+			return fileVerdict(cachedResult), nil
+		}
 	}
 
 	// Tier 2: Ask Bloom filters to eliminate files we don't need to search
@@ -278,22 +1228,25 @@ func (v *Validator) IsValid(ctx context.Context, code string) bool {
 	// - This means we occasionally search a file unnecessarily
 	// - But saving 380ms 99% of the time is worth it
 	possibleFiles := make([]int, 0, len(bloomFilters))
+	possibleWeight := 0
 	for i, filter := range bloomFilters {
 		if filter.TestString(code) {
 			possibleFiles = append(possibleFiles, i)
+			possibleWeight += v.fileWeight(filePaths[i])
 		}
 	}
 
-	// Early exit: Need code in at least 2 files to be valid
+	// Early exit: Need code in at least 2 files (by weight) to be valid
 	//
 	// Why this optimization is huge:
 	// - If 0 or 1 files said "maybe" → mathematically impossible to be valid
 	// - We can return immediately without any disk I/O
 	// - This catches ~98% of invalid codes (typos, expired, fraudulent)
 	// - Each early exit saves ~1140ms (not searching 3 files)
-	if len(possibleFiles) < 2 {
-		v.cache.Set(code, false)
-		return false
+	if possibleWeight < requiredWeight {
+		v.bloomExits.Add(1)
+		cacheResult(false)
+		return fileVerdict(false), nil
 	}
 
 	// Tier 3: Search actual files (but only where Bloom filter said "maybe")
@@ -311,67 +1264,537 @@ func (v *Validator) IsValid(ctx context.Context, code string) bool {
 	// Real-world impact:
 	// - Invalid code → 0 files searched → 0ms (vs 1140ms)
 	// - Valid code in 2 files → 2 files searched → ~380ms parallel (vs 1140ms serial)
+	paths := make([]string, len(possibleFiles))
+	for i, fileIndex := range possibleFiles {
+		paths[i] = filePaths[fileIndex]
+	}
+
+	// Report the file verification tier separately from the total coupon time
+	defer timing.FromContext(ctx).Since("coupon-files", time.Now())
+
+	// Concurrent validations of the same uncached code share one search, so
+	// a burst of "BLACKFRIDAY" lookups costs one file scan instead of hundreds
+	// Waiting happens on a budgeted goroutine so this caller can give up when
+	// ctx ends; with the budget exhausted it waits for the search itself
+	flight := make(chan singleflight.Result, 1)
+	v.goroutines.goOrRun(func() {
+		val, err, shared := v.searches.Do(code, func() (interface{}, error) {
+			return v.verifyGuarded(ctx, code, paths)
+		})
+		flight <- singleflight.Result{Val: val, Err: err, Shared: shared}
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return unavailable, ctx.Err()
+	case res = <-flight:
+	}
+
+	valid, _ := res.Val.(bool)
+	if err := res.Err; err != nil {
+		// Nothing is cached when the shared search failed. If it was only cut
+		// short by the caller that started it and this caller still has
+		// time, verify on its own
+		if ctx.Err() != nil {
+			return unavailable, ctx.Err()
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			valid, err = v.verifyGuarded(ctx, code, paths)
+		}
+		if err != nil {
+			if ctx.Err() == nil && v.options.TrustBloomOnError {
+				return v.trustBloom(ctx, code, err), nil
+			}
+			return unavailable, err
+		}
+	}
+
+	cacheResult(valid)
+	return fileVerdict(valid), nil
+}
+
+// trustBloom is the TrustBloomOnError fallback for a code the Bloom filters
+// found in enough files but whose file verification failed with err. The
+// result is not cached, so verification resumes once the files are back
+func (v *Validator) trustBloom(ctx context.Context, code string, err error) Evaluation {
+	v.fallbacks.Add(1)
+	v.options.Logger.ErrorContext(ctx, "coupon file verification failed, trusting Bloom filters",
+		"code", code,
+		"error", err,
+	)
+	return Evaluation{Valid: true, Reason: ReasonBloomOnly}
+}
+
+// verifyGuarded runs verifyInFiles behind the circuit breaker, failing fast
+// with ErrSearchUnavailable while it is open
+func (v *Validator) verifyGuarded(ctx context.Context, code string, paths []string) (bool, error) {
+	if !v.breaker.allow() {
+		return false, ErrSearchUnavailable
+	}
+	valid, err := v.verifyInFiles(ctx, code, paths)
+	// A caller's timeout or disconnect is no sign of a failing disk, so only
+	// searches that ended on their own count towards opening the breaker
+	if err != nil && ctx.Err() != nil {
+		v.breaker.release()
+	} else {
+		v.breaker.record(err)
+	}
+	return valid, err
+}
+
+// verifyInFiles searches the given files concurrently and reports whether
+// the weights of the files containing the code add up to 2. An error means a search did not
+// complete, because ctx ended or a file could not be read, so the answer is
+// unknown and must not be cached
+func (v *Validator) verifyInFiles(ctx context.Context, code string, paths []string) (bool, error) {
 	type result struct {
-		found bool
-		err   error
+		weight int
+		found  bool
+		err    error
 	}
 
-	resultsCh := make(chan result, len(possibleFiles))
+	resultsCh := make(chan result, len(paths))
 	searchCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	var wg sync.WaitGroup
-	for _, fileIndex := range possibleFiles {
+	for _, filePath := range paths {
 		wg.Add(1)
-		go func(filePath string) {
+		v.goroutines.goOrRun(func() {
 			defer wg.Done()
 
-			found, err := searchFileForCoupon(searchCtx, filePath, code)
+			if err := v.acquireSearch(searchCtx); err != nil {
+				return
+			}
+			defer v.releaseSearch()
+
+			found, err := v.searchCouponFile(searchCtx, filePath, code)
 
 			select {
 			case <-searchCtx.Done():
 				return
-			case resultsCh <- result{found: found, err: err}:
+			case resultsCh <- result{weight: v.fileWeight(filePath), found: found, err: err}:
 			}
-		}(filePaths[fileIndex])
+		})
 	}
 
-	go func() {
+	v.goroutines.goOrRun(func() {
 		wg.Wait()
 		close(resultsCh)
-	}()
+	})
 
-	// Count actual occurrences
-	filesWithCoupon := 0
+	// Sum the weights of the files actually containing the code
+	weightFound := 0
+	var searchErr error
 	for res := range resultsCh {
+		if res.err != nil && searchErr == nil {
+			searchErr = res.err
+		}
 		if res.err == nil && res.found {
-			filesWithCoupon++
-			// Early termination: if found in 2+ files, it's valid
-			if filesWithCoupon >= 2 {
+			weightFound += res.weight
+			// Early termination: once the weight reaches 2, it's valid
+			if weightFound >= requiredWeight {
 				cancel() // Stop other searches
 				// Drain remaining results
 				for range resultsCh {
 				}
-				v.cache.Set(code, true)
-				return true
+				return true, nil
+			}
+		}
+	}
+
+	// A cancelled search reports "not found" early; caching that would turn a
+	// valid code invalid until it is evicted
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if searchErr != nil {
+		return false, searchErr
+	}
+	return false, nil
+}
+
+// fileWeight returns how much a match in the file at path counts toward
+// requiredWeight
+func (v *Validator) fileWeight(path string) int {
+	if weight := v.options.FileWeights[filepath.Base(path)]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// cachePingKey is used by PingCache; it can never collide with a real code
+// because codes with control characters are rejected before the cache
+const cachePingKey = "\x00ping"
+
+// Canonicalize returns the canonical form of a code, the form validation,
+// overrides and the cache key on
+func (v *Validator) Canonicalize(code string) string {
+	return v.options.Canonicalize(code)
+}
+
+// PingCache looks up a key that is never stored in the in-memory cache, so
+// health checks can detect a cache whose lock has stalled without evicting
+// or spilling real entries
+func (v *Validator) PingCache() {
+	if v.cache == nil {
+		return
+	}
+	v.cache.Get(cachePingKey)
+}
+
+// SetOverride forces the validation result for a code regardless of file
+// membership, e.g. to enable a code not yet in the files or to disable a
+// leaked one. A nil valid removes the override
+func (v *Validator) SetOverride(code string, valid *bool) {
+	code = v.options.Canonicalize(code)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if valid == nil {
+		delete(v.overrides, code)
+		return
+	}
+	if v.overrides == nil {
+		v.overrides = make(map[string]bool)
+	}
+	v.overrides[code] = *valid
+}
+
+// Overrides returns a copy of the current overrides keyed by canonical code
+func (v *Validator) Overrides() map[string]bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	overrides := make(map[string]bool, len(v.overrides))
+	for code, valid := range v.overrides {
+		overrides[code] = valid
+	}
+	return overrides
+}
+
+// hasAllowedChars reports whether every character of code is in AllowedChars
+func (v *Validator) hasAllowedChars(code string) bool {
+	for _, c := range code {
+		if !v.allowedChars[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheResult stores a validation result unless the code exceeds MaxCacheKeyLength
+func (v *Validator) cacheResult(code string, valid bool) {
+	if len(code) > v.options.MaxCacheKeyLength {
+		return
+	}
+	v.cacheFor(valid).Set(code, valid)
+}
+
+// cacheFor returns the in-memory cache results like valid are kept in
+func (v *Validator) cacheFor(valid bool) *lruCache {
+	if !valid && v.negCache != nil {
+		return v.negCache
+	}
+	return v.cache
+}
+
+// getCached looks a code up in the in-memory caches
+func (v *Validator) getCached(code string) (bool, bool) {
+	if valid, found := v.cache.Get(code); found || v.negCache == nil {
+		return valid, found
+	}
+	return v.negCache.Get(code)
+}
+
+// MaxConcurrentSearches returns the configured limit on concurrent file searches
+// Callers fanning out many validations can size their worker pools from it
+func (v *Validator) MaxConcurrentSearches() int {
+	return v.options.MaxConcurrentSearches
+}
+
+// acquireSearch takes a file search slot, waiting until one is free or ctx is done
+func (v *Validator) acquireSearch(ctx context.Context) error {
+	select {
+	case v.searchSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSearch returns a file search slot taken by acquireSearch
+func (v *Validator) releaseSearch() {
+	<-v.searchSem
+}
+
+// FileInspection reports how a single coupon file answers for one code
+type FileInspection struct {
+	FileIndex    int  `json:"fileIndex"`
+	BloomMaybe   bool `json:"bloomMaybe"`
+	FileContains bool `json:"fileContains"`
+}
+
+// ErrMalformedCode is returned by Inspect for codes failing the length or
+// character rules, which validation rejects without searching the files
+var ErrMalformedCode = errors.New("coupon code is malformed")
+
+// Inspect reports per-file Bloom and file membership for a code
+// Unlike IsValid it bypasses the caches and searches every file, so a
+// BloomMaybe=true with FileContains=false entry is a Bloom false positive.
+// The searches run within the same search limit, goroutine budget and
+// circuit breaker as validation, and fail with the same errors as Verify
+func (v *Validator) Inspect(ctx context.Context, code string) ([]FileInspection, error) {
+	code = v.options.Canonicalize(code)
+	if n := utf8.RuneCountInString(code); n < v.options.MinLength || n > v.options.MaxLength || !v.hasAllowedChars(code) {
+		return nil, ErrMalformedCode
+	}
+
+	v.mu.RLock()
+	bloomFilters := v.bloomFilters
+	filePaths := v.filePaths
+	loaded := v.loaded
+	v.mu.RUnlock()
+	if !loaded {
+		return nil, ErrNotReady
+	}
+
+	if !v.breaker.allow() {
+		return nil, ErrSearchUnavailable
+	}
+
+	inspections := make([]FileInspection, len(filePaths))
+	errs := make([]error, len(filePaths))
+	var wg sync.WaitGroup
+	for i, filePath := range filePaths {
+		wg.Add(1)
+		v.goroutines.goOrRun(func() {
+			defer wg.Done()
+
+			contains, err := v.searchOne(ctx, filePath, code)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to search file %d: %w", i, err)
+				return
+			}
+			inspections[i] = FileInspection{
+				FileIndex:    i,
+				BloomMaybe:   i < len(bloomFilters) && bloomFilters[i].TestString(code),
+				FileContains: contains,
 			}
+		})
+	}
+	wg.Wait()
+
+	var err error
+	for _, err = range errs {
+		if err != nil {
+			break
+		}
+	}
+	// As in verifyGuarded, only searches that ended on their own count
+	// towards opening the breaker
+	if err != nil && ctx.Err() != nil {
+		v.breaker.release()
+	} else {
+		v.breaker.record(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inspections, nil
+}
+
+// Locate returns the indexes of the files that contain a code, in ascending
+// order. Files are searched concurrently, skipping those whose Bloom filter
+// rules the code out; like Inspect it bypasses overrides and caches
+func (v *Validator) Locate(ctx context.Context, code string) ([]int, error) {
+	code = v.options.Canonicalize(code)
+
+	v.mu.RLock()
+	bloomFilters := v.bloomFilters
+	filePaths := v.filePaths
+	v.mu.RUnlock()
+
+	type result struct {
+		index int
+		found bool
+		err   error
+	}
+
+	resultsCh := make(chan result, len(filePaths))
+	var wg sync.WaitGroup
+	for i, filePath := range filePaths {
+		if i < len(bloomFilters) && !bloomFilters[i].TestString(code) {
+			continue
+		}
+
+		wg.Add(1)
+		v.goroutines.goOrRun(func() {
+			defer wg.Done()
+
+			found, err := v.searchOne(ctx, filePath, code)
+			resultsCh <- result{index: i, found: found, err: err}
+		})
+	}
+	wg.Wait()
+	close(resultsCh)
+
+	indexes := make([]int, 0, len(filePaths))
+	for res := range resultsCh {
+		if res.err != nil {
+			return nil, fmt.Errorf("failed to search file %d: %w", res.index, res.err)
+		}
+		if res.found {
+			indexes = append(indexes, res.index)
+		}
+	}
+
+	// Results arrive in completion order; sort so the output is stable
+	slices.Sort(indexes)
+	return indexes, nil
+}
+
+// Exists reports whether a code is in at least one coupon file, regardless
+// of the 2-file validity rule, e.g. for typeahead. Files are checked one at
+// a time, skipping those whose Bloom filter rules the code out, until one
+// contains it. Results are cached apart from validity; like IsValid it
+// bypasses overrides and reports false when the files can't be searched
+func (v *Validator) Exists(ctx context.Context, code string) bool {
+	code = v.options.Canonicalize(code)
+
+	if n := utf8.RuneCountInString(code); n < v.options.MinLength || n > v.options.MaxLength || !v.hasAllowedChars(code) {
+		return false
+	}
+	if v.existCache != nil {
+		if exists, found := v.existCache.Get(code); found {
+			return exists
+		}
+	}
+
+	v.mu.RLock()
+	bloomFilters := v.bloomFilters
+	filePaths := v.filePaths
+	loaded := v.loaded
+	v.mu.RUnlock()
+	if !loaded {
+		return false
+	}
+
+	exists := false
+	for i, filePath := range filePaths {
+		if i < len(bloomFilters) && !bloomFilters[i].TestString(code) {
+			continue
+		}
+		found, err := v.searchOne(ctx, filePath, code)
+		if err != nil {
+			// Unknown, so nothing is cached
+			return false
+		}
+		if found {
+			exists = true
+			break
 		}
 	}
 
-	isValid := filesWithCoupon >= 2
-	v.cache.Set(code, isValid)
-	return isValid
+	if v.existCache != nil && len(code) <= v.options.MaxCacheKeyLength {
+		v.existCache.Set(code, exists)
+	}
+	return exists
+}
+
+// searchOne searches one coupon file within the concurrent search limit
+func (v *Validator) searchOne(ctx context.Context, filePath, code string) (bool, error) {
+	if err := v.acquireSearch(ctx); err != nil {
+		return false, err
+	}
+	defer v.releaseSearch()
+	return v.searchCouponFile(ctx, filePath, code)
+}
+
+// searchCouponFile searches one coupon file, using its memory mapping when
+// UseMmap is set and falling back to streaming once the mapping is released
+func (v *Validator) searchCouponFile(ctx context.Context, filePath, code string) (bool, error) {
+	v.fileSearches.Add(1)
+
+	v.mu.RLock()
+	mapped := v.mapped[filePath]
+	v.mu.RUnlock()
+
+	if mapped != nil {
+		found, err := mapped.search(ctx, code)
+		if !errors.Is(err, errMappingClosed) {
+			return found, err
+		}
+	}
+	return searchFile(ctx, filePath, code)
+}
+
+// Retry policy for transient errors while searching a coupon file
+const (
+	searchAttempts     = 3
+	searchRetryBackoff = 10 * time.Millisecond
+)
+
+// openCouponFile opens a coupon file for searching, replaceable in tests
+var openCouponFile = defaultOpenCouponFile
+
+func defaultOpenCouponFile(filePath string) (io.ReadCloser, error) {
+	return os.Open(filePath)
 }
 
 // searchFileForCoupon streams through a file looking for a specific coupon code
+// Transient read errors are retried a few times with jittered backoff so a
+// flaky read doesn't turn a valid code into a spurious negative
 func searchFileForCoupon(ctx context.Context, filePath, couponCode string) (bool, error) {
-	file, err := os.Open(filePath)
+	var err error
+	for attempt := 0; attempt < searchAttempts; attempt++ {
+		if attempt > 0 {
+			// Exponential backoff with up to 50% jitter, abandoned on cancellation
+			backoff := searchRetryBackoff << (attempt - 1)
+			backoff += time.Duration(rand.Int64N(int64(backoff/2) + 1))
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return false, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		var found bool
+		found, err = scanFileForCoupon(ctx, filePath, couponCode)
+		if err == nil || !isTransientSearchError(err) {
+			return found, err
+		}
+	}
+	return false, err
+}
+
+// isTransientSearchError reports whether a failed search is worth retrying
+// Cancellation and missing or unreadable files won't fix themselves
+func isTransientSearchError(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, fs.ErrNotExist) &&
+		!errors.Is(err, fs.ErrPermission)
+}
+
+// scanFileForCoupon makes a single pass over a file looking for the code
+func scanFileForCoupon(ctx context.Context, filePath, couponCode string) (bool, error) {
+	file, err := openCouponFile(filePath)
 	if err != nil {
 		return false, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	contents, err := maybeGunzip(file)
+	if err != nil {
+		return false, err
+	}
+
+	scanner := bufio.NewScanner(contents)
 	const maxScanTokenSize = 1024 * 1024 // 1MB
 	buf := make([]byte, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
@@ -397,20 +1820,142 @@ func searchFileForCoupon(ctx context.Context, filePath, couponCode string) (bool
 	return false, nil
 }
 
+// lastModifiedLocked returns when file i was last modified: the
+// Last-Modified of the URL it was downloaded from, or else its mtime when
+// its filter was built. Zero when unknown
+func (v *Validator) lastModifiedLocked(i int) time.Time {
+	if i < len(v.sources) {
+		if modified, err := http.ParseTime(v.sources[i].lastModified); err == nil {
+			return modified
+		}
+	}
+	if i < len(v.modTimes) {
+		return v.modTimes[i]
+	}
+	return time.Time{}
+}
+
+// statsSnapshot holds the stats that only change when data is loaded, so
+// GetStats can read them without taking v.mu
+type statsSnapshot struct {
+	filePaths      []string
+	filterBytes    uint64
+	buildDurations []int64
+	lastModified   []string  // RFC 3339 per file, "" when unknown
+	oldest         time.Time // oldest file modification, zero when unknown
+	overlap        map[int]int64
+	overlapCut     bool
+	diskCache      *diskCache
+}
+
+// publishStatsLocked snapshots the load-time stats for GetStats. Callers
+// hold v.mu and call it whenever the loaded data or sources change
+func (v *Validator) publishStatsLocked() {
+	snapshot := &statsSnapshot{
+		filePaths:      v.filePaths,
+		buildDurations: make([]int64, len(v.buildTimes)),
+		lastModified:   make([]string, len(v.filePaths)),
+		overlap:        v.overlap,
+		overlapCut:     v.overlapCut,
+		diskCache:      v.diskCache,
+	}
+	for _, filter := range v.bloomFilters {
+		if filter != nil {
+			snapshot.filterBytes += uint64(filter.Cap()) / 8
+		}
+	}
+	for i, d := range v.buildTimes {
+		snapshot.buildDurations[i] = d.Milliseconds()
+	}
+	for i := range v.filePaths {
+		modified := v.lastModifiedLocked(i)
+		if modified.IsZero() {
+			continue
+		}
+		snapshot.lastModified[i] = modified.UTC().Format(time.RFC3339)
+		if snapshot.oldest.IsZero() || modified.Before(snapshot.oldest) {
+			snapshot.oldest = modified
+		}
+	}
+	v.snapshot.Store(snapshot)
+}
+
 // GetStats returns statistics about loaded files and cache
+// dataAge is how many seconds old the oldest loaded file is
+// It reads a snapshot published at load time and atomic counters, never
+// v.mu or the cache locks, so frequent scraping doesn't slow validations
 func (v *Validator) GetStats() map[string]interface{} {
-	v.mu.RLock()
-	defer v.mu.RUnlock()
+	snapshot := v.snapshot.Load()
+	if snapshot == nil {
+		snapshot = &statsSnapshot{}
+	}
 
 	stats := make(map[string]interface{})
-	stats["total_files"] = len(v.filePaths)
-	stats["file_paths"] = v.filePaths
-	stats["bloom_filters_loaded"] = len(v.bloomFilters)
+	stats["totalFiles"] = len(snapshot.filePaths)
+	stats["filePaths"] = snapshot.filePaths
+	stats["bloomFiltersLoaded"] = len(snapshot.buildDurations)
+	stats["filterBuilds"] = v.filterBuilds.Load()
+	stats["filterMemoryBytes"] = snapshot.filterBytes
+	stats["fileBuildDurationsMs"] = snapshot.buildDurations
+	stats["fileLastModified"] = snapshot.lastModified
+	if !snapshot.oldest.IsZero() {
+		stats["dataAge"] = int64(time.Since(snapshot.oldest).Seconds())
+	}
+	if snapshot.overlap != nil {
+		stats["overlapDistribution"] = snapshot.overlap
+		stats["overlapTruncated"] = snapshot.overlapCut
+	}
+
+	if v.cache != nil {
+		stats["cacheSize"] = v.cache.Len()
+		stats["cacheCapacity"] = v.cache.capacity
+		stats["existsCacheSize"] = v.existCache.Len()
+	} else {
+		stats["cacheSize"] = 0
+		stats["cacheCapacity"] = 0
+		stats["existsCacheSize"] = 0
+	}
+	if v.options.CacheMemoryBudget > 0 {
+		stats["cacheMemoryBudget"] = v.options.CacheMemoryBudget
+	}
+	if v.negCache != nil {
+		stats["negativeCacheSize"] = v.negCache.Len()
+		stats["negativeCacheCapacity"] = v.negCache.capacity
+	}
+	stats["cacheHits"] = v.cacheHits.Load()
+	stats["cacheMisses"] = v.cacheMisses.Load()
+	stats["bloomEarlyExits"] = v.bloomExits.Load()
+	stats["fileSearches"] = v.fileSearches.Load()
+	stats["searchBreaker"] = v.breaker.State()
+	stats["goroutinesActive"] = v.goroutines.Active()
+	stats["goroutineBudget"] = v.options.MaxGoroutines
+	stats["bloomFallbacks"] = v.fallbacks.Load()
 
-	v.cache.mu.RLock()
-	stats["cache_size"] = v.cache.order.Len()
-	stats["cache_capacity"] = v.cache.capacity
-	v.cache.mu.RUnlock()
+	if snapshot.diskCache != nil {
+		stats["diskCacheSize"] = snapshot.diskCache.Len()
+		stats["diskCacheCapacity"] = snapshot.diskCache.capacity
+	}
 
 	return stats
 }
+
+// Close releases resources held by the validator, such as the disk cache
+// and any memory-mapped coupon files
+func (v *Validator) Close() error {
+	v.mu.Lock()
+	mapped := v.mapped
+	v.mapped = nil
+	diskCache := v.diskCache
+	v.diskCache = nil
+	v.publishStatsLocked()
+	v.mu.Unlock()
+
+	// Unmapping waits for in-flight searches, so it happens outside v.mu
+	err := closeMappings(mapped)
+	if diskCache != nil {
+		if cerr := diskCache.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
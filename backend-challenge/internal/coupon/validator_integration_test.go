@@ -39,7 +39,7 @@ func TestValidator_RealFiles(t *testing.T) {
 	t.Logf("Files loaded in %v", time.Since(start))
 
 	stats := validator.GetStats()
-	t.Logf("Loaded %v files", stats["total_files"])
+	t.Logf("Loaded %v files", stats["totalFiles"])
 
 	// Test validation - we don't know which specific codes are valid in real files
 	// so we test format validation and that the function doesn't panic
@@ -1,11 +1,21 @@
 package coupon
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // setupTestFiles creates temporary test files and returns their paths
@@ -53,8 +63,8 @@ func TestValidator_LoadFromFiles(t *testing.T) {
 		}
 
 		stats := validator.GetStats()
-		if stats["total_files"] != 3 {
-			t.Errorf("expected 3 files loaded, got %v", stats["total_files"])
+		if stats["totalFiles"] != 3 {
+			t.Errorf("expected 3 files loaded, got %v", stats["totalFiles"])
 		}
 	})
 
@@ -77,6 +87,68 @@ func TestValidator_LoadFromFiles(t *testing.T) {
 	})
 }
 
+func TestValidator_LoadFromDir(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"c.txt":     "VALIDABC\nSPECIAL9\n",
+		"a.txt":     "VALIDABC\nTESTCODE\n",
+		"b.txt":     "TESTCODE\nSPECIAL9\n",
+		"readme.md": "VALIDABC\n",
+	}
+	for name, content := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory matching the glob must be skipped
+	if err := os.Mkdir(filepath.Join(dir, "nested.txt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("loads matching files in sorted order", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromDir(context.Background(), dir, "*.txt"); err != nil {
+			t.Fatalf("LoadFromDir() error = %v", err)
+		}
+
+		stats := validator.GetStats()
+		if stats["totalFiles"] != 3 {
+			t.Errorf("totalFiles = %v, want 3", stats["totalFiles"])
+		}
+		want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")}
+		if paths, _ := stats["filePaths"].([]string); !slices.Equal(paths, want) {
+			t.Errorf("filePaths = %v, want %v", paths, want)
+		}
+
+		for _, code := range []string{"VALIDABC", "TESTCODE", "SPECIAL9"} {
+			if !validator.IsValid(context.Background(), code) {
+				t.Errorf("expected %s to be valid", code)
+			}
+		}
+	})
+
+	t.Run("no matching files", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromDir(context.Background(), dir, "*.gz"); err == nil {
+			t.Error("expected error for a pattern with no matches, got nil")
+		}
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromDir(context.Background(), t.TempDir(), ""); err == nil {
+			t.Error("expected error for an empty directory, got nil")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromDir(context.Background(), dir, "["); err == nil {
+			t.Error("expected error for a malformed pattern, got nil")
+		}
+	})
+}
+
 func TestValidator_IsValid(t *testing.T) {
 	file1, file2, file3, cleanup := setupTestFiles(t)
 	defer cleanup()
@@ -222,6 +294,86 @@ func TestValidator_IsValid_ContextCancellation(t *testing.T) {
 	// Since files are small, might complete before cancellation is detected
 	// Just verify it doesn't panic
 	_ = result
+
+	// Let the abandoned search finish before other tests swap the search hooks
+	validator.goroutines.wait()
+}
+
+func TestValidator_IsValid_CancelledSearchNotCached(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	// Searches give up without finding the code once their context has ended,
+	// however small the file
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	tests := []struct {
+		name   string
+		newCtx func() (context.Context, context.CancelFunc)
+	}{
+		{"cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}},
+		{"expired deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Nanosecond)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator()
+			if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+
+			ctx, cancel := tt.newCtx()
+			defer cancel()
+			if validator.IsValid(ctx, "VALIDABC") {
+				t.Fatal("expected an incomplete validation to report false")
+			}
+			// IsValid gives up without waiting for the shared search; wait for it
+			// so its result, had it been cached, is visible
+			validator.goroutines.wait()
+
+			if cached, found := validator.cache.Get("VALIDABC"); found {
+				t.Errorf("incomplete validation was cached as %v", cached)
+			}
+			if !validator.IsValid(context.Background(), "VALIDABC") {
+				t.Error("expected VALIDABC to be valid with a fresh context")
+			}
+		})
+	}
+}
+
+func TestValidator_IsValid_SearchErrorNotCached(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		return false, errors.New("read failed")
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+	if validator.IsValid(context.Background(), "VALIDABC") {
+		t.Fatal("expected a failed search to report false")
+	}
+	searchFile = searchFileForCoupon
+
+	if !validator.IsValid(context.Background(), "VALIDABC") {
+		t.Error("expected VALIDABC to be valid once files can be read")
+	}
 }
 
 func TestValidator_GetStats(t *testing.T) {
@@ -232,8 +384,8 @@ func TestValidator_GetStats(t *testing.T) {
 
 	t.Run("stats before loading", func(t *testing.T) {
 		stats := validator.GetStats()
-		if stats["total_files"] != 0 {
-			t.Errorf("expected 0 files before loading, got %v", stats["total_files"])
+		if stats["totalFiles"] != 0 {
+			t.Errorf("expected 0 files before loading, got %v", stats["totalFiles"])
 		}
 	})
 
@@ -243,13 +395,13 @@ func TestValidator_GetStats(t *testing.T) {
 		}
 
 		stats := validator.GetStats()
-		if stats["total_files"] != 3 {
-			t.Errorf("expected 3 files after loading, got %v", stats["total_files"])
+		if stats["totalFiles"] != 3 {
+			t.Errorf("expected 3 files after loading, got %v", stats["totalFiles"])
 		}
 
-		filePaths, ok := stats["file_paths"].([]string)
+		filePaths, ok := stats["filePaths"].([]string)
 		if !ok {
-			t.Error("expected file_paths to be []string")
+			t.Error("expected filePaths to be []string")
 		}
 		if len(filePaths) != 3 {
 			t.Errorf("expected 3 file paths, got %d", len(filePaths))
@@ -305,3 +457,1250 @@ func TestValidator_LargeFile(t *testing.T) {
 		t.Error("expected NOTTHIS1 to be invalid")
 	}
 }
+
+func TestValidator_MaxCacheKeyLength(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// 12-character code present in two files
+	files := make([]string, 2)
+	for i := range files {
+		files[i] = filepath.Join(tmpDir, fmt.Sprintf("coupons%d.txt", i+1))
+		if err := os.WriteFile(files[i], []byte("LONGCODE1234\nVALIDABC\n"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	// Relaxed length rule, but cache keys stay capped at 10 characters
+	validator := NewValidatorWithOptions(ValidatorOptions{
+		MaxLength:         12,
+		MaxCacheKeyLength: 10,
+		CacheCapacity:     DefaultCacheCapacity,
+	})
+	if err := validator.LoadFromFiles(context.Background(), files); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	if !validator.IsValid(context.Background(), "LONGCODE1234") {
+		t.Error("expected over-long but allowed code to be valid")
+	}
+	if size := validator.GetStats()["cacheSize"]; size != 0 {
+		t.Errorf("expected over-long code not to be cached, cacheSize = %v", size)
+	}
+
+	if !validator.IsValid(context.Background(), "VALIDABC") {
+		t.Error("expected VALIDABC to be valid")
+	}
+	if size := validator.GetStats()["cacheSize"]; size != 1 {
+		t.Errorf("expected code within key limit to be cached, cacheSize = %v", size)
+	}
+}
+
+func TestNewValidatorWithOptions_Defaults(t *testing.T) {
+	validator := NewValidatorWithOptions(ValidatorOptions{MaxLength: 12})
+
+	if validator.options.MinLength != DefaultMinLength {
+		t.Errorf("MinLength = %d, want %d", validator.options.MinLength, DefaultMinLength)
+	}
+	if validator.options.MaxCacheKeyLength != 12 {
+		t.Errorf("MaxCacheKeyLength = %d, want MaxLength (12)", validator.options.MaxCacheKeyLength)
+	}
+}
+
+func TestValidator_Inspect(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// Force a Bloom false positive: file 3 does not contain TESTCODE
+	validator.bloomFilters[2].AddString("TESTCODE")
+
+	tests := []struct {
+		name     string
+		code     string
+		expected []FileInspection
+	}{
+		{
+			name: "code in all files",
+			code: "validabc",
+			expected: []FileInspection{
+				{FileIndex: 0, BloomMaybe: true, FileContains: true},
+				{FileIndex: 1, BloomMaybe: true, FileContains: true},
+				{FileIndex: 2, BloomMaybe: true, FileContains: true},
+			},
+		},
+		{
+			name: "bloom false positive in file 3",
+			code: "TESTCODE",
+			expected: []FileInspection{
+				{FileIndex: 0, BloomMaybe: true, FileContains: true},
+				{FileIndex: 1, BloomMaybe: true, FileContains: true},
+				{FileIndex: 2, BloomMaybe: true, FileContains: false},
+			},
+		},
+		{
+			name: "code in no files",
+			code: "NOTEXIST",
+			expected: []FileInspection{
+				{FileIndex: 0, BloomMaybe: false, FileContains: false},
+				{FileIndex: 1, BloomMaybe: false, FileContains: false},
+				{FileIndex: 2, BloomMaybe: false, FileContains: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validator.Inspect(context.Background(), tt.code)
+			if err != nil {
+				t.Fatalf("Inspect(%q) error = %v", tt.code, err)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Inspect(%q) = %+v, expected %+v", tt.code, got, tt.expected)
+			}
+		})
+	}
+
+	// Codes validation rejects outright are not searched for
+	for _, code := range []string{"SHORT", "WAYTOOLONGCODE", "VALID@BC"} {
+		if _, err := validator.Inspect(context.Background(), code); !errors.Is(err, ErrMalformedCode) {
+			t.Errorf("Inspect(%q) error = %v, want ErrMalformedCode", code, err)
+		}
+	}
+	if _, err := NewValidator().Inspect(context.Background(), "VALIDABC"); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Inspect() before loading error = %v, want ErrNotReady", err)
+	}
+}
+
+func TestValidator_IsValid_MultiByteLength(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Every code appears in both files, so only the length rule decides
+	content := "ÜBERCODE\nÜÜÜÜÜÜÜÜÜÜ\nÜÜÜÜÜÜÜ\nÜÜÜÜÜÜÜÜÜÜÜ\n"
+	files := make([]string, 2)
+	for i := range files {
+		files[i] = filepath.Join(tmpDir, fmt.Sprintf("coupons%d.txt", i+1))
+		if err := os.WriteFile(files[i], []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	validator := NewValidatorWithOptions(ValidatorOptions{
+		AllowedChars:      DefaultAllowedChars + "Ü",
+		MaxCacheKeyLength: 64,
+		CacheCapacity:     DefaultCacheCapacity,
+	})
+	if err := validator.LoadFromFiles(context.Background(), files); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		expected bool
+	}{
+		{"8 runes, 9 bytes", "ÜBERCODE", true},
+		{"10 runes, 20 bytes", "ÜÜÜÜÜÜÜÜÜÜ", true},
+		{"7 runes, 14 bytes", "ÜÜÜÜÜÜÜ", false},
+		{"11 runes, 22 bytes", "ÜÜÜÜÜÜÜÜÜÜÜ", false},
+		{"lowercase multi-byte", "überCode", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+				t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidator_MaxConcurrentSearches(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{MaxConcurrentSearches: 2})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	if got := validator.MaxConcurrentSearches(); got != 2 {
+		t.Fatalf("MaxConcurrentSearches() = %d, want 2", got)
+	}
+
+	// Instrument file searches to record peak concurrency
+	var active, peak atomic.Int64
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	// Distinct codes so every request misses the cache and searches files
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			validator.IsValid(context.Background(), fmt.Sprintf("VALIDAB%d", n%10))
+			validator.IsValid(context.Background(), "VALIDABC")
+		}(i)
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("peak concurrent searches = %d, want <= 2", p)
+	}
+	if p := peak.Load(); p == 0 {
+		t.Error("expected at least one file search")
+	}
+}
+
+func TestValidator_GetStats_BuildDurations(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if durations := validator.GetStats()["fileBuildDurationsMs"].([]int64); len(durations) != 0 {
+		t.Errorf("expected no build durations before loading, got %v", durations)
+	}
+
+	start := time.Now()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	loadTime := time.Since(start)
+
+	durations, ok := validator.GetStats()["fileBuildDurationsMs"].([]int64)
+	if !ok {
+		t.Fatalf("expected fileBuildDurationsMs to be []int64")
+	}
+	if len(durations) != 3 {
+		t.Fatalf("expected 3 build durations, got %d", len(durations))
+	}
+
+	// Each build is measured, and none can outlast the whole load
+	for i, d := range validator.buildTimes {
+		if d <= 0 || d > loadTime {
+			t.Errorf("file %d build duration %v not within (0, %v]", i, d, loadTime)
+		}
+		if durations[i] != d.Milliseconds() {
+			t.Errorf("file %d reported %dms, want %dms", i, durations[i], d.Milliseconds())
+		}
+	}
+}
+
+func TestValidator_IsValid_AllowedChars(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Out-of-charset codes are planted in both files so only the charset
+	// check can reject them
+	files := make([]string, 2)
+	for i := range files {
+		files[i] = filepath.Join(tmpDir, fmt.Sprintf("coupons%d.txt", i+1))
+		if err := os.WriteFile(files[i], []byte("VALID.ABC\nVALID/ABC\nVALID_ABC\n12345678\n"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), files); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	var searches atomic.Int64
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		searches.Add(1)
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	tests := []struct {
+		name           string
+		code           string
+		expected       bool
+		expectSearches bool
+	}{
+		{"dot", "VALID.ABC", false, false},
+		{"slash", "VALID/ABC", false, false},
+		{"underscore", "valid_abc", false, false},
+		{"digits only", "12345678", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searches.Store(0)
+
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+				t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+			}
+			if searched := searches.Load() > 0; searched != tt.expectSearches {
+				t.Errorf("IsValid(%q) searched files = %v, expected %v", tt.code, searched, tt.expectSearches)
+			}
+		})
+	}
+}
+
+func TestValidator_SetOverride(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	allow, deny := true, false
+
+	// Prime the cache so the overrides must take precedence over it
+	if !validator.IsValid(context.Background(), "VALIDABC") {
+		t.Fatal("expected VALIDABC to be valid before override")
+	}
+
+	validator.SetOverride("newcode1", &allow)
+	validator.SetOverride("VALIDABC", &deny)
+
+	tests := []struct {
+		name     string
+		code     string
+		expected bool
+	}{
+		{"force-allow absent code", "NEWCODE1", true},
+		{"force-deny present cached code", "VALIDABC", false},
+		{"other codes unaffected", "TESTCODE", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+				t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+			}
+		})
+	}
+
+	// Removing the override restores file-based validation
+	validator.SetOverride("VALIDABC", nil)
+	if !validator.IsValid(context.Background(), "VALIDABC") {
+		t.Error("expected VALIDABC to be valid after removing override")
+	}
+	if overrides := validator.Overrides(); len(overrides) != 1 || !overrides["NEWCODE1"] {
+		t.Errorf("Overrides() = %v, want only NEWCODE1", overrides)
+	}
+}
+
+func TestValidator_Evaluate_SkipLengthCheck(t *testing.T) {
+	dir := t.TempDir()
+	paths := make([]string, 3)
+	for i, content := range []string{"SHORT\nVALIDABC\n", "SHORT\nVALIDABC\n", "OTHER\n"} {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("coupons%d.txt", i+1))
+		if err := os.WriteFile(paths[i], []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), paths); err != nil {
+		t.Fatalf("LoadFromFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		opts EvaluateOptions
+		want bool
+	}{
+		{"short code rejected by default", "SHORT", EvaluateOptions{}, false},
+		{"short code passes when skipped", "SHORT", EvaluateOptions{SkipLengthCheck: true}, true},
+		{"short code in one file still invalid", "OTHER", EvaluateOptions{SkipLengthCheck: true}, false},
+		{"in-range code unaffected", "VALIDABC", EvaluateOptions{SkipLengthCheck: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validator.Evaluate(context.Background(), tt.code, tt.opts).Valid; got != tt.want {
+				t.Errorf("Evaluate(%q, %+v) = %v, want %v", tt.code, tt.opts, got, tt.want)
+			}
+		})
+	}
+
+	// The bypassed result must not leak into the default path via the cache
+	if validator.IsValid(context.Background(), "SHORT") {
+		t.Error("IsValid(SHORT) = true after a skipped-length Evaluate, want false")
+	}
+}
+
+func TestValidator_IsValid_SharesInFlightSearches(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// Hold every search until all callers have piled onto the same code
+	release := make(chan struct{})
+	var searches atomic.Int64
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		searches.Add(1)
+		<-release
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	const callers = 100
+	results := make(chan bool, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- validator.IsValid(context.Background(), "VALIDABC")
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for valid := range results {
+		if !valid {
+			t.Fatal("expected every caller to see VALIDABC as valid")
+		}
+	}
+
+	// VALIDABC is in all three files, so one shared search touches each at most once
+	if n := searches.Load(); n == 0 || n > 3 {
+		t.Errorf("file searches = %d, want between 1 and 3", n)
+	}
+	if valid, found := validator.cache.Get("VALIDABC"); !found || !valid {
+		t.Error("expected the shared result to populate the cache")
+	}
+}
+
+// failingReadCloser simulates a transient I/O error mid-read
+type failingReadCloser struct{}
+
+func (failingReadCloser) Read([]byte) (int, error) { return 0, errors.New("read: input/output error") }
+func (failingReadCloser) Close() error             { return nil }
+
+func TestSearchFileForCoupon_RetriesTransientErrors(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		failures     int
+		path         string
+		wantFound    bool
+		wantErr      bool
+		wantAttempts int64
+	}{
+		{"fails once then succeeds", 1, file1, true, false, 2},
+		{"keeps failing", searchAttempts, file1, false, true, searchAttempts},
+		{"missing file is not retried", 0, filepath.Join(filepath.Dir(file1), "missing"), false, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int64
+			openCouponFile = func(filePath string) (io.ReadCloser, error) {
+				if attempts.Add(1) <= int64(tt.failures) {
+					return failingReadCloser{}, nil
+				}
+				return os.Open(filePath)
+			}
+			defer func() { openCouponFile = defaultOpenCouponFile }()
+
+			found, err := searchFileForCoupon(context.Background(), tt.path, "VALIDABC")
+			if found != tt.wantFound || (err != nil) != tt.wantErr {
+				t.Errorf("searchFileForCoupon() = %v, %v; want found %v, error %v", found, err, tt.wantFound, tt.wantErr)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n, tt.wantAttempts)
+			}
+		})
+	}
+
+	t.Run("validation survives a transient failure", func(t *testing.T) {
+		validator := NewValidator()
+		if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+			t.Fatalf("failed to load files: %v", err)
+		}
+
+		// Every file fails on its first read
+		var mu sync.Mutex
+		failed := make(map[string]bool)
+		openCouponFile = func(filePath string) (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !failed[filePath] {
+				failed[filePath] = true
+				return failingReadCloser{}, nil
+			}
+			return os.Open(filePath)
+		}
+		defer func() { openCouponFile = defaultOpenCouponFile }()
+
+		if !validator.IsValid(context.Background(), "TESTCODE") {
+			t.Error("expected TESTCODE to be valid despite transient read errors")
+		}
+	})
+
+	t.Run("cancellation stops retrying", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		openCouponFile = func(filePath string) (io.ReadCloser, error) {
+			cancel()
+			return failingReadCloser{}, nil
+		}
+		defer func() { openCouponFile = defaultOpenCouponFile }()
+
+		if _, err := searchFileForCoupon(ctx, file1, "VALIDABC"); !errors.Is(err, context.Canceled) {
+			t.Errorf("searchFileForCoupon() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestValidator_IsValid_Canonicalize(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		code     string
+		expected bool
+	}{
+		{"dashed", "VALID-ABC", true},
+		{"spaced", "valid abc", true},
+		{"mixed formatting", " Test-Code\t", true},
+		{"several dashes", "SP-EC-IA-L9", true},
+		{"formatting cannot make a short code long enough", "VALID-AB", false},
+		{"dashed code in one file only", "COUPON-01", false},
+	}
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.expected {
+				t.Errorf("IsValid(%q) = %v, expected %v", tt.code, got, tt.expected)
+			}
+		})
+	}
+
+	// Overrides and inspection key on the canonical code too
+	validator.SetOverride("only-one-1", &[]bool{true}[0])
+	if !validator.IsValid(context.Background(), "ONLYONE1") {
+		t.Error("expected the override set for only-one-1 to apply to ONLYONE1")
+	}
+	inspections, err := validator.Inspect(context.Background(), "valid-abc")
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	for _, inspection := range inspections {
+		if !inspection.FileContains {
+			t.Errorf("expected file %d to contain valid-abc once canonicalized", inspection.FileIndex)
+		}
+	}
+}
+
+func TestValidator_CustomCanonicalize(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	// A deployment whose codes never contain dashes only upper-cases
+	validator := NewValidatorWithOptions(ValidatorOptions{Canonicalize: strings.ToUpper})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	if !validator.IsValid(context.Background(), "validabc") {
+		t.Error("expected validabc to be valid")
+	}
+	if validator.IsValid(context.Background(), "VALID-ABC") {
+		t.Error("expected VALID-ABC to be invalid without dash stripping")
+	}
+	if got := validator.Canonicalize("happy-hrs"); got != "HAPPY-HRS" {
+		t.Errorf("Canonicalize() = %q, want %q", got, "HAPPY-HRS")
+	}
+}
+
+func TestValidator_Locate_SortedByFileIndex(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// Earlier files finish last, so completion order is the reverse of file order
+	delays := map[string]time.Duration{file1: 30 * time.Millisecond, file2: 15 * time.Millisecond, file3: 0}
+	searchFile = func(ctx context.Context, filePath, couponCode string) (bool, error) {
+		time.Sleep(delays[filePath])
+		return searchFileForCoupon(ctx, filePath, couponCode)
+	}
+	defer func() { searchFile = searchFileForCoupon }()
+
+	tests := []struct {
+		code string
+		want []int
+	}{
+		{"VALIDABC", []int{0, 1, 2}},
+		{"SPECIAL9", []int{1, 2}},
+		{"coupon-01", []int{0}},
+		{"NOTFOUND", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := validator.Locate(context.Background(), tt.code)
+			if err != nil {
+				t.Fatalf("Locate() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Locate(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidator_GetStats_DoesNotBlock(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: DefaultCacheCapacity, NegativeCacheCapacity: 10})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+	validator.IsValid(context.Background(), "VALIDABC")
+
+	// Hold every lock validations take; stats must still be served
+	validator.mu.Lock()
+	validator.cache.mu.Lock()
+	validator.negCache.mu.Lock()
+	defer func() {
+		validator.negCache.mu.Unlock()
+		validator.cache.mu.Unlock()
+		validator.mu.Unlock()
+	}()
+
+	done := make(chan map[string]interface{}, 1)
+	go func() { done <- validator.GetStats() }()
+	select {
+	case stats := <-done:
+		if stats["totalFiles"] != 3 || stats["cacheSize"] != 1 {
+			t.Errorf("GetStats() = totalFiles %v, cacheSize %v; want 3, 1", stats["totalFiles"], stats["cacheSize"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetStats() blocked on the validation locks")
+	}
+}
+
+func TestValidator_GetStats_ConcurrentValidations(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: 4})
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	codes := []string{"VALIDABC", "TESTCODE", "SPECIAL9", "COUPON01", "NOTFOUND", "ONLYONE1"}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ctx.Err() == nil; j++ {
+				validator.IsValid(ctx, codes[j%len(codes)])
+			}
+		}()
+	}
+
+	// Counters only grow and the cache never exceeds its capacity
+	var lastLookups int64
+	for i := 0; i < 1000; i++ {
+		stats := validator.GetStats()
+		if stats["totalFiles"] != 3 || len(stats["fileBuildDurationsMs"].([]int64)) != 3 {
+			t.Fatalf("inconsistent file stats: %v", stats)
+		}
+		if size := stats["cacheSize"].(int); size < 0 || size > 4 {
+			t.Fatalf("cacheSize = %d, want 0..4", size)
+		}
+		lookups := stats["cacheHits"].(int64) + stats["cacheMisses"].(int64)
+		if lookups < lastLookups {
+			t.Fatalf("cache lookups went from %d to %d", lastLookups, lookups)
+		}
+		lastLookups = lookups
+	}
+	cancel()
+	wg.Wait()
+}
+
+func BenchmarkValidator_IsValidWhileScrapingStats(b *testing.B) {
+	validator := NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("VALIDABC\nTESTCODE\n"),
+		strings.NewReader("VALIDABC\nTESTCODE\n"),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Compare ns/op of the two runs; scraping should barely move it
+	for _, scrape := range []bool{false, true} {
+		b.Run(fmt.Sprintf("scrape=%v", scrape), func(b *testing.B) {
+			stop := make(chan struct{})
+			var scraper sync.WaitGroup
+			if scrape {
+				scraper.Add(1)
+				go func() {
+					defer scraper.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							validator.GetStats()
+						}
+					}
+				}()
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					validator.IsValid(ctx, "VALIDABC")
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			scraper.Wait()
+		})
+	}
+}
+
+func TestValidator_Exists(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	tests := []struct {
+		code       string
+		wantExists bool
+		wantValid  bool
+	}{
+		{"VALIDABC", true, true},
+		{"SPECIAL9", true, true},
+		{"ONLYONE1", true, false},
+		{"coupon-01", true, false},
+		{"NOTFOUND", false, false},
+		{"SHORT", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := validator.Exists(context.Background(), tt.code); got != tt.wantExists {
+				t.Errorf("Exists(%q) = %v, want %v", tt.code, got, tt.wantExists)
+			}
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.wantValid {
+				t.Errorf("IsValid(%q) = %v, want %v", tt.code, got, tt.wantValid)
+			}
+		})
+	}
+
+	// Existence and validity are cached apart, so neither answer shadows the other
+	if exists, found := validator.existCache.Get("ONLYONE1"); !found || !exists {
+		t.Errorf("exists cache for ONLYONE1 = %v, %v, want true, true", exists, found)
+	}
+	if valid, found := validator.cache.Get("ONLYONE1"); !found || valid {
+		t.Errorf("validity cache for ONLYONE1 = %v, %v, want false, true", valid, found)
+	}
+}
+
+func TestValidator_FileWeights(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		weights map[string]int
+		code    string
+		want    bool
+	}{
+		{"default weights need two files", nil, "ONLYONE1", false},
+		{"authoritative file alone", map[string]int{"coupons3.txt": 2}, "ONLYONE1", true},
+		{"authoritative file plus normal file", map[string]int{"coupons3.txt": 2}, "SPECIAL9", true},
+		{"single normal file", map[string]int{"coupons3.txt": 2}, "INVALID1", false},
+		{"non-positive weight counts one", map[string]int{"coupons3.txt": 0}, "ONLYONE1", false},
+		{"two normal files", map[string]int{"coupons3.txt": 2}, "TESTCODE", true},
+		{"in no file", map[string]int{"coupons3.txt": 2}, "NOTFOUND", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidatorWithOptions(ValidatorOptions{FileWeights: tt.weights})
+			if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.want {
+				t.Errorf("IsValid(%s) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidator_Verify_NotReady(t *testing.T) {
+	validator := NewValidator()
+
+	valid, err := validator.Verify(context.Background(), "VALIDABC")
+	if !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Verify() = %v, %v, want %v", valid, err, ErrNotReady)
+	}
+	if valid {
+		t.Error("expected no valid verdict before load")
+	}
+
+	// Rules that don't need the files still give a definite answer
+	if _, err := validator.Verify(context.Background(), "SHORT"); err != nil {
+		t.Errorf("Verify(SHORT) error = %v, want nil", err)
+	}
+
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	valid, err = validator.Verify(context.Background(), "VALIDABC")
+	if !valid || err != nil {
+		t.Errorf("Verify() after load = %v, %v, want true, nil", valid, err)
+	}
+}
+
+func TestValidator_LoadFromReaders(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte("VALIDABC\nSPECIAL9\nCOUPON03\nCCCC3333\nONLYONE1\n"))
+	gz.Close()
+
+	validator := NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("VALIDABC\nTESTCODE\nCOUPON01\nINVALID1\nAAAA1111\n"),
+		strings.NewReader("VALIDABC\r\nTESTCODE\r\nSPECIAL9\r\nCOUPON02\r\nBBBB2222"),
+		&gzipped,
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"VALIDABC", true},
+		{"TESTCODE", true},
+		{"SPECIAL9", true},
+		{"ONLYONE1", false},
+		{"COUPON01", false},
+		{"NOTFOUND", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := validator.IsValid(context.Background(), tt.code); got != tt.want {
+				t.Errorf("IsValid(%s) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+
+	// Bloom false positives are still caught by searching the held contents
+	files, err := validator.Locate(context.Background(), "SPECIAL9")
+	if err != nil || !slices.Equal(files, []int{1, 2}) {
+		t.Errorf("Locate(SPECIAL9) = %v, %v, want [1 2]", files, err)
+	}
+
+	if _, err := validator.Reload(context.Background()); err == nil {
+		t.Error("expected Reload to fail for reader sources")
+	}
+	if err := validator.LoadFromReaders(context.Background(), nil); err == nil {
+		t.Error("expected an error when no readers are provided")
+	}
+}
+
+func TestValidator_LoadFromReadersMatchesFiles(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	paths := []string{file1, file2, file3}
+
+	fromFiles := NewValidator()
+	if err := fromFiles.LoadFromFiles(context.Background(), paths); err != nil {
+		t.Fatalf("LoadFromFiles() error = %v", err)
+	}
+
+	readers := make([]io.Reader, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		readers[i] = bytes.NewReader(data)
+	}
+	fromReaders := NewValidator()
+	if err := fromReaders.LoadFromReaders(context.Background(), readers); err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+
+	// Both go through the same build, so the same data gives the same filters
+	filesFilters, readerFilters := loadedFilters(fromFiles), loadedFilters(fromReaders)
+	for i := range paths {
+		if !filesFilters[i].Equal(readerFilters[i]) {
+			t.Errorf("filter %d differs between files and readers", i)
+		}
+	}
+	if !slices.Equal(fromFiles.checksums, fromReaders.checksums) {
+		t.Errorf("checksums = %v from files, %v from readers", fromFiles.checksums, fromReaders.checksums)
+	}
+}
+
+func TestValidator_NegativeCache(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	tests := []struct {
+		name             string
+		negativeCapacity int
+		wantPositives    bool
+	}{
+		{"shared cache evicts positives", 0, false},
+		{"separate negative cache keeps positives", 1000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidatorWithOptions(ValidatorOptions{
+				CacheCapacity:         10,
+				NegativeCacheCapacity: tt.negativeCapacity,
+			})
+			if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+
+			for _, code := range []string{"VALIDABC", "TESTCODE", "SPECIAL9"} {
+				if !validator.IsValid(context.Background(), code) {
+					t.Fatalf("expected %s to be valid", code)
+				}
+			}
+
+			// Far more negatives than the main cache holds
+			for i := 0; i < 500; i++ {
+				if validator.IsValid(context.Background(), fmt.Sprintf("NEG%05d", i)) {
+					t.Fatalf("expected NEG%05d to be invalid", i)
+				}
+			}
+
+			for _, code := range []string{"VALIDABC", "TESTCODE", "SPECIAL9"} {
+				_, found := validator.cache.Get(code)
+				if found != tt.wantPositives {
+					t.Errorf("%s cached = %v, want %v", code, found, tt.wantPositives)
+				}
+			}
+			if tt.negativeCapacity > 0 {
+				if valid, found := validator.getCached("NEG00499"); !found || valid {
+					t.Errorf("getCached(NEG00499) = %v, %v, want false, true", valid, found)
+				}
+				if stats := validator.GetStats(); stats["negativeCacheSize"] != 500 {
+					t.Errorf("negativeCacheSize = %v, want 500", stats["negativeCacheSize"])
+				}
+			}
+		})
+	}
+}
+
+func TestValidator_CacheCapacity(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		capacity     int
+		wantCapacity int
+		wantCached   bool
+	}{
+		{"custom capacity", 50, 50, true},
+		{"zero disables caching", 0, 0, false},
+		{"negative disables caching", -1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidatorWithOptions(ValidatorOptions{
+				CacheCapacity:         tt.capacity,
+				NegativeCacheCapacity: 100,
+			})
+			if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+
+			var searches atomic.Int64
+			original := searchFile
+			searchFile = func(ctx context.Context, filePath, code string) (bool, error) {
+				searches.Add(1)
+				return original(ctx, filePath, code)
+			}
+			defer func() { searchFile = original }()
+
+			if !validator.IsValid(context.Background(), "VALIDABC") {
+				t.Fatal("expected VALIDABC to be valid")
+			}
+			first := searches.Load()
+			if !validator.IsValid(context.Background(), "VALIDABC") {
+				t.Fatal("expected VALIDABC to be valid")
+			}
+			if repeated := searches.Load() - first; (repeated == 0) != tt.wantCached {
+				t.Errorf("repeated validation searched %d files, want cached %v", repeated, tt.wantCached)
+			}
+
+			validator.PingCache()
+			validator.Exists(context.Background(), "VALIDABC")
+
+			stats := validator.GetStats()
+			if stats["cacheCapacity"] != tt.wantCapacity {
+				t.Errorf("cacheCapacity = %v, want %d", stats["cacheCapacity"], tt.wantCapacity)
+			}
+			if !tt.wantCached {
+				if stats["cacheSize"] != 0 || validator.negCache != nil {
+					t.Errorf("cacheSize = %v, negative cache %v, want nothing cached", stats["cacheSize"], validator.negCache)
+				}
+				if got := stats["cacheHits"].(int64) + stats["cacheMisses"].(int64); got != 0 {
+					t.Errorf("cache lookups = %d, want 0", got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidator_PingCacheKeepsEntries(t *testing.T) {
+	validator := NewValidatorWithOptions(ValidatorOptions{CacheCapacity: 1})
+	validator.cacheResult("VALIDABC", true)
+
+	validator.PingCache()
+
+	if valid, found := validator.cache.Get("VALIDABC"); !found || !valid {
+		t.Errorf("cache.Get(VALIDABC) = %v, %v after a ping, want true, true", valid, found)
+	}
+	if size := validator.cache.Len(); size != 1 {
+		t.Errorf("cache size = %d after a ping, want 1", size)
+	}
+}
+
+func TestValidator_LookupCounters(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	var searches atomic.Int64
+	original := searchFile
+	searchFile = func(ctx context.Context, filePath, code string) (bool, error) {
+		searches.Add(1)
+		return original(ctx, filePath, code)
+	}
+	defer func() { searchFile = original }()
+
+	// A miss that needs the files, a hit, and a miss the Bloom filters answer
+	for _, code := range []string{"VALIDABC", "VALIDABC", "NOTACODE"} {
+		validator.IsValid(context.Background(), code)
+	}
+
+	stats := validator.GetStats()
+	want := map[string]int64{
+		"cacheHits":       1,
+		"cacheMisses":     2,
+		"bloomEarlyExits": 1,
+		"fileSearches":    searches.Load(),
+	}
+	for key, n := range want {
+		if stats[key] != n {
+			t.Errorf("%s = %v, want %d", key, stats[key], n)
+		}
+	}
+	if searches.Load() == 0 {
+		t.Error("expected VALIDABC to be searched for in the files")
+	}
+}
+
+func TestLRUCache_MemoryPressure(t *testing.T) {
+	clock := time.Unix(0, 0)
+	heap := uint64(0)
+
+	cache := newLRUCache(10)
+	cache.pressure = newMemoryPressure(1000, time.Second)
+	cache.pressure.now = func() time.Time { return clock }
+	cache.pressure.heapAlloc = func() uint64 { return heap }
+
+	var evicted []string
+	cache.onEvict = func(key string, valid bool) { evicted = append(evicted, key) }
+
+	// Under budget the cache grows past its capacity
+	for i := 0; i < 50; i++ {
+		clock = clock.Add(time.Second)
+		cache.Set(fmt.Sprintf("CODE%04d", i), true)
+	}
+	if n := cache.order.Len(); n != 50 {
+		t.Fatalf("cache size under budget = %d, want 50", n)
+	}
+
+	// Over budget, but between checks nothing is evicted yet
+	heap = 2000
+	cache.Set("CODE0050", true)
+	if n := cache.order.Len(); n != 51 {
+		t.Fatalf("cache size before the next check = %d, want 51", n)
+	}
+
+	// The next check trims down to capacity, oldest entries first
+	clock = clock.Add(time.Second)
+	cache.Set("CODE0051", true)
+	if n := cache.order.Len(); n != 10 {
+		t.Fatalf("cache size after pressure = %d, want 10", n)
+	}
+	if len(evicted) != 42 || evicted[0] != "CODE0000" {
+		t.Errorf("evicted %d entries starting at %v, want 42 starting at CODE0000", len(evicted), evicted[:1])
+	}
+	if _, found := cache.Get("CODE0051"); !found {
+		t.Error("expected the newest entry to be kept")
+	}
+	if _, found := cache.Get("CODE0041"); found {
+		t.Error("expected CODE0041 to be evicted")
+	}
+}
+
+func TestValidator_GetStats_FileLastModified_LocalFiles(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	mtime := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(file2, mtime, mtime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	validator := NewValidator()
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("LoadFromFiles() error = %v", err)
+	}
+
+	stats := validator.GetStats()
+	got, _ := stats["fileLastModified"].([]string)
+	if len(got) != 3 || got[1] != "2026-01-15T12:00:00Z" {
+		t.Errorf("fileLastModified = %v, want file 2 at 2026-01-15T12:00:00Z", got)
+	}
+	if age, _ := stats["dataAge"].(int64); age < int64(time.Since(mtime).Seconds())-5 {
+		t.Errorf("dataAge = %d, want the age of the oldest file", age)
+	}
+}
+
+func TestValidator_TrustBloomOnError(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustBloom bool
+		wantValid  bool
+		wantErr    bool
+		wantReason Reason
+	}{
+		{"off: verification error is reported", false, false, true, ReasonUnavailable},
+		{"on: Bloom result is trusted", true, true, false, ReasonBloomOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file1, file2, file3, cleanup := setupTestFiles(t)
+			defer cleanup()
+
+			var logs bytes.Buffer
+			validator := NewValidatorWithOptions(ValidatorOptions{
+				TrustBloomOnError: tt.trustBloom,
+				CacheCapacity:     DefaultCacheCapacity,
+				Logger:            slog.New(slog.NewTextHandler(&logs, nil)),
+			})
+			defer validator.Close()
+			if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+				t.Fatalf("failed to load files: %v", err)
+			}
+
+			// The files disappear after the filters were built
+			cleanup()
+
+			valid, err := validator.Verify(context.Background(), "VALIDABC")
+			if valid != tt.wantValid || (err != nil) != tt.wantErr {
+				t.Errorf("Verify(VALIDABC) = %v, %v, want %v, error %v", valid, err, tt.wantValid, tt.wantErr)
+			}
+			if got := validator.Evaluate(context.Background(), "TESTCODE", EvaluateOptions{}); got.Reason != tt.wantReason {
+				t.Errorf("Evaluate(TESTCODE) reason = %q, want %q", got.Reason, tt.wantReason)
+			}
+
+			// Codes the filters rule out never reach the files
+			if valid, err := validator.Verify(context.Background(), "NOTFOUND"); valid || err != nil {
+				t.Errorf("Verify(NOTFOUND) = %v, %v, want false, nil", valid, err)
+			}
+
+			// Fallback results are not cached
+			if _, found := validator.getCached("VALIDABC"); found {
+				t.Error("expected VALIDABC not to be cached")
+			}
+
+			fallbacks := validator.GetStats()["bloomFallbacks"].(int64)
+			if tt.trustBloom {
+				if fallbacks != 2 || !strings.Contains(logs.String(), "trusting Bloom filters") {
+					t.Errorf("bloomFallbacks = %d, logs %q, want 2 logged fallbacks", fallbacks, logs.String())
+				}
+			} else if fallbacks != 0 || logs.Len() != 0 {
+				t.Errorf("bloomFallbacks = %d, logs %q, want none", fallbacks, logs.String())
+			}
+		})
+	}
+}
+
+func TestValidator_FilterSizing(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidatorWithOptions(ValidatorOptions{ExpectedItems: 1000, FalsePositiveRate: 0.001})
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	// The default filter takes about 120MB per file
+	bytes, _ := validator.GetStats()["filterMemoryBytes"].(uint64)
+	if bytes == 0 || bytes > 3*64*1024 {
+		t.Errorf("filterMemoryBytes = %d, want a few KB for three 1000-item filters", bytes)
+	}
+	if !validator.IsValid(context.Background(), "VALIDABC") || validator.IsValid(context.Background(), "ONLYONE1") {
+		t.Error("small filters give different results")
+	}
+}
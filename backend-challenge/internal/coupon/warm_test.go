@@ -0,0 +1,86 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidator_WarmPageCache(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+	paths := []string{file1, file2, file3}
+
+	validator := NewValidator()
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), paths); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	var want int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", path, err)
+		}
+		want += info.Size()
+	}
+
+	got, err := validator.WarmPageCache(context.Background())
+	if err != nil {
+		t.Fatalf("WarmPageCache() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("WarmPageCache() read %d bytes, want %d", got, want)
+	}
+
+	codes := map[string]bool{
+		"VALIDABC": true,
+		"TESTCODE": true,
+		"SPECIAL9": true,
+		"ONLYONE1": false,
+		"NOTACODE": false,
+	}
+	for code, valid := range codes {
+		if result := validator.IsValid(context.Background(), code); result != valid {
+			t.Errorf("IsValid(%q) after warming = %v, want %v", code, result, valid)
+		}
+	}
+}
+
+func TestValidator_WarmPageCache_Cancelled(t *testing.T) {
+	file1, file2, file3, cleanup := setupTestFiles(t)
+	defer cleanup()
+
+	validator := NewValidator()
+	defer validator.Close()
+	if err := validator.LoadFromFiles(context.Background(), []string{file1, file2, file3}); err != nil {
+		t.Fatalf("failed to load files: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := validator.WarmPageCache(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("WarmPageCache() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestValidator_WarmPageCache_Readers(t *testing.T) {
+	validator := NewValidator()
+	defer validator.Close()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("VALIDABC\n"),
+		strings.NewReader("VALIDABC\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+
+	got, err := validator.WarmPageCache(context.Background())
+	if err != nil || got != 0 {
+		t.Errorf("WarmPageCache() = %d, %v, want 0, nil", got, err)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// DefaultMaxBatchSize caps batch requests when no limit is configured
+const DefaultMaxBatchSize = 100
+
+// batchLimit returns the effective batch cap for a configured limit
+func batchLimit(maxBatchSize int) int {
+	if maxBatchSize <= 0 {
+		return DefaultMaxBatchSize
+	}
+	return maxBatchSize
+}
+
+// rejectOversizedBatch writes a 400 and returns true when a batch of n
+// entries exceeds the cap, bounding the work and response size per request
+func rejectOversizedBatch(w http.ResponseWriter, n, maxBatchSize int, entries string, logger *slog.Logger) bool {
+	limit := batchLimit(maxBatchSize)
+	if n <= limit {
+		return false
+	}
+	logger.Warn("batch request too large", "entries", entries, "count", n, "limit", limit)
+	WriteError(w, http.StatusBadRequest, fmt.Sprintf("Too many %s: at most %d per request", entries, limit), logger)
+	return true
+}
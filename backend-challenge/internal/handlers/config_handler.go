@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+// ConfigHandler exposes the effective configuration for debugging deployments
+type ConfigHandler struct {
+	config map[string]any
+	log    *slog.Logger
+}
+
+// NewConfigHandler creates a new config handler
+// The configuration is sanitized once up front, so secrets never reach the handler
+func NewConfigHandler(cfg *config.Config, log *slog.Logger) *ConfigHandler {
+	return &ConfigHandler{
+		config: cfg.Sanitized(),
+		log:    log,
+	}
+}
+
+// GetConfig handles GET /api/config
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, h.config, h.log)
+}
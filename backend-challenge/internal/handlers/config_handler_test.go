@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: "8080"},
+		Auth: config.AuthConfig{
+			APIKeys:        []string{"apitest"},
+			SigningSecrets: map[string]string{"apitest": "hmac-secret"},
+		},
+		LogLevel: "debug",
+	}
+	handler := NewConfigHandler(cfg, logger.New("error"))
+
+	w := httptest.NewRecorder()
+	handler.GetConfig(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "apitest") || strings.Contains(w.Body.String(), "hmac-secret") {
+		t.Errorf("response leaks a secret: %s", w.Body.String())
+	}
+
+	var resp struct {
+		Server   struct{ Port string }
+		Auth     struct{ APIKeys, SigningSecrets string }
+		LogLevel string
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Server.Port != "8080" || resp.LogLevel != "debug" {
+		t.Errorf("expected non-secret fields to be present, got %+v", resp)
+	}
+	if resp.Auth.APIKeys != "***" || resp.Auth.SigningSecrets != "***" {
+		t.Errorf("expected secrets to be redacted, got %+v", resp.Auth)
+	}
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// CouponValidator is the coupon validator functionality used by the coupon endpoints
+type CouponValidator interface {
+	IsValid(ctx context.Context, code string) bool
+	Inspect(ctx context.Context, code string) ([]coupon.FileInspection, error)
+	SetOverride(code string, valid *bool)
+	MaxConcurrentSearches() int
+}
+
+// CouponEvaluator is implemented by validators that can say why a code
+// passed or failed; batch validation reports those reasons when available
+type CouponEvaluator interface {
+	Evaluate(ctx context.Context, code string, opts coupon.EvaluateOptions) coupon.Evaluation
+}
+
+// CouponExistenceChecker is implemented by validators that can tell whether
+// a code is in any coupon file, regardless of the validity rule
+type CouponExistenceChecker interface {
+	Exists(ctx context.Context, code string) bool
+}
+
+// CouponRedeemer atomically validates and uses up single-use coupon codes
+// A retry with the key of an earlier successful redemption succeeds again
+type CouponRedeemer interface {
+	RedeemWithKey(ctx context.Context, code, idempotencyKey string) (bool, error)
+}
+
+// CouponInspection is the response body for the coupon inspect endpoint
+// Code is the canonical code that was inspected, Input the code as submitted
+type CouponInspection struct {
+	Code  string                  `json:"code"`
+	Input string                  `json:"input"`
+	Files []coupon.FileInspection `json:"files"`
+}
+
+// CouponValidation is the response body for single-code validation
+// Code is the canonical code that was checked, Input the code as submitted
+type CouponValidation struct {
+	Code  string `json:"code"`
+	Input string `json:"input"`
+	Valid bool   `json:"valid"`
+}
+
+// CouponExistence is the response body for the coupon exists probe
+type CouponExistence struct {
+	Code   string `json:"code"`
+	Input  string `json:"input"`
+	Exists bool   `json:"exists"`
+}
+
+// CouponRedemption is the response body for coupon redemption
+// Code is the canonical code that was redeemed, Input the code as submitted
+type CouponRedemption struct {
+	Code     string `json:"code"`
+	Input    string `json:"input"`
+	Redeemed bool   `json:"redeemed"`
+}
+
+// CouponBatchRequest is the request body for batch coupon validation
+type CouponBatchRequest struct {
+	Codes []string `json:"codes"`
+}
+
+// CouponBatchResponse maps each submitted code to its verdict and the reason
+type CouponBatchResponse struct {
+	Results map[string]coupon.Evaluation `json:"results"`
+}
+
+// CouponOverride is the request and response body for coupon overrides
+type CouponOverride struct {
+	Code  string `json:"code,omitempty"`
+	Valid *bool  `json:"valid"`
+}
+
+// CouponHandler handles coupon-related HTTP requests
+type CouponHandler struct {
+	validator     CouponValidator
+	redeemer      CouponRedeemer // optional, nil disables redemption
+	cacheTTL      time.Duration
+	maxBatchSize  int
+	invalidStatus int // status for invalid codes, see SetInvalidStatus
+	log           *slog.Logger
+}
+
+// NewCouponHandler creates a new coupon handler
+// cacheTTL is how long proxies may cache a valid result, 0 disables caching
+// maxBatchSize caps the codes per batch request, 0 uses DefaultMaxBatchSize
+func NewCouponHandler(validator CouponValidator, cacheTTL time.Duration, maxBatchSize int, log *slog.Logger) *CouponHandler {
+	return &CouponHandler{
+		validator:    validator,
+		cacheTTL:     cacheTTL,
+		maxBatchSize: maxBatchSize,
+		log:          log,
+	}
+}
+
+// SetRedeemer enables coupon redemption through redeemer
+func (h *CouponHandler) SetRedeemer(redeemer CouponRedeemer) {
+	h.redeemer = redeemer
+}
+
+// SetInvalidStatus sets the status ValidateCoupon answers invalid codes
+// with: http.StatusNotFound, the default when zero, or http.StatusOK for
+// consumers treating validation as a lookup
+func (h *CouponHandler) SetInvalidStatus(status int) {
+	h.invalidStatus = status
+}
+
+// ValidateCoupon handles GET /api/coupon/{couponCode}/validate
+// Invalid but well-formed codes get {"valid": false} with a 404, or a 200
+// when set by SetInvalidStatus; only a blank code is a 400
+// Validators that can report an unchecked code (service.CouponVerifier) get
+// a 503 instead of a false verdict while they are not ready or unavailable
+func (h *CouponHandler) ValidateCoupon(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "couponCode")
+	if strings.TrimSpace(code) == "" {
+		WriteError(w, http.StatusBadRequest, "Coupon code is required", h.log)
+		return
+	}
+
+	valid := false
+	if verifier, ok := h.validator.(service.CouponVerifier); ok {
+		var err error
+		if valid, err = verifier.Verify(r.Context(), code); err != nil {
+			h.writeVerifyError(w, err)
+			return
+		}
+	} else {
+		valid = h.validator.IsValid(r.Context(), code)
+	}
+	status := http.StatusOK
+	if !valid && h.invalidStatus != http.StatusOK {
+		status = http.StatusNotFound
+	}
+	h.setCacheControl(w, valid)
+	WriteJSON(w, status, CouponValidation{Code: h.canonicalize(code), Input: code, Valid: valid}, h.log)
+}
+
+// CouponExists handles GET /api/coupon/{couponCode}/exists
+// It reports whether the code is in at least one coupon file, for typeahead;
+// a code that exists need not be valid. Cached like validation results
+func (h *CouponHandler) CouponExists(w http.ResponseWriter, r *http.Request) {
+	checker, ok := h.validator.(CouponExistenceChecker)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Coupon existence checks are not supported", h.log)
+		return
+	}
+
+	code := chi.URLParam(r, "couponCode")
+	if strings.TrimSpace(code) == "" {
+		WriteError(w, http.StatusBadRequest, "Coupon code is required", h.log)
+		return
+	}
+
+	exists := checker.Exists(r.Context(), code)
+	h.setCacheControl(w, exists)
+	WriteJSON(w, http.StatusOK, CouponExistence{Code: h.canonicalize(code), Input: code, Exists: exists}, h.log)
+}
+
+// canonicalize returns the code in the form the validator checks it;
+// validators without their own canonicalization get it as submitted
+func (h *CouponHandler) canonicalize(code string) string {
+	if canonicalizer, ok := h.validator.(service.CouponCanonicalizer); ok {
+		return canonicalizer.Canonicalize(code)
+	}
+	return code
+}
+
+// writeVerifyError writes the response for a validation that did not complete
+func (h *CouponHandler) writeVerifyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, coupon.ErrNotReady):
+		WriteError(w, http.StatusServiceUnavailable, "Coupon validation is not ready yet, please retry", h.log)
+	case errors.Is(err, context.DeadlineExceeded):
+		WriteError(w, http.StatusGatewayTimeout, "Coupon could not be validated in time, please retry", h.log)
+	case errors.Is(err, context.Canceled):
+		WriteError(w, statusClientClosedRequest, "Request was cancelled", h.log)
+	default:
+		h.log.Warn("coupon validation unavailable", "error", err)
+		WriteError(w, http.StatusServiceUnavailable, "Coupon validation is temporarily unavailable, please retry", h.log)
+	}
+}
+
+// RedeemCoupon handles POST /api/coupon/{couponCode}/redeem
+// A valid code is marked used and {"redeemed": true} returned exactly once;
+// invalid and already redeemed codes get {"redeemed": false}. A retry
+// carrying the Idempotency-Key of the successful request gets true again
+func (h *CouponHandler) RedeemCoupon(w http.ResponseWriter, r *http.Request) {
+	if h.redeemer == nil {
+		WriteError(w, http.StatusNotFound, "Coupon redemption is not enabled", h.log)
+		return
+	}
+
+	code := chi.URLParam(r, "couponCode")
+	if strings.TrimSpace(code) == "" {
+		WriteError(w, http.StatusBadRequest, "Coupon code is required", h.log)
+		return
+	}
+
+	redeemed, err := h.redeemer.RedeemWithKey(r.Context(), code, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		h.writeVerifyError(w, err)
+		return
+	}
+	canonical := h.canonicalize(code)
+	if redeemed {
+		h.log.Info("coupon redeemed", "code", canonical)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, CouponRedemption{Code: canonical, Input: code, Redeemed: redeemed}, h.log)
+}
+
+// InspectCoupon handles GET /api/coupon/{couponCode}/inspect
+// Malformed codes get 400 rather than a search, and searches that can't
+// complete fail as they do for ValidateCoupon
+func (h *CouponHandler) InspectCoupon(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "couponCode")
+	if strings.TrimSpace(code) == "" {
+		WriteError(w, http.StatusBadRequest, "Coupon code is required", h.log)
+		return
+	}
+
+	files, err := h.validator.Inspect(r.Context(), code)
+	if errors.Is(err, coupon.ErrMalformedCode) {
+		WriteError(w, http.StatusBadRequest, "Coupon code has an invalid length or characters", h.log)
+		return
+	}
+	if err != nil {
+		h.writeVerifyError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, CouponInspection{Code: h.canonicalize(code), Input: code, Files: files}, h.log)
+}
+
+// SetOverride handles PUT /api/coupon/{couponCode}/override
+// The body {"valid": true|false} forces the code's result regardless of the files
+func (h *CouponHandler) SetOverride(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "couponCode")
+
+	var req CouponOverride
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Valid == nil {
+		WriteError(w, http.StatusBadRequest, "Request body must contain a boolean \"valid\"", h.log)
+		return
+	}
+
+	h.validator.SetOverride(code, req.Valid)
+	canonical := h.canonicalize(code)
+	h.log.Info("coupon override set", "code", canonical, "valid", *req.Valid)
+	WriteJSON(w, http.StatusOK, CouponOverride{Code: canonical, Valid: req.Valid}, h.log)
+}
+
+// DeleteOverride handles DELETE /api/coupon/{couponCode}/override
+func (h *CouponHandler) DeleteOverride(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "couponCode")
+
+	h.validator.SetOverride(code, nil)
+	h.log.Info("coupon override removed", "code", h.canonicalize(code))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ValidateBatch handles POST /api/coupon/validate
+// Codes are validated by a worker pool sized from the validator's search limit
+func (h *CouponHandler) ValidateBatch(w http.ResponseWriter, r *http.Request) {
+	var req CouponBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("failed to decode coupon batch request", "error", err)
+		WriteError(w, http.StatusBadRequest, "Invalid request body", h.log)
+		return
+	}
+	if len(req.Codes) == 0 {
+		WriteError(w, http.StatusBadRequest, "At least one coupon code is required", h.log)
+		return
+	}
+	if rejectOversizedBatch(w, len(req.Codes), h.maxBatchSize, "coupon codes", h.log) {
+		return
+	}
+
+	evaluations := h.validateAll(r.Context(), req.Codes)
+
+	results := make(map[string]coupon.Evaluation, len(req.Codes))
+	for i, code := range req.Codes {
+		results[code] = evaluations[i]
+	}
+	WriteJSON(w, http.StatusOK, CouponBatchResponse{Results: results}, h.log)
+}
+
+// setCacheControl lets the client briefly cache valid results; invalid results
+// are never stored so a code that becomes valid isn't cached negatively
+// Results are private: they answer authenticated requests, so a shared proxy
+// must not serve them to other clients
+func (h *CouponHandler) setCacheControl(w http.ResponseWriter, valid bool) {
+	if !valid || h.cacheTTL <= 0 {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(h.cacheTTL.Seconds())))
+	w.Header().Add("Vary", "api_key")
+}
+
+// validateAll validates codes concurrently, returning results by index
+func (h *CouponHandler) validateAll(ctx context.Context, codes []string) []coupon.Evaluation {
+	evaluations := make([]coupon.Evaluation, len(codes))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for n := min(batchWorkers(h.validator.MaxConcurrentSearches()), len(codes)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				evaluations[i] = h.evaluate(ctx, codes[i])
+			}
+		}()
+	}
+
+	for i := range codes {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return evaluations
+}
+
+// evaluate validates a code with its reason. Validators that can't explain
+// their verdicts report every invalid code as not found
+func (h *CouponHandler) evaluate(ctx context.Context, code string) coupon.Evaluation {
+	if evaluator, ok := h.validator.(CouponEvaluator); ok {
+		return evaluator.Evaluate(ctx, code, coupon.EvaluateOptions{})
+	}
+	if h.validator.IsValid(ctx, code) {
+		return coupon.Evaluation{Valid: true, Reason: coupon.ReasonValid}
+	}
+	return coupon.Evaluation{Reason: coupon.ReasonNotFound}
+}
+
+// batchWorkers sizes a batch worker pool from the validator's search limit
+// A single batch uses at most half the search slots, leaving the rest for
+// other requests; each worker may still fan out to several files, but the
+// validator's own semaphore bounds the total
+func batchWorkers(maxSearches int) int {
+	return max(1, maxSearches/2)
+}
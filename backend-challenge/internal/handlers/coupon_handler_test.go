@@ -0,0 +1,707 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// stubCouponValidator returns fixed results and records peak IsValid concurrency
+type stubCouponValidator struct {
+	valid       map[string]bool
+	files       []coupon.FileInspection
+	err         error
+	maxSearches int
+
+	overrides map[string]*bool
+
+	active atomic.Int64
+	peak   atomic.Int64
+}
+
+func (s *stubCouponValidator) SetOverride(code string, valid *bool) {
+	if s.overrides == nil {
+		s.overrides = make(map[string]*bool)
+	}
+	s.overrides[code] = valid
+}
+
+func (s *stubCouponValidator) IsValid(ctx context.Context, code string) bool {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return s.valid[code]
+}
+
+func (s *stubCouponValidator) Inspect(ctx context.Context, code string) ([]coupon.FileInspection, error) {
+	return s.files, s.err
+}
+
+func (s *stubCouponValidator) MaxConcurrentSearches() int {
+	return s.maxSearches
+}
+
+func TestCouponHandler_InspectCoupon(t *testing.T) {
+	files := []coupon.FileInspection{
+		{FileIndex: 0, BloomMaybe: true, FileContains: true},
+		{FileIndex: 1, BloomMaybe: true, FileContains: false},
+		{FileIndex: 2, BloomMaybe: false, FileContains: false},
+	}
+
+	tests := []struct {
+		name           string
+		validator      *stubCouponValidator
+		expectedStatus int
+	}{
+		{
+			name:           "returns per-file flags",
+			validator:      &stubCouponValidator{files: files},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "inspection error",
+			validator:      &stubCouponValidator{err: errors.New("read failed")},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "malformed code",
+			validator:      &stubCouponValidator{err: coupon.ErrMalformedCode},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not loaded",
+			validator:      &stubCouponValidator{err: coupon.ErrNotReady},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCouponHandler(tt.validator, 0, 0, logger.New("error"))
+
+			r := chi.NewRouter()
+			r.Get("/api/coupon/{couponCode}/inspect", handler.InspectCoupon)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/coupon/HAPPYHRS/inspect", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			// Response bodies use camelCase keys throughout
+			if body := w.Body.String(); !strings.Contains(body, `"fileIndex":1,"bloomMaybe":true,"fileContains":false`) {
+				t.Errorf("expected camelCase file keys, got %s", body)
+			}
+
+			var resp CouponInspection
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != "HAPPYHRS" {
+				t.Errorf("expected code HAPPYHRS, got %q", resp.Code)
+			}
+			if len(resp.Files) != len(files) || resp.Files[1] != files[1] {
+				t.Errorf("unexpected files in response: %+v", resp.Files)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_ValidateBatch(t *testing.T) {
+	validator := &stubCouponValidator{
+		valid:       map[string]bool{"HAPPYHRS": true, "FIFTYOFF": true},
+		maxSearches: 8,
+	}
+	handler := NewCouponHandler(validator, 0, 200, logger.New("error"))
+
+	// A large batch must not run more validations at once than the pool allows
+	codes := make([]string, 0, 200)
+	for i := 0; i < 100; i++ {
+		codes = append(codes, "HAPPYHRS", fmt.Sprintf("BADCODE%d", i))
+	}
+	body, _ := json.Marshal(CouponBatchRequest{Codes: codes})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/coupon/validate", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	handler.ValidateBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp CouponBatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Results["HAPPYHRS"].Valid {
+		t.Error("expected HAPPYHRS to be valid")
+	}
+	if resp.Results["BADCODE7"].Valid {
+		t.Error("expected BADCODE7 to be invalid")
+	}
+	if len(resp.Results) != 101 {
+		t.Errorf("expected 101 distinct results, got %d", len(resp.Results))
+	}
+
+	if peak := validator.peak.Load(); peak > int64(batchWorkers(validator.maxSearches)) {
+		t.Errorf("peak concurrent validations = %d, want <= %d", peak, batchWorkers(validator.maxSearches))
+	}
+}
+
+func TestCouponHandler_ValidateBatch_Reasons(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\nONEFILE1\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+
+	body, _ := json.Marshal(CouponBatchRequest{Codes: []string{"HAPPYHRS", "SHORT", "ONEFILE1", "WAYTOOLONGCODE"}})
+	req := httptest.NewRequest(http.MethodPost, "/api/coupon/validate", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	handler.ValidateBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp CouponBatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	expected := map[string]coupon.Evaluation{
+		"HAPPYHRS":       {Valid: true, Reason: coupon.ReasonValid},
+		"SHORT":          {Reason: coupon.ReasonTooShort},
+		"ONEFILE1":       {Reason: coupon.ReasonNotFound},
+		"WAYTOOLONGCODE": {Reason: coupon.ReasonTooLong},
+	}
+	for code, want := range expected {
+		if got := resp.Results[code]; got != want {
+			t.Errorf("%s: got %+v, want %+v", code, got, want)
+		}
+	}
+}
+
+func TestCouponHandler_ValidateBatch_InvalidRequest(t *testing.T) {
+	handler := NewCouponHandler(&stubCouponValidator{maxSearches: 8}, 0, 0, logger.New("error"))
+
+	for _, body := range []string{`{`, `{"codes":[]}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/coupon/validate", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ValidateBatch(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestCouponHandler_Overrides(t *testing.T) {
+	validator := &stubCouponValidator{}
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+
+	r := chi.NewRouter()
+	r.Put("/api/coupon/{couponCode}/override", handler.SetOverride)
+	r.Delete("/api/coupon/{couponCode}/override", handler.DeleteOverride)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expected       *bool
+	}{
+		{"force allow", http.MethodPut, `{"valid":true}`, http.StatusOK, &[]bool{true}[0]},
+		{"force deny", http.MethodPut, `{"valid":false}`, http.StatusOK, &[]bool{false}[0]},
+		{"missing valid", http.MethodPut, `{}`, http.StatusBadRequest, &[]bool{false}[0]},
+		{"remove", http.MethodDelete, ``, http.StatusNoContent, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/coupon/LEAKED01/override", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			got := validator.overrides["LEAKED01"]
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("override = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_CacheControl(t *testing.T) {
+	validator := &stubCouponValidator{
+		valid:       map[string]bool{"HAPPYHRS": true, "FIFTYOFF": true},
+		maxSearches: 8,
+	}
+
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		request  func() *http.Request
+		handle   func(*CouponHandler) http.HandlerFunc
+		want     string
+	}{
+		{
+			name:     "valid code is cached briefly",
+			cacheTTL: 30 * time.Second,
+			request:  func() *http.Request { return singleCouponRequest("HAPPYHRS") },
+			handle:   func(h *CouponHandler) http.HandlerFunc { return h.ValidateCoupon },
+			want:     "private, max-age=30",
+		},
+		{
+			name:     "invalid code is not stored",
+			cacheTTL: 30 * time.Second,
+			request:  func() *http.Request { return singleCouponRequest("BADCODE1") },
+			handle:   func(h *CouponHandler) http.HandlerFunc { return h.ValidateCoupon },
+			want:     "no-store",
+		},
+		{
+			name:     "zero TTL disables caching",
+			cacheTTL: 0,
+			request:  func() *http.Request { return singleCouponRequest("HAPPYHRS") },
+			handle:   func(h *CouponHandler) http.HandlerFunc { return h.ValidateCoupon },
+			want:     "no-store",
+		},
+		{
+			// POST responses aren't cached, so batches send no header
+			name:     "batch leaves caching alone",
+			cacheTTL: 30 * time.Second,
+			request:  func() *http.Request { return batchCouponRequest(`{"codes":["HAPPYHRS","FIFTYOFF"]}`) },
+			handle:   func(h *CouponHandler) http.HandlerFunc { return h.ValidateBatch },
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCouponHandler(validator, tt.cacheTTL, 0, logger.New("error"))
+			w := httptest.NewRecorder()
+			tt.handle(handler)(w, tt.request())
+
+			// Invalid codes are answered with 404 by default
+			if w.Code != http.StatusOK && w.Code != http.StatusNotFound {
+				t.Fatalf("expected status 200 or 404, got %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+			if strings.HasPrefix(tt.want, "private") && w.Header().Get("Vary") != "api_key" {
+				t.Errorf("Vary = %q, want api_key", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func singleCouponRequest(code string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/coupon/"+url.PathEscape(code)+"/validate", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("couponCode", code)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func batchCouponRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/api/coupon/validate", strings.NewReader(body))
+}
+
+func TestCouponHandler_ValidateBatch_Cap(t *testing.T) {
+	validator := &stubCouponValidator{valid: map[string]bool{"HAPPYHRS": true}, maxSearches: 8}
+
+	tests := []struct {
+		name           string
+		maxBatchSize   int
+		codes          int
+		expectedStatus int
+	}{
+		{"at cap", 3, 3, http.StatusOK},
+		{"above cap", 3, 4, http.StatusBadRequest},
+		{"at default cap", 0, DefaultMaxBatchSize, http.StatusOK},
+		{"above default cap", 0, DefaultMaxBatchSize + 1, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCouponHandler(validator, 0, tt.maxBatchSize, logger.New("error"))
+
+			codes := make([]string, tt.codes)
+			for i := range codes {
+				codes[i] = fmt.Sprintf("CODE%04d", i)
+			}
+			body, _ := json.Marshal(CouponBatchRequest{Codes: codes})
+
+			w := httptest.NewRecorder()
+			handler.ValidateBatch(w, batchCouponRequest(string(body)))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_ValidateCoupon_NotReady(t *testing.T) {
+	// A validator whose files haven't finished loading
+	handler := NewCouponHandler(coupon.NewValidator(), 0, 0, logger.New("error"))
+
+	w := httptest.NewRecorder()
+	handler.ValidateCoupon(w, singleCouponRequest("HAPPYHRS"))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCouponHandler_ValidateCoupon(t *testing.T) {
+	tests := []struct {
+		name          string
+		invalidStatus int
+		code          string
+		wantStatus    int
+		wantValid     bool
+	}{
+		{"valid code", 0, "HAPPYHRS", http.StatusOK, true},
+		{"invalid code is not found by default", 0, "BADCODE1", http.StatusNotFound, false},
+		{"invalid code with 404 set", http.StatusNotFound, "BADCODE1", http.StatusNotFound, false},
+		{"invalid code with 200 set", http.StatusOK, "BADCODE1", http.StatusOK, false},
+		{"valid code with 200 set", http.StatusOK, "HAPPYHRS", http.StatusOK, true},
+		{"missing code", 0, "", http.StatusBadRequest, false},
+		{"blank code with 200 set", http.StatusOK, "  ", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCouponHandler(&stubCouponValidator{valid: map[string]bool{"HAPPYHRS": true}}, 0, 0, logger.New("error"))
+			handler.SetInvalidStatus(tt.invalidStatus)
+			w := httptest.NewRecorder()
+			handler.ValidateCoupon(w, singleCouponRequest(tt.code))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Code == http.StatusBadRequest {
+				return
+			}
+			var resp CouponValidation
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", resp.Valid, tt.wantValid)
+			}
+			// The stub has no canonicalization of its own
+			if resp.Code != tt.code || resp.Input != tt.code {
+				t.Errorf("code, input = %q, %q, want %q for both", resp.Code, resp.Input, tt.code)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_ValidateCoupon_CanonicalCode(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+
+	tests := []struct {
+		name      string
+		input     string
+		wantCode  string
+		wantValid bool
+	}{
+		{"already canonical", "HAPPYHRS", "HAPPYHRS", true},
+		{"lowercase with whitespace", " happy hrs ", "HAPPYHRS", true},
+		{"formatted with dashes", "happy-hrs", "HAPPYHRS", true},
+		{"invalid code still normalized", " bad-code1", "BADCODE1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ValidateCoupon(w, singleCouponRequest(tt.input))
+
+			wantStatus := http.StatusOK
+			if !tt.wantValid {
+				wantStatus = http.StatusNotFound
+			}
+			if w.Code != wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, wantStatus, w.Body.String())
+			}
+			var resp CouponValidation
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != tt.wantCode || resp.Input != tt.input || resp.Valid != tt.wantValid {
+				t.Errorf("got %+v, want code %q, input %q, valid %v", resp, tt.wantCode, tt.input, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_InspectCoupon_CanonicalCode(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+
+	tests := []struct {
+		name       string
+		input      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"already canonical", "HAPPYHRS", http.StatusOK, "HAPPYHRS"},
+		{"formatted with dashes", "happy-hrs", http.StatusOK, "HAPPYHRS"},
+		{"blank", "   ", http.StatusBadRequest, ""},
+		{"too short", "happy", http.StatusBadRequest, ""},
+		{"disallowed characters", "HAPPY$HRS", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.InspectCoupon(w, singleCouponRequest(tt.input))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp CouponInspection
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != tt.wantCode || resp.Input != tt.input {
+				t.Errorf("got %+v, want code %q, input %q", resp, tt.wantCode, tt.input)
+			}
+			if len(resp.Files) != 2 || !resp.Files[0].FileContains || !resp.Files[1].FileContains {
+				t.Errorf("files = %+v, want HAPPYHRS in both", resp.Files)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_RedeemCoupon(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+	handler.SetRedeemer(coupon.NewRedeemer(validator))
+
+	r := chi.NewRouter()
+	r.Post("/api/coupon/{couponCode}/redeem", handler.RedeemCoupon)
+
+	tests := []struct {
+		name         string
+		code         string
+		wantCode     string
+		wantRedeemed bool
+	}{
+		{"first redemption", "happy-hrs", "HAPPYHRS", true},
+		{"already redeemed", "HAPPYHRS", "HAPPYHRS", false},
+		{"invalid code", "bad-code1", "BADCODE1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/coupon/"+tt.code+"/redeem", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			var resp CouponRedemption
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Redeemed != tt.wantRedeemed {
+				t.Errorf("redeemed = %v, want %v", resp.Redeemed, tt.wantRedeemed)
+			}
+			if resp.Code != tt.wantCode || resp.Input != tt.code {
+				t.Errorf("code, input = %q, %q, want %q, %q", resp.Code, resp.Input, tt.wantCode, tt.code)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_RedeemCoupon_IdempotencyKey(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+	handler.SetRedeemer(coupon.NewRedeemer(validator))
+
+	r := chi.NewRouter()
+	r.Post("/api/coupon/{couponCode}/redeem", handler.RedeemCoupon)
+
+	tests := []struct {
+		name         string
+		key          string
+		wantRedeemed bool
+	}{
+		{"first redemption", "checkout-42", true},
+		{"retried redemption", "checkout-42", true},
+		{"genuine second redemption", "checkout-43", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/coupon/HAPPYHRS/redeem", nil)
+			req.Header.Set("Idempotency-Key", tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			var resp CouponRedemption
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Redeemed != tt.wantRedeemed {
+				t.Errorf("redeemed = %v, want %v", resp.Redeemed, tt.wantRedeemed)
+			}
+		})
+	}
+}
+
+func TestCouponHandler_RedeemCoupon_NotReady(t *testing.T) {
+	validator := coupon.NewValidator()
+	handler := NewCouponHandler(validator, 0, 0, logger.New("error"))
+	handler.SetRedeemer(coupon.NewRedeemer(validator))
+
+	w := httptest.NewRecorder()
+	handler.RedeemCoupon(w, singleCouponRequest("HAPPYHRS"))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCouponHandler_CouponExists(t *testing.T) {
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("HAPPYHRS\nONEFILE1\n"),
+		strings.NewReader("HAPPYHRS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+	handler := NewCouponHandler(validator, time.Minute, 0, logger.New("error"))
+
+	r := chi.NewRouter()
+	r.Get("/api/coupon/{couponCode}/exists", handler.CouponExists)
+
+	tests := []struct {
+		name             string
+		code             string
+		wantExists       bool
+		wantCacheControl string
+	}{
+		{"code in every file", "HAPPYHRS", true, "private, max-age=60"},
+		{"code in one file exists", "onefile-1", true, "private, max-age=60"},
+		{"unknown code", "NOTACODE", false, "no-store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/coupon/"+tt.code+"/exists", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+			var resp CouponExistence
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Exists != tt.wantExists {
+				t.Errorf("exists = %v, want %v", resp.Exists, tt.wantExists)
+			}
+			if resp.Input != tt.code {
+				t.Errorf("input = %q, want %q", resp.Input, tt.code)
+			}
+		})
+	}
+
+	// Existing in one file is not enough to be valid
+	if validator.IsValid(context.Background(), "ONEFILE1") {
+		t.Error("expected ONEFILE1 to be invalid")
+	}
+}
+
+func TestCouponHandler_CouponExists_Unsupported(t *testing.T) {
+	handler := NewCouponHandler(&stubCouponValidator{}, 0, 0, logger.New("error"))
+
+	w := httptest.NewRecorder()
+	handler.CouponExists(w, singleCouponRequest("HAPPYHRS"))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
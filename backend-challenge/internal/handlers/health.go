@@ -1,32 +1,50 @@
 package handlers
 
 import (
-	"encoding/json"
 	"log/slog"
 	"net/http"
 	"time"
 )
 
+// CachePinger is a component whose cache can be probed by the deep health check
+type CachePinger interface {
+	PingCache()
+}
+
 // HealthHandler provides health check endpoint
 type HealthHandler struct {
-	logger *slog.Logger
+	cache       CachePinger
+	cacheBudget time.Duration
+	logger      *slog.Logger
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(logger *slog.Logger) *HealthHandler {
+// cache may be nil, in which case the deep check skips the cache probe
+func NewHealthHandler(cache CachePinger, cacheBudget time.Duration, logger *slog.Logger) *HealthHandler {
 	return &HealthHandler{
-		logger: logger,
+		cache:       cache,
+		cacheBudget: cacheBudget,
+		logger:      logger,
 	}
 }
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Version   string    `json:"version"`
+	Status    string            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Version   string            `json:"version"`
+	Checks    map[string]string `json:"checks,omitempty"`
 }
 
+// Health check results
+const (
+	checkOK   = "ok"
+	checkSlow = "slow"
+)
+
 // ServeHTTP handles health check requests
+// With ?deep=true subsystems are probed too; a slow subsystem reports
+// "degraded" but keeps the 200 status since the server can still serve
 func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status:    "healthy",
@@ -34,10 +52,33 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Version:   "1.0.0",
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	if r.URL.Query().Get("deep") == "true" && h.cache != nil {
+		response.Checks = map[string]string{"cache": h.pingCache()}
+		if response.Checks["cache"] != checkOK {
+			response.Status = "degraded"
+			h.logger.Warn("cache health check exceeded its budget", "budget", h.cacheBudget)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, response, h.logger)
+}
+
+// pingCache times a cache round trip against the budget without waiting
+// longer than the budget for a stalled cache
+func (h *HealthHandler) pingCache() string {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.cache.PingCache()
+	}()
+
+	timer := time.NewTimer(h.cacheBudget)
+	defer timer.Stop()
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("failed to encode health response", "error", err)
+	select {
+	case <-done:
+		return checkOK
+	case <-timer.C:
+		return checkSlow
 	}
 }
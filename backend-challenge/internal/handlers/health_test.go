@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+)
+
+// stubCachePinger simulates a cache round trip taking delay
+type stubCachePinger struct {
+	delay time.Duration
+}
+
+func (s stubCachePinger) PingCache() {
+	time.Sleep(s.delay)
+}
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		cache      CachePinger
+		target     string
+		wantStatus string
+		wantCache  string
+	}{
+		{"shallow check skips probes", stubCachePinger{delay: 200 * time.Millisecond}, "/health", "healthy", ""},
+		{"responsive cache", stubCachePinger{}, "/health?deep=true", "healthy", "ok"},
+		{"slow cache", stubCachePinger{delay: 200 * time.Millisecond}, "/health?deep=true", "degraded", "slow"},
+		{"no cache configured", nil, "/health?deep=true", "healthy", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(tt.cache, 20*time.Millisecond, logger.New("error"))
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+			start := time.Now()
+			handler.ServeHTTP(w, req)
+
+			// A stalled cache must not stall the health check past its budget
+			if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+				t.Errorf("health check took %v, want it bounded by the budget", elapsed)
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var resp HealthResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if got := resp.Checks["cache"]; got != tt.wantCache {
+				t.Errorf("cache check = %q, want %q", got, tt.wantCache)
+			}
+		})
+	}
+}
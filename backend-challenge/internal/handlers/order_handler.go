@@ -1,30 +1,48 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/timing"
 )
 
+// statusClientClosedRequest is the non-standard status for a client that went away
+const statusClientClosedRequest = 499
+
 // OrderHandler handles order-related HTTP requests
 type OrderHandler struct {
 	orderService *service.OrderService
+	serverTiming bool
 	log          *slog.Logger
 }
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler(orderService *service.OrderService, log *slog.Logger) *OrderHandler {
+// serverTiming adds a Server-Timing header breaking down where time was spent
+func NewOrderHandler(orderService *service.OrderService, serverTiming bool, log *slog.Logger) *OrderHandler {
 	return &OrderHandler{
 		orderService: orderService,
+		serverTiming: serverTiming,
 		log:          log,
 	}
 }
 
 // CreateOrder handles POST /api/order
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var rec *timing.Recorder
+	if h.serverTiming {
+		rec = &timing.Recorder{}
+		r = r.WithContext(timing.NewContext(r.Context(), rec))
+	}
+
 	var req models.OrderRequest
 
 	// Parse request body
@@ -34,27 +52,80 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate and create order
-	order, err := h.orderService.CreateOrder(r.Context(), req)
+	// Validate and create order; the Idempotency-Key buckets feature flags
+	ctx := requestinfo.WithIdempotencyKey(r.Context(), r.Header.Get("Idempotency-Key"))
+	order, err := h.orderService.CreateOrder(ctx, req)
+
+	// Headers must be set before the status is written
+	if rec != nil {
+		rec.Since("total", start)
+		w.Header().Set("Server-Timing", rec.Header())
+	}
+
 	if err != nil {
 		h.log.Error("failed to create order", "error", err)
-
-		switch err {
-		case service.ErrEmptyOrder:
-			WriteError(w, http.StatusBadRequest, "Order must contain at least one item", h.log)
-		case service.ErrInvalidQuantity:
-			WriteError(w, http.StatusBadRequest, "Quantity must be positive", h.log)
-		case service.ErrInvalidProduct:
-			WriteError(w, http.StatusBadRequest, "Invalid product", h.log)
-		case service.ErrInvalidCoupon:
-			WriteError(w, http.StatusBadRequest, "Coupon code is not valid", h.log)
-		default:
-			WriteError(w, http.StatusInternalServerError, "Internal server error", h.log)
-		}
+		h.writeOrderError(w, err)
 		return
 	}
 
 	// Return successful response
-	WriteJSON(w, http.StatusOK, order, h.log)
+	respond(w, r, http.StatusOK, order, h.log)
 	h.log.Info("order created successfully", "order_id", order.ID, "items_count", len(order.Items))
 }
+
+// CheckCoupon handles POST /api/coupon/check, reporting whether a coupon is
+// valid and applies to a cart, with the resulting discount
+func (h *OrderHandler) CheckCoupon(w http.ResponseWriter, r *http.Request) {
+	var req models.CouponCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode coupon check request", "error", err)
+		WriteError(w, http.StatusBadRequest, "Invalid request body", h.log)
+		return
+	}
+	if req.Coupon == "" {
+		WriteError(w, http.StatusBadRequest, "Coupon code is required", h.log)
+		return
+	}
+
+	ctx := requestinfo.WithIdempotencyKey(r.Context(), r.Header.Get("Idempotency-Key"))
+	check, err := h.orderService.CheckCoupon(ctx, req.Coupon, req.Cart)
+	if err != nil {
+		h.log.Error("failed to check coupon", "error", err)
+		h.writeOrderError(w, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, check, h.log)
+}
+
+// writeOrderError maps an order service error to its HTTP response
+func (h *OrderHandler) writeOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		WriteError(w, http.StatusGatewayTimeout, "Order could not be completed in time, please retry", h.log)
+	case errors.Is(err, context.Canceled):
+		WriteError(w, statusClientClosedRequest, "Request was cancelled", h.log)
+	case errors.Is(err, service.ErrEmptyOrder):
+		WriteError(w, http.StatusBadRequest, "Order must contain at least one item", h.log)
+	case errors.Is(err, service.ErrMissingProductID):
+		WriteError(w, http.StatusBadRequest, err.Error(), h.log)
+	case errors.Is(err, service.ErrInvalidQuantity):
+		WriteError(w, http.StatusBadRequest, "Quantity must be positive", h.log)
+	case errors.Is(err, service.ErrInvalidProduct):
+		WriteError(w, http.StatusBadRequest, "Invalid product", h.log)
+	case errors.Is(err, service.ErrUnknownStore):
+		WriteError(w, http.StatusBadRequest, "Unknown store", h.log)
+	case errors.Is(err, service.ErrInvalidCoupon):
+		WriteError(w, http.StatusBadRequest, "Coupon code is not valid", h.log)
+	case errors.Is(err, service.ErrCouponNotApplicable):
+		WriteError(w, http.StatusUnprocessableEntity, "Coupon is valid but no discount applies to this order", h.log)
+	case errors.Is(err, service.ErrCouponUnavailable):
+		WriteError(w, http.StatusServiceUnavailable, "Coupon validation is temporarily unavailable, please retry", h.log)
+	case errors.Is(err, service.ErrPriceChanged):
+		WriteError(w, http.StatusConflict, "Prices have changed, please review the order total", h.log)
+	case errors.Is(err, service.ErrOrderTooLarge):
+		WriteError(w, http.StatusBadRequest, "Order total exceeds the maximum allowed", h.log)
+	default:
+		WriteError(w, http.StatusInternalServerError, "Internal server error", h.log)
+	}
+}
@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
@@ -19,7 +26,7 @@ func TestOrderHandler_CreateOrder(t *testing.T) {
 	productRepo := repository.NewInMemoryProductRepository()
 	orderService := service.NewOrderService(productRepo, nil)
 	log := logger.New("info")
-	handler := NewOrderHandler(orderService, log)
+	handler := NewOrderHandler(orderService, false, log)
 
 	tests := []struct {
 		name           string
@@ -87,6 +94,24 @@ func TestOrderHandler_CreateOrder(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			checkResponse:  nil,
 		},
+		{
+			name: "expected total matches",
+			requestBody: map[string]interface{}{
+				"items":         []models.OrderItem{{ProductID: "1", Quantity: 2}},
+				"expectedTotal": 25.98,
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse:  nil,
+		},
+		{
+			name: "expected total outdated",
+			requestBody: map[string]interface{}{
+				"items":         []models.OrderItem{{ProductID: "1", Quantity: 2}},
+				"expectedTotal": 23.98,
+			},
+			expectedStatus: http.StatusConflict,
+			checkResponse:  nil,
+		},
 		{
 			name:           "invalid JSON",
 			requestBody:    "invalid json",
@@ -133,3 +158,304 @@ func TestOrderHandler_CreateOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderHandler_CreateOrder_XML(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	orderService := service.NewOrderService(productRepo, nil)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	body := `{"items":[{"productId":"1","quantity":2}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+
+	handler.CreateOrder(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Fatalf("Content-Type = %q, want application/xml", ct)
+	}
+
+	var order models.Order
+	if err := xml.NewDecoder(w.Body).Decode(&order); err != nil {
+		t.Fatalf("failed to decode XML: %v", err)
+	}
+	if order.ID == "" {
+		t.Error("order ID is empty")
+	}
+	if len(order.Items) != 1 || order.Items[0].ProductID != "1" || order.Items[0].Quantity != 2 {
+		t.Errorf("unexpected items %+v", order.Items)
+	}
+	if len(order.Products) != 1 || order.Products[0].ID != 1 {
+		t.Errorf("unexpected products %+v", order.Products)
+	}
+}
+
+func TestOrderHandler_CreateOrder_ContextDone(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	orderService := service.NewOrderService(productRepo, nil)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		expectedStatus int
+	}{
+		{name: "deadline exceeded", ctx: expired, expectedStatus: http.StatusGatewayTimeout},
+		{name: "cancelled", ctx: cancelled, expectedStatus: statusClientClosedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"items":[{"productId":"1","quantity":1}]}`
+			req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body)).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			handler.CreateOrder(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+// unavailableVerifier fails every coupon check, like a validator whose file
+// search circuit breaker is open
+type unavailableVerifier struct{}
+
+func (unavailableVerifier) IsValid(ctx context.Context, code string) bool { return false }
+
+func (unavailableVerifier) Verify(ctx context.Context, code string) (bool, error) {
+	return false, coupon.ErrSearchUnavailable
+}
+
+func TestOrderHandler_CreateOrder_CouponUnavailable(t *testing.T) {
+	orderService := service.NewOrderService(repository.NewInMemoryProductRepository(), unavailableVerifier{})
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	body := `{"items":[{"productId":"1","quantity":1}],"couponCode":"HAPPYHOURS"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateOrder(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestOrderHandler_CreateOrder_CouponNotApplicable(t *testing.T) {
+	validator := &stubCouponValidator{valid: map[string]bool{"HAPPYHRS": true}}
+	orderService := service.NewOrderService(repository.NewInMemoryProductRepository(), validator)
+	orderService.SetRejectUnmatchedCoupons(true)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	body := `{"items":[{"productId":"1","quantity":1}],"couponCode":"HAPPYHRS"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateOrder(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestOrderHandler_CreateOrder_ServerTiming(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	validator := &stubCouponValidator{valid: map[string]bool{"HAPPYHOURS": true}}
+	orderService := service.NewOrderService(productRepo, validator)
+	wellFormed := regexp.MustCompile(`^coupon;dur=\d+\.\d{3}, total;dur=\d+\.\d{3}$`)
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			handler := NewOrderHandler(orderService, enabled, logger.New("error"))
+
+			body := `{"couponCode":"HAPPYHOURS","items":[{"productId":"1","quantity":1}]}`
+			req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.CreateOrder(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			header := w.Header().Get("Server-Timing")
+			if !enabled {
+				if header != "" {
+					t.Errorf("Server-Timing = %q, want no header when disabled", header)
+				}
+				return
+			}
+			if !wellFormed.MatchString(header) {
+				t.Errorf("Server-Timing = %q, want coupon and total metrics", header)
+			}
+		})
+	}
+}
+
+func TestOrderHandler_CheckCoupon(t *testing.T) {
+	rules, err := service.ParseDiscountRules(`{"BIGSPENDER":[{"type":"minimum","minSubtotal":30},{"type":"percentage","rate":0.1}]}`)
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	validator := &stubCouponValidator{valid: map[string]bool{"BIGSPENDER": true}}
+	orderService := service.NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), validator, rules)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatus     int
+		wantValid      bool
+		wantApplicable bool
+		wantDiscount   models.Price
+	}{
+		{
+			name:           "valid and applicable",
+			body:           `{"coupon":"BIGSPENDER","cart":{"items":[{"productId":"1","quantity":3}]}}`,
+			wantStatus:     http.StatusOK,
+			wantValid:      true,
+			wantApplicable: true,
+			wantDiscount:   3.90,
+		},
+		{
+			name:       "valid but below the minimum",
+			body:       `{"coupon":"BIGSPENDER","cart":{"items":[{"productId":"1","quantity":1}]}}`,
+			wantStatus: http.StatusOK,
+			wantValid:  true,
+		},
+		{
+			name:       "invalid code",
+			body:       `{"coupon":"SUPER100","cart":{"items":[{"productId":"1","quantity":3}]}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing coupon",
+			body:       `{"cart":{"items":[{"productId":"1","quantity":1}]}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cart",
+			body:       `{"coupon":"BIGSPENDER","cart":{"items":[]}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/coupon/check", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.CheckCoupon(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var check models.CouponCheck
+			if err := json.NewDecoder(w.Body).Decode(&check); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if check.Valid != tt.wantValid || check.Applicable != tt.wantApplicable || check.Discount != tt.wantDiscount {
+				t.Errorf("got valid=%v applicable=%v discount=%v, want %v, %v, %v",
+					check.Valid, check.Applicable, check.Discount, tt.wantValid, tt.wantApplicable, tt.wantDiscount)
+			}
+		})
+	}
+}
+
+func TestOrderHandler_CreateOrder_CategoryCoupon(t *testing.T) {
+	// A code in both files is valid; the discount only covers Salad lines
+	validator := coupon.NewValidator()
+	err := validator.LoadFromReaders(context.Background(), []io.Reader{
+		strings.NewReader("SALADDAYS\nHAPPYHRS\n"),
+		strings.NewReader("SALADDAYS\n"),
+	})
+	if err != nil {
+		t.Fatalf("LoadFromReaders() error = %v", err)
+	}
+	defer validator.Close()
+
+	rules, err := service.ParseDiscountRules(`{"SALADDAYS":[{"type":"line","category":"Salad","rate":0.25}]}`)
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	orderService := service.NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), validator, rules)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	// 2x Chicken Waffle 12.99, 1x Caesar Salad 8.99, 2x Greek Salad 9.49:
+	// 25% off the 27.97 of salad is 6.99, so 53.95 becomes 46.96
+	body := `{"couponCode":"salad-days","items":[
+		{"productId":"1","quantity":2},
+		{"productId":"4","quantity":1},
+		{"productId":"5","quantity":2}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateOrder(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var order models.Order
+	if err := json.NewDecoder(w.Body).Decode(&order); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if order.AppliedCoupon != "SALADDAYS" || !order.CouponApplied {
+		t.Errorf("applied coupon = %q (%v), want SALADDAYS", order.AppliedCoupon, order.CouponApplied)
+	}
+	if order.Discount != 6.99 {
+		t.Errorf("Discount = %v, want 6.99", order.Discount)
+	}
+	if order.Total != 46.96 {
+		t.Errorf("Total = %v, want 46.96", order.Total)
+	}
+}
+
+func TestOrderHandler_CreateOrder_NullItems(t *testing.T) {
+	orderService := service.NewOrderService(repository.NewInMemoryProductRepository(), nil)
+	handler := NewOrderHandler(orderService, false, logger.New("error"))
+
+	tests := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{"null items array", `{"items":null}`, "Order must contain at least one item"},
+		{"null item", `{"items":[null]}`, "items[0]: productId is required"},
+		{"empty item after a valid one", `{"items":[{"productId":"1","quantity":1},{}]}`, "items[1]: productId is required"},
+		{"item without productId", `{"items":[{"quantity":2}]}`, "items[0]: productId is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.CreateOrder(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body["error"] != tt.wantError {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantError)
+			}
+		})
+	}
+}
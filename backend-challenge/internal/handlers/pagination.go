@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be a positive integer")
+	ErrInvalidOffset = errors.New("offset must be a non-negative integer")
+)
+
+// Pagination holds the parsed limit/offset query parameters
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+// parsePagination parses the limit and offset query parameters
+// A missing limit falls back to the configured default and limits above the
+// configured maximum are clamped to it
+func parsePagination(r *http.Request, cfg config.PaginationConfig) (Pagination, error) {
+	p := Pagination{
+		Limit:  cfg.DefaultPageSize,
+		Offset: 0,
+	}
+
+	query := r.URL.Query()
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return Pagination{}, ErrInvalidLimit
+		}
+		p.Limit = min(limit, cfg.MaxPageSize)
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return Pagination{}, ErrInvalidOffset
+		}
+		p.Offset = offset
+	}
+
+	return p, nil
+}
+
+// PageInfo describes where a page sits in the full result set
+type PageInfo struct {
+	Total      int
+	TotalPages int // 0 when there are no results
+	HasNext    bool
+	HasPrev    bool
+}
+
+// pageInfo computes the PageInfo of page p over total results. TotalPages
+// counts limit-sized pages, whatever the offset
+func pageInfo(total int, p Pagination) PageInfo {
+	info := PageInfo{
+		Total:   total,
+		HasNext: p.Offset+p.Limit < total,
+		HasPrev: p.Offset > 0,
+	}
+	if p.Limit > 0 {
+		info.TotalPages = (total + p.Limit - 1) / p.Limit
+	}
+	return info
+}
+
+// setPageHeaders reports info in response headers, since paged list responses
+// are plain arrays with nowhere to carry it. Call before writing the body
+func setPageHeaders(w http.ResponseWriter, info PageInfo) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(info.Total))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(info.TotalPages))
+	w.Header().Set("X-Has-Next", strconv.FormatBool(info.HasNext))
+	w.Header().Set("X-Has-Prev", strconv.FormatBool(info.HasPrev))
+}
+
+// paginate returns the page of items described by p
+func paginate[T any](items []T, p Pagination) []T {
+	if p.Offset >= len(items) {
+		return []T{}
+	}
+
+	end := min(p.Offset+p.Limit, len(items))
+	return items[p.Offset:end]
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+)
+
+// testPagination is the pagination configuration used by handler tests
+var testPagination = config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100}
+
+func TestParsePagination(t *testing.T) {
+	cfg := config.PaginationConfig{DefaultPageSize: 5, MaxPageSize: 50}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    Pagination
+		wantErr error
+	}{
+		{
+			name:  "defaults applied",
+			query: "",
+			want:  Pagination{Limit: 5, Offset: 0},
+		},
+		{
+			name:  "explicit limit and offset",
+			query: "?limit=10&offset=20",
+			want:  Pagination{Limit: 10, Offset: 20},
+		},
+		{
+			name:  "limit clamped to max",
+			query: "?limit=1000",
+			want:  Pagination{Limit: 50, Offset: 0},
+		},
+		{
+			name:    "negative limit",
+			query:   "?limit=-1",
+			wantErr: ErrInvalidLimit,
+		},
+		{
+			name:    "zero limit",
+			query:   "?limit=0",
+			wantErr: ErrInvalidLimit,
+		},
+		{
+			name:    "non-numeric limit",
+			query:   "?limit=abc",
+			wantErr: ErrInvalidLimit,
+		},
+		{
+			name:    "negative offset",
+			query:   "?offset=-5",
+			wantErr: ErrInvalidOffset,
+		},
+		{
+			name:    "non-numeric offset",
+			query:   "?offset=1.5",
+			wantErr: ErrInvalidOffset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tt.query, nil)
+
+			got, err := parsePagination(req, cfg)
+			if err != tt.wantErr {
+				t.Fatalf("parsePagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parsePagination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListProducts_Pagination(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	handler := NewProductHandler(svc, config.PaginationConfig{DefaultPageSize: 4, MaxPageSize: 6}, logger.New("error"))
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int64
+	}{
+		{
+			name:           "default page size",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{1, 2, 3, 4},
+		},
+		{
+			name:           "limit clamped to max page size",
+			query:          "?limit=50",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:           "last partial page",
+			query:          "?limit=4&offset=8",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{9, 10},
+		},
+		{
+			name:           "offset past end",
+			query:          "?offset=100",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{},
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListProducts(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var products []models.Product
+			if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(products) != len(tt.expectedIDs) {
+				t.Fatalf("products count = %d, want %d", len(products), len(tt.expectedIDs))
+			}
+
+			for i, id := range tt.expectedIDs {
+				if products[i].ID != id {
+					t.Errorf("products[%d].ID = %d, want %d", i, products[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		page  Pagination
+		want  PageInfo
+	}{
+		{"first page", 10, Pagination{Limit: 4}, PageInfo{Total: 10, TotalPages: 3, HasNext: true}},
+		{"middle page", 10, Pagination{Limit: 4, Offset: 4}, PageInfo{Total: 10, TotalPages: 3, HasNext: true, HasPrev: true}},
+		{"last partial page", 10, Pagination{Limit: 4, Offset: 8}, PageInfo{Total: 10, TotalPages: 3, HasPrev: true}},
+		{"exact last page", 8, Pagination{Limit: 4, Offset: 4}, PageInfo{Total: 8, TotalPages: 2, HasPrev: true}},
+		{"single page", 3, Pagination{Limit: 4}, PageInfo{Total: 3, TotalPages: 1}},
+		{"empty results", 0, Pagination{Limit: 4}, PageInfo{}},
+		{"offset past end", 10, Pagination{Limit: 4, Offset: 20}, PageInfo{Total: 10, TotalPages: 3, HasPrev: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageInfo(tt.total, tt.page); got != tt.want {
+				t.Errorf("pageInfo(%d, %+v) = %+v, want %+v", tt.total, tt.page, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListProducts_PageHeaders(t *testing.T) {
+	handler := NewProductHandler(service.NewProductService(repository.NewInMemoryProductRepository()), testPagination, logger.New("error"))
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   map[string]string
+	}{
+		{
+			name:  "first page",
+			query: "?limit=4",
+			want:  map[string]string{"X-Total-Count": "10", "X-Total-Pages": "3", "X-Has-Next": "true", "X-Has-Prev": "false"},
+		},
+		{
+			name:  "last partial page",
+			query: "?limit=4&offset=8",
+			want:  map[string]string{"X-Total-Count": "10", "X-Total-Pages": "3", "X-Has-Next": "false", "X-Has-Prev": "true"},
+		},
+		{
+			name:   "xml",
+			query:  "?limit=4&offset=4",
+			accept: "application/xml",
+			want:   map[string]string{"X-Total-Count": "10", "X-Total-Pages": "3", "X-Has-Next": "true", "X-Has-Prev": "true"},
+		},
+		{
+			name:  "store catalog",
+			query: "?store=default&limit=20",
+			want:  map[string]string{"X-Total-Count": "10", "X-Total-Pages": "1", "X-Has-Next": "false", "X-Has-Prev": "false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ListProducts(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			for header, want := range tt.want {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
 	"github.com/go-chi/chi/v5"
@@ -12,23 +19,66 @@ import (
 
 // ProductHandler handles product-related HTTP requests
 type ProductHandler struct {
-	service *service.ProductService
-	logger  *slog.Logger
+	service    *service.ProductService
+	pagination config.PaginationConfig
+	logger     *slog.Logger
 }
 
 // NewProductHandler creates a new product handler
-func NewProductHandler(service *service.ProductService, logger *slog.Logger) *ProductHandler {
+func NewProductHandler(service *service.ProductService, pagination config.PaginationConfig, logger *slog.Logger) *ProductHandler {
 	return &ProductHandler{
-		service: service,
-		logger:  logger,
+		service:    service,
+		pagination: pagination,
+		logger:     logger,
 	}
 }
 
 // ListProducts handles GET /api/product
-// Returns all available products as per OpenAPI spec
+// Returns available products as per OpenAPI spec, paged via optional limit/offset
+// The X-Total-Count, X-Total-Pages, X-Has-Next and X-Has-Prev headers
+// describe the page for clients building pagers
+// Passing ids=1,4,7 fetches just those products in one request instead
+// Passing store=X lists that store's catalog and prices instead of the default's
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if r.URL.Query().Has("ids") {
+		h.listProductsByIDs(w, r)
+		return
+	}
+
+	page, err := parsePagination(r, h.pagination)
+	if err != nil {
+		h.logger.Warn("invalid pagination parameters", "query", r.URL.RawQuery, "error", err)
+		WriteError(w, http.StatusBadRequest, err.Error(), h.logger)
+		return
+	}
+
+	if r.URL.Query().Has("store") {
+		h.listStoreProducts(w, r, page)
+		return
+	}
+
+	// JSON is streamed straight from the repository; XML needs the whole list
+	// for its root element, so it is built in memory
+	if !prefersXML(r.Header.Get("Accept")) {
+		// The headers precede the body, so the total is counted up front; a
+		// product added mid-stream can make it one off from the items sent
+		total, err := h.service.CountProducts(ctx)
+		if err != nil {
+			h.logger.Error("failed to count products", "error", err)
+			WriteError(w, http.StatusInternalServerError, "Internal server error", h.logger)
+			return
+		}
+		setPageHeaders(w, pageInfo(total, page))
+
+		w.Header().Add("Vary", "Accept")
+		streamJSONArray(w, http.StatusOK, func(yield func(models.Product) error) error {
+			return h.iterateProductPage(ctx, page, yield)
+		}, h.logger)
+		return
+	}
+
 	products, err := h.service.ListProducts(ctx)
 	if err != nil {
 		h.logger.Error("failed to list products", "error", err)
@@ -36,7 +86,48 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, products, h.logger)
+	setPageHeaders(w, pageInfo(len(products), page))
+	respond(w, r, http.StatusOK, models.ProductList(paginate(products, page)), h.logger)
+}
+
+// listStoreProducts handles GET /api/product?store=X
+func (h *ProductHandler) listStoreProducts(w http.ResponseWriter, r *http.Request, page Pagination) {
+	storeID := r.URL.Query().Get("store")
+	products, err := h.service.ListStoreProducts(r.Context(), storeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrStoreNotFound) {
+			WriteError(w, http.StatusNotFound, "Store not found", h.logger)
+			return
+		}
+		h.logger.Error("failed to list store products", "store", storeID, "error", err)
+		WriteError(w, http.StatusInternalServerError, "Internal server error", h.logger)
+		return
+	}
+
+	setPageHeaders(w, pageInfo(len(products), page))
+	respond(w, r, http.StatusOK, models.ProductList(paginate(products, page)), h.logger)
+}
+
+// errPageComplete stops product iteration once a page is full
+var errPageComplete = errors.New("page complete")
+
+// iterateProductPage calls yield for each product in the requested page
+func (h *ProductHandler) iterateProductPage(ctx context.Context, page Pagination, yield func(models.Product) error) error {
+	index := 0
+	err := h.service.IterateProducts(ctx, func(product models.Product) error {
+		defer func() { index++ }()
+		if index < page.Offset {
+			return nil
+		}
+		if index >= page.Offset+page.Limit {
+			return errPageComplete
+		}
+		return yield(product)
+	})
+	if errors.Is(err, errPageComplete) {
+		return nil
+	}
+	return err
 }
 
 // GetProduct handles GET /api/product/{productId}
@@ -44,6 +135,8 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 // - 200: successful operation
 // - 400: Invalid ID supplied
 // - 404: Product not found
+// Soft-deleted products are only returned with includeDeleted=true, which
+// lets historical orders display products that are no longer sold
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	productID := chi.URLParam(r, "productId")
@@ -69,7 +162,9 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "Invalid ID supplied", h.logger)
 		return
 	}
-	product, err := h.service.GetProduct(ctx, productIDInt)
+	includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("includeDeleted"))
+
+	product, err := h.service.GetProduct(ctx, productIDInt, includeDeleted)
 	if err != nil {
 		if err == repository.ErrProductNotFound {
 			h.logger.Info("product not found", "productId", productID)
@@ -82,5 +177,141 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, product, h.logger)
+	respond(w, r, http.StatusOK, product, h.logger)
+}
+
+// CreateProduct handles POST /api/product
+// Returns 201 with the stored product, or 409 if the ID is already taken
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var product models.Product
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		h.logger.Warn("failed to decode product request", "error", err)
+		WriteError(w, http.StatusBadRequest, "Invalid request body", h.logger)
+		return
+	}
+
+	if product.ID < 0 {
+		WriteError(w, http.StatusBadRequest, "Invalid ID supplied", h.logger)
+		return
+	}
+
+	created, err := h.service.CreateProduct(r.Context(), product)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProductExists):
+			h.logger.Info("product already exists", "productId", product.ID)
+			WriteError(w, http.StatusConflict, "Product already exists", h.logger)
+		case errors.Is(err, service.ErrInvalidProductName),
+			errors.Is(err, service.ErrInvalidProductPrice),
+			errors.Is(err, service.ErrInvalidProductCategory),
+			errors.Is(err, service.ErrInvalidProductImageURL):
+			h.logger.Warn("invalid product", "error", err)
+			WriteError(w, http.StatusBadRequest, err.Error(), h.logger)
+		default:
+			h.logger.Error("failed to create product", "error", err)
+			WriteError(w, http.StatusInternalServerError, "Internal server error", h.logger)
+		}
+		return
+	}
+
+	respond(w, r, http.StatusCreated, created, h.logger)
+	h.logger.Info("product created", "productId", created.ID)
+}
+
+// DeleteProduct handles DELETE /api/product/{productId}
+// Products are soft-deleted so orders that reference them stay valid
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "productId")
+
+	productIDInt, err := strconv.ParseInt(productID, 10, 64)
+	if err != nil || productIDInt <= 0 {
+		h.logger.Warn("invalid product ID", "productId", productID)
+		WriteError(w, http.StatusBadRequest, "Invalid ID supplied", h.logger)
+		return
+	}
+
+	if err := h.service.DeleteProduct(r.Context(), productIDInt); err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			WriteError(w, http.StatusNotFound, "Product not found", h.logger)
+			return
+		}
+
+		h.logger.Error("failed to delete product", "productId", productID, "error", err)
+		WriteError(w, http.StatusInternalServerError, "Internal server error", h.logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logger.Info("product deleted", "productId", productIDInt)
+}
+
+// ReloadProducts handles POST /api/product/reload
+// The catalog is swapped for the edited seed file in one step; a seed that
+// fails to load leaves the current catalog serving
+func (h *ProductHandler) ReloadProducts(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ReloadProducts(r.Context()); err != nil {
+		if errors.Is(err, repository.ErrReloadNotSupported) {
+			WriteError(w, http.StatusNotFound, "Products are not loaded from a seed file", h.logger)
+			return
+		}
+
+		h.logger.Error("failed to reload products", "error", err)
+		WriteError(w, http.StatusInternalServerError, "Failed to reload products", h.logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logger.Info("products reloaded")
+}
+
+// ListCategories handles GET /api/category
+// Returns the product categories accepted by the API
+func (h *ProductHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, models.Categories, h.logger)
+}
+
+// listProductsByIDs handles GET /api/product?ids=1,4,7
+// Unknown IDs are omitted from the response; at most MaxBatchSize IDs are accepted
+func (h *ProductHandler) listProductsByIDs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Count before parsing so an oversized list isn't parsed at all
+	rawIDs := r.URL.Query().Get("ids")
+	if rejectOversizedBatch(w, strings.Count(rawIDs, ",")+1, h.pagination.MaxBatchSize, "product IDs", h.logger) {
+		return
+	}
+
+	ids, err := parseProductIDs(rawIDs)
+	if err != nil {
+		h.logger.Warn("invalid product IDs", "ids", r.URL.Query().Get("ids"), "error", err)
+		WriteError(w, http.StatusBadRequest, "Invalid ID supplied", h.logger)
+		return
+	}
+
+	products, err := h.service.GetProducts(ctx, ids)
+	if err != nil {
+		h.logger.Error("failed to get products", "ids", ids, "error", err)
+		WriteError(w, http.StatusInternalServerError, "Internal server error", h.logger)
+		return
+	}
+
+	respond(w, r, http.StatusOK, models.ProductList(products), h.logger)
+}
+
+// parseProductIDs parses a comma-separated list of positive product IDs
+func parseProductIDs(value string) ([]int64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("no product IDs supplied")
+	}
+
+	parts := strings.Split(value, ",")
+	ids := make([]int64, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid product ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
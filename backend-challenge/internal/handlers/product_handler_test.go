@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
@@ -18,7 +23,7 @@ func TestListProducts(t *testing.T) {
 	repo := repository.NewInMemoryProductRepository()
 	svc := service.NewProductService(repo)
 	log := logger.New("error")
-	handler := NewProductHandler(svc, log)
+	handler := NewProductHandler(svc, testPagination, log)
 
 	// Create request
 	req := httptest.NewRequest(http.MethodGet, "/api/product", nil)
@@ -47,12 +52,62 @@ func TestListProducts(t *testing.T) {
 	}
 }
 
+func TestListProducts_ByStore(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	stores, err := repository.NewSeededStoreCatalog(context.Background(), repo)
+	if err != nil {
+		t.Fatalf("NewSeededStoreCatalog() error = %v", err)
+	}
+	svc.SetStores(stores)
+	handler := NewProductHandler(svc, testPagination, logger.New("error"))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantPrice  models.Price // price of product 1
+	}{
+		{"no store is the default store", "", http.StatusOK, 12.99},
+		{"default store", "?store=default", http.StatusOK, 12.99},
+		{"airport store", "?store=airport", http.StatusOK, 16.24},
+		{"unknown store", "?store=moon", http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListProducts(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var products []models.Product
+			if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(products) != 10 {
+				t.Fatalf("expected 10 products, got %d", len(products))
+			}
+			if products[0].ID != 1 || products[0].Price != tt.wantPrice {
+				t.Errorf("first product = %d at %v, want 1 at %v", products[0].ID, products[0].Price, tt.wantPrice)
+			}
+		})
+	}
+}
+
 func TestGetProduct_Success(t *testing.T) {
 	// Setup
 	repo := repository.NewInMemoryProductRepository()
 	svc := service.NewProductService(repo)
 	log := logger.New("error")
-	handler := NewProductHandler(svc, log)
+	handler := NewProductHandler(svc, testPagination, log)
 
 	// Create router to handle URL params
 	r := chi.NewRouter()
@@ -90,6 +145,10 @@ func TestGetProduct_Success(t *testing.T) {
 	if product.Category != "Waffle" {
 		t.Errorf("expected product category 'Waffle', got %s", product.Category)
 	}
+
+	if product.ImageURL == "" || product.Description == "" {
+		t.Errorf("expected seeded image and description, got %q and %q", product.ImageURL, product.Description)
+	}
 }
 
 func TestGetProduct_NotFound(t *testing.T) {
@@ -97,7 +156,7 @@ func TestGetProduct_NotFound(t *testing.T) {
 	repo := repository.NewInMemoryProductRepository()
 	svc := service.NewProductService(repo)
 	log := logger.New("error")
-	handler := NewProductHandler(svc, log)
+	handler := NewProductHandler(svc, testPagination, log)
 
 	// Create router to handle URL params
 	r := chi.NewRouter()
@@ -130,7 +189,7 @@ func TestGetProduct_InvalidID(t *testing.T) {
 	repo := repository.NewInMemoryProductRepository()
 	svc := service.NewProductService(repo)
 	log := logger.New("error")
-	handler := NewProductHandler(svc, log)
+	handler := NewProductHandler(svc, testPagination, log)
 
 	// Create router to handle URL params
 	r := chi.NewRouter()
@@ -179,7 +238,7 @@ func TestGetProduct_MultipleProducts(t *testing.T) {
 	repo := repository.NewInMemoryProductRepository()
 	svc := service.NewProductService(repo)
 	log := logger.New("error")
-	handler := NewProductHandler(svc, log)
+	handler := NewProductHandler(svc, testPagination, log)
 
 	// Create router to handle URL params
 	r := chi.NewRouter()
@@ -228,3 +287,403 @@ func TestGetProduct_MultipleProducts(t *testing.T) {
 		})
 	}
 }
+
+func TestListProducts_ByIDs(t *testing.T) {
+	// Setup
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	log := logger.New("error")
+	handler := NewProductHandler(svc, testPagination, log)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int64
+	}{
+		{"all found", "?ids=1,4,7", http.StatusOK, []int64{1, 4, 7}},
+		{"request order preserved", "?ids=7,1", http.StatusOK, []int64{7, 1}},
+		{"some missing", "?ids=2,999,3", http.StatusOK, []int64{2, 3}},
+		{"duplicates returned once", "?ids=5,5", http.StatusOK, []int64{5}},
+		{"none found", "?ids=998,999", http.StatusOK, []int64{}},
+		{"non-numeric ID", "?ids=1,abc", http.StatusBadRequest, nil},
+		{"negative ID", "?ids=-1", http.StatusBadRequest, nil},
+		{"empty list", "?ids=", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListProducts(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var products []models.Product
+			if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(products) != len(tc.expectedIDs) {
+				t.Fatalf("expected %d products, got %d", len(tc.expectedIDs), len(products))
+			}
+
+			for i, id := range tc.expectedIDs {
+				if products[i].ID != id {
+					t.Errorf("expected products[%d].ID = %d, got %d", i, id, products[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestListCategories(t *testing.T) {
+	// Setup
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	log := logger.New("error")
+	handler := NewProductHandler(svc, testPagination, log)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/category", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListCategories(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var categories []string
+	if err := json.NewDecoder(w.Body).Decode(&categories); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	expected := []string{"Waffle", "Salad", "Pizza", "Burger"}
+	if len(categories) != len(expected) {
+		t.Fatalf("expected %d categories, got %d", len(expected), len(categories))
+	}
+	for i, c := range expected {
+		if categories[i] != c {
+			t.Errorf("expected categories[%d] = %s, got %s", i, c, categories[i])
+		}
+	}
+
+	// Every seeded product must use a known category
+	products, _ := repo.GetAll(req.Context())
+	for _, p := range products {
+		if !models.IsValidCategory(p.Category) {
+			t.Errorf("seed product %d has unknown category %q", p.ID, p.Category)
+		}
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedID     int64
+		expectedImage  string
+		expectedDesc   string
+	}{
+		{
+			name:           "auto-assigned ID",
+			body:           `{"name":"Fish Burger","price":12.5,"category":"Burger"}`,
+			expectedStatus: http.StatusCreated,
+			expectedID:     11,
+		},
+		{
+			name:           "explicit new ID",
+			body:           `{"id":42,"name":"Fish Burger","price":12.5,"category":"Burger"}`,
+			expectedStatus: http.StatusCreated,
+			expectedID:     42,
+		},
+		{
+			name:           "duplicate ID",
+			body:           `{"id":1,"name":"Fish Burger","price":12.5,"category":"Burger"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "unknown category",
+			body:           `{"name":"Fish Burger","price":12.5,"category":"Sushi"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "image and description",
+			body:           `{"name":"Fish Burger","price":12.5,"category":"Burger","imageUrl":"https://cdn.test/fish.jpg","description":"Battered cod"}`,
+			expectedStatus: http.StatusCreated,
+			expectedID:     11,
+			expectedImage:  "https://cdn.test/fish.jpg",
+			expectedDesc:   "Battered cod",
+		},
+		{
+			name:           "relative image URL",
+			body:           `{"name":"Fish Burger","price":12.5,"category":"Burger","imageUrl":"fish.jpg"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "non-http image URL",
+			body:           `{"name":"Fish Burger","price":12.5,"category":"Burger","imageUrl":"javascript:alert(1)"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewInMemoryProductRepository()
+			svc := service.NewProductService(repo)
+			handler := NewProductHandler(svc, testPagination, logger.New("error"))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/product", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.CreateProduct(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var product models.Product
+			if err := json.NewDecoder(w.Body).Decode(&product); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if product.ID != tt.expectedID {
+				t.Errorf("expected ID %d, got %d", tt.expectedID, product.ID)
+			}
+			if product.ImageURL != tt.expectedImage || product.Description != tt.expectedDesc {
+				t.Errorf("expected image %q and description %q, got %q and %q", tt.expectedImage, tt.expectedDesc, product.ImageURL, product.Description)
+			}
+
+			// The duplicate must not have overwritten the seeded product
+			if seeded, _ := repo.GetByID(req.Context(), 1); seeded.Name != "Chicken Waffle" {
+				t.Errorf("seeded product overwritten: %+v", seeded)
+			}
+		})
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	handler := NewProductHandler(svc, testPagination, logger.New("error"))
+
+	r := chi.NewRouter()
+	r.Get("/api/product", handler.ListProducts)
+	r.Get("/api/product/{productId}", handler.GetProduct)
+	r.Delete("/api/product/{productId}", handler.DeleteProduct)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		expectedStatus int
+	}{
+		{"delete existing", http.MethodDelete, "/api/product/3", http.StatusNoContent},
+		{"delete again", http.MethodDelete, "/api/product/3", http.StatusNotFound},
+		{"delete invalid ID", http.MethodDelete, "/api/product/abc", http.StatusBadRequest},
+		{"get deleted", http.MethodGet, "/api/product/3", http.StatusNotFound},
+		{"get deleted for history", http.MethodGet, "/api/product/3?includeDeleted=true", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(tt.method, tt.target); w.Code != tt.expectedStatus {
+				t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.target, tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	var products []models.Product
+	if err := json.NewDecoder(serve(http.MethodGet, "/api/product").Body).Decode(&products); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, p := range products {
+		if p.ID == 3 {
+			t.Error("deleted product returned by list")
+		}
+	}
+
+	var historical models.Product
+	if err := json.NewDecoder(serve(http.MethodGet, "/api/product/3?includeDeleted=true").Body).Decode(&historical); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if historical.DeletedAt == nil {
+		t.Error("expected deletedAt on historical product")
+	}
+}
+
+func TestListProducts_StreamsLargeCatalog(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	for i := 0; i < 10000; i++ {
+		if _, err := repo.Create(context.Background(), models.Product{Name: "Bulk", Price: 1, Category: "Salad"}); err != nil {
+			t.Fatalf("failed to seed product: %v", err)
+		}
+	}
+
+	svc := service.NewProductService(repo)
+	pagination := config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 20000}
+	handler := NewProductHandler(svc, pagination, logger.New("error"))
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+		firstID  int64
+	}{
+		{"whole catalog", "?limit=20000", 10010, 1},
+		{"page in the middle", "?limit=500&offset=5000", 500, 5001},
+		{"offset past the end", "?offset=20000", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/product"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.ListProducts(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			var products []models.Product
+			if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+				t.Fatalf("failed to decode streamed response: %v", err)
+			}
+			if len(products) != tt.expected {
+				t.Fatalf("expected %d products, got %d", tt.expected, len(products))
+			}
+			if products == nil {
+				t.Error("expected an empty array, not null")
+			}
+			for i := 1; i < len(products); i++ {
+				if products[i].ID <= products[i-1].ID {
+					t.Fatalf("products not in ID order at index %d", i)
+				}
+			}
+			if len(products) > 0 && products[0].ID != tt.firstID {
+				t.Errorf("expected first ID %d, got %d", tt.firstID, products[0].ID)
+			}
+		})
+	}
+}
+
+func TestListProducts_ByIDs_BatchCap(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	pagination := config.PaginationConfig{DefaultPageSize: 20, MaxPageSize: 100, MaxBatchSize: 3}
+	handler := NewProductHandler(svc, pagination, logger.New("error"))
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"below cap", "?ids=1,2", http.StatusOK},
+		{"at cap", "?ids=1,2,3", http.StatusOK},
+		{"above cap", "?ids=1,2,3,4", http.StatusBadRequest},
+		{"above cap with invalid IDs", "?ids=a,b,c,d", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ListProducts(w, httptest.NewRequest(http.MethodGet, "/api/product"+tc.query, nil))
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	// Without a configured cap the default applies
+	handler = NewProductHandler(svc, testPagination, logger.New("error"))
+	ids := strings.TrimSuffix(strings.Repeat("1,", DefaultMaxBatchSize+1), ",")
+	w := httptest.NewRecorder()
+	handler.ListProducts(w, httptest.NewRequest(http.MethodGet, "/api/product?ids="+ids, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 above the default cap, got %d", w.Code)
+	}
+}
+
+func TestReloadProducts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	writeSeed := func(seed string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(seed), 0644); err != nil {
+			t.Fatalf("failed to write seed file: %v", err)
+		}
+	}
+
+	writeSeed(`[{"id":1,"name":"Chicken Waffle","price":12.99,"category":"Waffle"}]`)
+	repo, err := repository.NewInMemoryProductRepositoryFromFile(path)
+	if err != nil {
+		t.Fatalf("NewInMemoryProductRepositoryFromFile() error = %v", err)
+	}
+	handler := NewProductHandler(service.NewProductService(repo), testPagination, logger.New("error"))
+
+	r := chi.NewRouter()
+	r.Get("/api/product", handler.ListProducts)
+	r.Post("/api/product/reload", handler.ReloadProducts)
+
+	listNames := func() []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/product", nil))
+		var products []models.Product
+		if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		names := make([]string, len(products))
+		for i, product := range products {
+			names[i] = product.Name
+		}
+		return names
+	}
+
+	if got := listNames(); strings.Join(got, ",") != "Chicken Waffle" {
+		t.Fatalf("catalog before reload = %v", got)
+	}
+
+	writeSeed(`[{"id":2,"name":"Belgian Waffle","price":10.99,"category":"Waffle"},{"id":7,"name":"Margherita Pizza","price":14.99,"category":"Pizza"}]`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/product/reload", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("reload status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body.String())
+	}
+
+	if got := listNames(); strings.Join(got, ",") != "Belgian Waffle,Margherita Pizza" {
+		t.Errorf("catalog after reload = %v, want the edited seed", got)
+	}
+}
+
+func TestReloadProducts_NoSeedFile(t *testing.T) {
+	handler := NewProductHandler(service.NewProductService(repository.NewInMemoryProductRepository()), testPagination, logger.New("error"))
+
+	w := httptest.NewRecorder()
+	handler.ReloadProducts(w, httptest.NewRequest(http.MethodPost, "/api/product/reload", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -1,28 +1,99 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // WriteJSON writes a JSON response
+// The body is encoded before anything is sent, so an encoding failure
+// becomes a clean 500 instead of a half-written response
 func WriteJSON(w http.ResponseWriter, status int, data interface{}, logger *slog.Logger) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
 		logger.Error("failed to encode JSON response", "error", err)
+		writeBody(w, http.StatusInternalServerError, "application/json", internalErrorBody, logger)
+		return
 	}
+
+	writeBody(w, status, "application/json", buf.Bytes(), logger)
+}
+
+// WriteXML writes an XML response
+// Like WriteJSON, the body is fully encoded before the status is sent
+func WriteXML(w http.ResponseWriter, status int, data interface{}, logger *slog.Logger) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(data); err != nil {
+		logger.Error("failed to encode XML response", "error", err)
+		writeBody(w, http.StatusInternalServerError, "application/json", internalErrorBody, logger)
+		return
+	}
+
+	writeBody(w, status, "application/xml", buf.Bytes(), logger)
 }
 
 // WriteError writes an error response in JSON format
 func WriteError(w http.ResponseWriter, status int, message string, logger *slog.Logger) {
-	w.Header().Set("Content-Type", "application/json")
+	WriteJSON(w, status, map[string]string{"error": message}, logger)
+}
+
+// internalErrorBody is the pre-encoded envelope sent when a response fails to encode
+var internalErrorBody = []byte(`{"error":"Internal server error"}` + "\n")
+
+// writeBody sends the status, content type and an already-encoded body
+func writeBody(w http.ResponseWriter, status int, contentType string, body []byte, logger *slog.Logger) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 
-	response := map[string]string{"error": message}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logger.Error("failed to encode error response", "error", err)
+	if _, err := w.Write(body); err != nil {
+		logger.Error("failed to write response", "error", err)
 	}
 }
+
+// respond writes data in the format negotiated via the Accept header
+// XML is used when the client prefers it, JSON otherwise
+func respond(w http.ResponseWriter, r *http.Request, status int, data interface{}, logger *slog.Logger) {
+	w.Header().Add("Vary", "Accept")
+
+	if prefersXML(r.Header.Get("Accept")) {
+		WriteXML(w, status, data, logger)
+		return
+	}
+	WriteJSON(w, status, data, logger)
+}
+
+// prefersXML reports whether the Accept header ranks XML above JSON
+// Ties, wildcards and missing headers resolve to JSON
+func prefersXML(accept string) bool {
+	var jsonQ, xmlQ float64
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qStr, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch mediaType {
+		case "application/xml", "text/xml":
+			xmlQ = max(xmlQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+
+	return xmlQ > jsonQ
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/service"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/pkg/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPrefersXML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"application/xml", true},
+		{"text/xml", true},
+		{"*/*", false},
+		{"application/xml, application/json", false},
+		{"application/xml, application/json;q=0.5", true},
+		{"application/json;q=0.9, application/xml", true},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", true},
+		{"not a media type", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := prefersXML(tt.accept); got != tt.want {
+				t.Errorf("prefersXML(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentNegotiation_Products(t *testing.T) {
+	repo := repository.NewInMemoryProductRepository()
+	svc := service.NewProductService(repo)
+	handler := NewProductHandler(svc, testPagination, logger.New("error"))
+
+	r := chi.NewRouter()
+	r.Get("/api/product", handler.ListProducts)
+	r.Get("/api/product/{productId}", handler.GetProduct)
+
+	t.Run("list as JSON by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/product", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("Content-Type = %q, want application/json", ct)
+		}
+
+		var products []models.Product
+		if err := json.NewDecoder(w.Body).Decode(&products); err != nil {
+			t.Fatalf("failed to decode JSON: %v", err)
+		}
+		if len(products) != 10 {
+			t.Errorf("expected 10 products, got %d", len(products))
+		}
+	})
+
+	t.Run("list as XML", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/product", nil)
+		req.Header.Set("Accept", "application/xml")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Fatalf("Content-Type = %q, want application/xml", ct)
+		}
+		if !strings.HasPrefix(w.Body.String(), "<?xml") {
+			t.Errorf("expected XML declaration, got %q", w.Body.String())
+		}
+
+		var list struct {
+			XMLName  xml.Name         `xml:"products"`
+			Products []models.Product `xml:"product"`
+		}
+		if err := xml.NewDecoder(w.Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode XML: %v", err)
+		}
+		if len(list.Products) != 10 {
+			t.Errorf("expected 10 products, got %d", len(list.Products))
+		}
+		if list.Products[0].Name != "Chicken Waffle" {
+			t.Errorf("expected first product 'Chicken Waffle', got %s", list.Products[0].Name)
+		}
+	})
+
+	t.Run("single product as XML", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/product/7", nil)
+		req.Header.Set("Accept", "application/xml")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var product models.Product
+		if err := xml.NewDecoder(w.Body).Decode(&product); err != nil {
+			t.Fatalf("failed to decode XML: %v", err)
+		}
+		if product.ID != 7 || product.Name != "Margherita Pizza" || product.Price != 14.99 {
+			t.Errorf("unexpected product %+v", product)
+		}
+	})
+}
+
+// failingMarshaler always fails to encode
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func (failingMarshaler) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return errors.New("marshal failed")
+}
+
+func TestWriteResponse_EncodingFailure(t *testing.T) {
+	writers := map[string]func(w http.ResponseWriter, status int, data interface{}){
+		"json": func(w http.ResponseWriter, status int, data interface{}) {
+			WriteJSON(w, status, data, logger.New("error"))
+		},
+		"xml": func(w http.ResponseWriter, status int, data interface{}) {
+			WriteXML(w, status, data, logger.New("error"))
+		},
+	}
+
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			// A partially encodable value must not leak a 200 or partial body
+			write(w, http.StatusOK, []interface{}{"ok", failingMarshaler{}})
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("expected status 500, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected JSON error content type, got %q", ct)
+			}
+
+			var resp map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected a clean JSON error body, got %q: %v", w.Body.String(), err)
+			}
+			if resp["error"] != "Internal server error" {
+				t.Errorf("unexpected error message %q", resp["error"])
+			}
+		})
+	}
+}
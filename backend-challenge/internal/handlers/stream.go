@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// streamJSONArray writes the items produced by iterate as a JSON array,
+// encoding one item at a time so memory stays flat regardless of size
+//
+// Each item is encoded before anything is written, so an error before the
+// first item still becomes a clean 500. Once the array has started the
+// status is committed; a later error is logged and the response is cut off,
+// leaving invalid JSON rather than a silently truncated list
+func streamJSONArray[T any](w http.ResponseWriter, status int, iterate func(yield func(T) error) error, logger *slog.Logger) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	started := false
+
+	start := func() error {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		started = true
+		_, err := w.Write([]byte("["))
+		return err
+	}
+
+	err := iterate(func(item T) error {
+		buf.Reset()
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte(",")); err != nil {
+			return err
+		}
+
+		_, err := w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		return err
+	})
+	if err != nil {
+		logger.Error("failed to stream JSON response", "error", err)
+		if !started {
+			WriteError(w, http.StatusInternalServerError, "Internal server error", logger)
+		}
+		return
+	}
+
+	if !started {
+		if err := start(); err != nil {
+			logger.Error("failed to write response", "error", err)
+			return
+		}
+	}
+	if _, err := w.Write([]byte("]\n")); err != nil {
+		logger.Error("failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultInterval is used when no positive update interval is configured
+const DefaultInterval = 15 * time.Second
+
+// StatsSource provides coupon validator statistics
+type StatsSource interface {
+	GetStats() map[string]interface{}
+}
+
+// CouponCollector periodically copies coupon validator stats into gauges
+type CouponCollector struct {
+	source   StatsSource
+	interval time.Duration
+
+	filterMemory  prometheus.Gauge
+	cacheSize     prometheus.Gauge
+	cacheCapacity prometheus.Gauge
+	cacheHitRate  prometheus.Gauge
+}
+
+// NewCouponCollector creates a collector and registers its gauges with reg
+func NewCouponCollector(source StatsSource, reg prometheus.Registerer, interval time.Duration) (*CouponCollector, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	c := &CouponCollector{
+		source:   source,
+		interval: interval,
+		filterMemory: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "coupon_filter_memory_bytes",
+			Help: "Estimated memory used by the coupon Bloom filters",
+		}),
+		cacheSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "coupon_cache_size",
+			Help: "Number of entries in the coupon validation cache",
+		}),
+		cacheCapacity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "coupon_cache_capacity",
+			Help: "Maximum number of entries in the coupon validation cache",
+		}),
+		cacheHitRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "coupon_cache_hit_ratio",
+			Help: "Fraction of coupon validations answered from the cache",
+		}),
+	}
+
+	for _, g := range []prometheus.Collector{c.filterMemory, c.cacheSize, c.cacheCapacity, c.cacheHitRate} {
+		if err := reg.Register(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Run updates the gauges every interval until ctx is cancelled
+func (c *CouponCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.Update()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Update()
+		}
+	}
+}
+
+// Update reads the current stats and sets the gauges
+func (c *CouponCollector) Update() {
+	stats := c.source.GetStats()
+
+	c.filterMemory.Set(toFloat(stats["filterMemoryBytes"]))
+	c.cacheSize.Set(toFloat(stats["cacheSize"]))
+	c.cacheCapacity.Set(toFloat(stats["cacheCapacity"]))
+
+	hits, misses := toFloat(stats["cacheHits"]), toFloat(stats["cacheMisses"])
+	if total := hits + misses; total > 0 {
+		c.cacheHitRate.Set(hits / total)
+	} else {
+		c.cacheHitRate.Set(0)
+	}
+}
+
+// toFloat converts a numeric stat to float64, treating missing values as zero
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type stubStats map[string]interface{}
+
+func (s stubStats) GetStats() map[string]interface{} { return s }
+
+func TestCouponCollector_Update(t *testing.T) {
+	tests := []struct {
+		name         string
+		stats        stubStats
+		wantMemory   float64
+		wantSize     float64
+		wantCapacity float64
+		wantHitRate  float64
+	}{
+		{
+			name: "populated stats",
+			stats: stubStats{
+				"filterMemoryBytes": uint64(4096),
+				"cacheSize":         25,
+				"cacheCapacity":     100,
+				"cacheHits":         int64(3),
+				"cacheMisses":       int64(1),
+			},
+			wantMemory:   4096,
+			wantSize:     25,
+			wantCapacity: 100,
+			wantHitRate:  0.75,
+		},
+		{
+			name:  "no lookups yet",
+			stats: stubStats{"cacheCapacity": 100},
+			// Hit rate stays zero rather than NaN before any lookups
+			wantCapacity: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCouponCollector(tt.stats, prometheus.NewRegistry(), time.Second)
+			if err != nil {
+				t.Fatalf("NewCouponCollector() error = %v", err)
+			}
+			c.Update()
+
+			if got := testutil.ToFloat64(c.filterMemory); got != tt.wantMemory {
+				t.Errorf("filter memory = %v, want %v", got, tt.wantMemory)
+			}
+			if got := testutil.ToFloat64(c.cacheSize); got != tt.wantSize {
+				t.Errorf("cache size = %v, want %v", got, tt.wantSize)
+			}
+			if got := testutil.ToFloat64(c.cacheCapacity); got != tt.wantCapacity {
+				t.Errorf("cache capacity = %v, want %v", got, tt.wantCapacity)
+			}
+			if got := testutil.ToFloat64(c.cacheHitRate); got != tt.wantHitRate {
+				t.Errorf("cache hit rate = %v, want %v", got, tt.wantHitRate)
+			}
+		})
+	}
+}
+
+func TestCouponCollector_RunStopsOnCancel(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c, err := NewCouponCollector(stubStats{"cacheSize": 7}, reg, time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewCouponCollector() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after context cancellation")
+	}
+
+	if got := testutil.ToFloat64(c.cacheSize); got != 7 {
+		t.Errorf("cache size = %v, want 7", got)
+	}
+}
+
+func TestNewCouponCollector_DuplicateRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := NewCouponCollector(stubStats{}, reg, time.Second); err != nil {
+		t.Fatalf("first NewCouponCollector() error = %v", err)
+	}
+	if _, err := NewCouponCollector(stubStats{}, reg, time.Second); err == nil {
+		t.Error("second NewCouponCollector() on the same registry should fail")
+	}
+}
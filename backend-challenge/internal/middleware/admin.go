@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+)
+
+// AdminOnly middleware lets through only requests whose API key is listed in
+// AdminKeyIDs, refusing customer keys with 403. Keys are matched by the key
+// ID APIKeyAuth identified, so it must run first
+func AdminOnly(cfg config.AuthConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID := requestinfo.APIKeyID(r.Context())
+			if keyID == "" || !slices.Contains(cfg.AdminKeyIDs, keyID) {
+				http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+func TestAdminOnly(t *testing.T) {
+	cfg := config.AuthConfig{
+		APIKeys:      []string{"apitest", "adminkey1"},
+		APIKeyHashes: map[string]string{"ops": testKeyHash(t, "hashedadmin")},
+		AdminKeyIDs:  []string{"adminkey1", "ops"},
+	}
+	handler := APIKeyAuth(cfg)(AdminOnly(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{"admin key", "adminkey1", http.StatusOK},
+		{"hashed admin key", "hashedadmin", http.StatusOK},
+		{"customer key", "apitest", http.StatusForbidden},
+		{"invalid key", "wrongkey", http.StatusForbidden},
+		{"missing key", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/coupon/HAPPYHRS/override", nil)
+			if tt.apiKey != "" {
+				req.Header.Set("api_key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	// Without admin keys configured, admin routes refuse every key
+	locked := APIKeyAuth(config.AuthConfig{APIKeys: []string{"apitest"}})(AdminOnly(config.AuthConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	req := httptest.NewRequest(http.MethodPut, "/api/coupon/HAPPYHRS/override", nil)
+	req.Header.Set("api_key", "apitest")
+	w := httptest.NewRecorder()
+	locked.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status without admin keys = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -3,12 +3,19 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/apikey"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
 )
 
 // APIKeyAuth middleware validates API key from header
 // According to OpenAPI spec, API key is passed in "api_key" header
+// Keys are checked against both the plaintext APIKeys and the APIKeyHashes
+// Authenticated requests carry a non-sensitive key label and the key's ID
+// in their context
 func APIKeyAuth(cfg config.AuthConfig) func(next http.Handler) http.Handler {
+	keys := apikey.NewStore(cfg.APIKeys, cfg.APIKeyHashes)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			apiKey := r.Header.Get("api_key")
@@ -18,21 +25,15 @@ func APIKeyAuth(cfg config.AuthConfig) func(next http.Handler) http.Handler {
 				return
 			}
 
-			// Validate API key
-			valid := false
-			for _, validKey := range cfg.APIKeys {
-				if apiKey == validKey {
-					valid = true
-					break
-				}
-			}
-
+			keyID, valid := keys.Lookup(apiKey)
 			if !valid {
 				http.Error(w, "Forbidden: Invalid API key", http.StatusForbidden)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := requestinfo.WithAPIKeyLabel(r.Context(), requestinfo.KeyLabel(apiKey))
+			ctx = requestinfo.WithAPIKeyID(ctx, keyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
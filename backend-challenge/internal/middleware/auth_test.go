@@ -6,8 +6,63 @@ import (
 	"testing"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// testKeyHash returns a bcrypt hash of key at the minimum cost, to keep tests fast
+func testKeyHash(t *testing.T, key string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	return string(hash)
+}
+
+func TestAPIKeyAuth_HashedKeys(t *testing.T) {
+	hash := testKeyHash(t, "hashedkey1")
+	cfg := config.AuthConfig{
+		APIKeys:      []string{"apitest"},
+		APIKeyHashes: map[string]string{"partner": hash},
+	}
+	var keyID string
+	authHandler := APIKeyAuth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID = requestinfo.APIKeyID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		expectedStatus int
+		expectedID     string
+	}{
+		{"key matching its hash", "hashedkey1", http.StatusOK, "partner"},
+		{"plaintext fallback", "apitest", http.StatusOK, "apitest"},
+		{"non-matching key", "hashedkey2", http.StatusForbidden, ""},
+		{"the hash itself", hash, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/order", nil)
+			req.Header.Set("api_key", tt.apiKey)
+			w := httptest.NewRecorder()
+
+			keyID = ""
+			authHandler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+			if keyID != tt.expectedID {
+				t.Errorf("key ID = %q, want %q", keyID, tt.expectedID)
+			}
+		})
+	}
+}
+
 func TestAPIKeyAuth(t *testing.T) {
 	cfg := config.AuthConfig{
 		APIKeys: []string{"apitest", "testkey123"},
@@ -71,3 +126,21 @@ func TestAPIKeyAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeyAuth_SetsKeyLabel(t *testing.T) {
+	var label string
+	handler := APIKeyAuth(config.AuthConfig{APIKeys: []string{"apitest"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		label = requestinfo.APIKeyLabel(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/order", nil)
+	req.Header.Set("api_key", "apitest")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if label == "" || label == "apitest" {
+		t.Errorf("key label = %q, want a non-empty label that is not the raw key", label)
+	}
+	if label != requestinfo.KeyLabel("apitest") {
+		t.Errorf("key label = %q, want a stable label %q", label, requestinfo.KeyLabel("apitest"))
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CaseInsensitiveRoutes middleware resolves requests whose static path segments
+// differ only in case from a registered route (e.g. /api/Product -> /api/product)
+//
+// Only the static segments of the matched route pattern are rewritten; URL
+// parameter values such as {productId} keep their original casing. Must be
+// registered on the same router passed in as routes.
+func CaseInsensitiveRoutes(routes chi.Routes) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.RouteContext(r.Context())
+			if rctx == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			path := rctx.RoutePath
+			if path == "" {
+				path = r.URL.Path
+			}
+
+			// Fast path: exact match needs no rewriting
+			if routes.Match(chi.NewRouteContext(), r.Method, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if rewritten, ok := foldStaticSegments(routes, r.Method, path); ok {
+				rctx.RoutePath = rewritten
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// foldStaticSegments matches the lowercased path against routes and rebuilds it
+// using the route pattern's static segments and the original parameter values
+func foldStaticSegments(routes chi.Routes, method, path string) (string, bool) {
+	tctx := chi.NewRouteContext()
+	if !routes.Match(tctx, method, strings.ToLower(path)) {
+		return "", false
+	}
+
+	patternSegments := strings.Split(tctx.RoutePattern(), "/")
+	pathSegments := strings.Split(path, "/")
+	if len(patternSegments) != len(pathSegments) {
+		return "", false
+	}
+
+	for i, segment := range patternSegments {
+		// Keep parameter values exactly as the client sent them
+		if strings.HasPrefix(segment, "{") || segment == "*" {
+			continue
+		}
+		pathSegments[i] = segment
+	}
+
+	return strings.Join(pathSegments, "/"), true
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// newPathTestRouter builds a router that echoes the matched pattern and parameter
+func newPathTestRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(chimiddleware.StripSlashes)
+	r.Use(CaseInsensitiveRoutes(r))
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("health"))
+	})
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/product", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("list"))
+		})
+		r.Get("/product/{productId}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("product:" + chi.URLParam(r, "productId")))
+		})
+		r.Get("/coupon/{couponCode}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("coupon:" + chi.URLParam(r, "couponCode")))
+		})
+	})
+
+	return r
+}
+
+func TestPathNormalization(t *testing.T) {
+	router := newPathTestRouter()
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"exact path", "/api/product", http.StatusOK, "list"},
+		{"trailing slash", "/api/product/", http.StatusOK, "list"},
+		{"mixed case", "/api/Product", http.StatusOK, "list"},
+		{"upper case with trailing slash", "/API/PRODUCT/", http.StatusOK, "list"},
+		{"mixed case top-level route", "/Health", http.StatusOK, "health"},
+		{"param with trailing slash", "/api/product/5/", http.StatusOK, "product:5"},
+		{"param casing preserved", "/Api/Coupon/HappyHrs", http.StatusOK, "coupon:HappyHrs"},
+		{"param matching static name preserved", "/API/COUPON/PRODUCT", http.StatusOK, "coupon:PRODUCT"},
+		{"unknown path", "/api/unknown", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
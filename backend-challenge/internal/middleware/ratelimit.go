@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+// rateLimitResponse is the 429 body, matching the {"error": ...} envelope
+type rateLimitResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retryAfter"`
+}
+
+// fixedWindowLimiter counts requests per client in fixed time windows
+// All clients share the window boundaries, so counts are reset together
+// and memory is bounded by the clients seen in one window
+type fixedWindowLimiter struct {
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	counts      map[string]int
+	mu          sync.Mutex
+}
+
+// allow records a request from client and reports whether it is within the
+// limit, along with the time left until the current window resets
+func (l *fixedWindowLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.counts = make(map[string]int)
+	}
+
+	l.counts[client]++
+	return l.counts[client] <= l.limit, l.windowStart.Add(l.window).Sub(now)
+}
+
+// RateLimit middleware limits each client IP to cfg.Requests per window
+// Throttled requests get 429 with a Retry-After header and a JSON body
+// A non-positive request limit disables rate limiting
+func RateLimit(cfg config.RateLimitConfig) func(next http.Handler) http.Handler {
+	if cfg.Requests <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limiter := &fixedWindowLimiter{
+		limit:  cfg.Requests,
+		window: time.Duration(cfg.WindowSeconds) * time.Second,
+		counts: make(map[string]int),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, reset := limiter.allow(clientIP(r), time.Now())
+			if allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter := max(1, int(math.Ceil(reset.Seconds())))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(rateLimitResponse{Error: "rate limited", RetryAfter: retryAfter})
+		})
+	}
+}
+
+// clientIP returns the client address without the port
+// RealIP middleware should run first so proxy headers are honoured
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+func TestRateLimit(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RateLimit(config.RateLimitConfig{Requests: 2, WindowSeconds: 60})(okHandler)
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/product", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+
+	// Third request from the same IP (any port) is throttled
+	w := send("10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", w.Code)
+	}
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("expected Retry-After between 1 and 60, got %q", w.Header().Get("Retry-After"))
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+
+	var resp rateLimitResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "rate limited" {
+		t.Errorf("expected error %q, got %q", "rate limited", resp.Error)
+	}
+	if resp.RetryAfter != retryAfter {
+		t.Errorf("body retryAfter = %d, header Retry-After = %d", resp.RetryAfter, retryAfter)
+	}
+
+	// Other clients have their own budget
+	if w := send("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected other client to get 200, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RateLimit(config.RateLimitConfig{})(okHandler)
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/product", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+		}
+	}
+}
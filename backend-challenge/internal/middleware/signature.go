@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+)
+
+// maxSignedBodyBytes bounds the body buffered for signature verification
+const maxSignedBodyBytes = 1 << 20
+
+// SignatureAuth middleware verifies the X-Signature header, the hex
+// HMAC-SHA256 of the raw body (optionally prefixed with "sha256="), for API
+// keys that have a signing secret. Keys without a secret pass through, so
+// signing is opt-in per integration. Secrets are looked up by the key ID
+// APIKeyAuth identified, so it must run first
+func SignatureAuth(cfg config.AuthConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, required := cfg.SigningSecrets[requestinfo.APIKeyID(r.Context())]
+			if !required {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			signature := strings.TrimPrefix(r.Header.Get("X-Signature"), "sha256=")
+			if signature == "" {
+				http.Error(w, "Unauthorized: request signature required", http.StatusUnauthorized)
+				return
+			}
+			given, err := hex.DecodeString(signature)
+			if err != nil {
+				http.Error(w, "Unauthorized: invalid request signature", http.StatusUnauthorized)
+				return
+			}
+
+			// Buffer the body so it can be verified and still decoded downstream
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+			if err != nil {
+				http.Error(w, "Bad Request: unreadable request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			if !hmac.Equal(given, mac.Sum(nil)) {
+				http.Error(w, "Unauthorized: invalid request signature", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignatureAuth(t *testing.T) {
+	cfg := config.AuthConfig{
+		APIKeys:        []string{"apitest", "partner"},
+		APIKeyHashes:   map[string]string{"hashed-partner": testKeyHash(t, "hashedkey1")},
+		SigningSecrets: map[string]string{"partner": "s3cret", "hashed-partner": "h4shed"},
+	}
+	body := `{"items":[{"productId":"1","quantity":1}]}`
+
+	// The handler must still be able to read the verified body
+	var received string
+	handler := APIKeyAuth(cfg)(SignatureAuth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		signature      string
+		body           string
+		expectedStatus int
+	}{
+		{"valid signature", "partner", sign("s3cret", body), body, http.StatusOK},
+		{"valid signature with prefix", "partner", "sha256=" + sign("s3cret", body), body, http.StatusOK},
+		{"tampered body", "partner", sign("s3cret", body), strings.Replace(body, `"quantity":1`, `"quantity":9`, 1), http.StatusUnauthorized},
+		{"wrong secret", "partner", sign("other", body), body, http.StatusUnauthorized},
+		{"missing signature when required", "partner", "", body, http.StatusUnauthorized},
+		{"malformed signature", "partner", "not-hex", body, http.StatusUnauthorized},
+		{"key without secret is not checked", "apitest", "", body, http.StatusOK},
+		{"hashed key signs with its ID's secret", "hashedkey1", sign("h4shed", body), body, http.StatusOK},
+		{"hashed key with another key's secret", "hashedkey1", sign("s3cret", body), body, http.StatusUnauthorized},
+		{"hashed key without signature", "hashedkey1", "", body, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/api/order", strings.NewReader(tt.body))
+			req.Header.Set("api_key", tt.apiKey)
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusOK && received != tt.body {
+				t.Errorf("handler read body %q, want %q", received, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// DefaultRequestTimeout applies to routes without a configured timeout when
+// no request timeout is set
+const DefaultRequestTimeout = 60 * time.Second
+
+// routeTimeout is a route pattern with the handler enforcing its timeout
+type routeTimeout struct {
+	pattern string
+	prefix  bool // pattern ended in /*, matching every path below it
+	handler http.Handler
+}
+
+// matches reports whether a lowercased request path falls under the pattern
+func (rt routeTimeout) matches(path string) bool {
+	if rt.prefix {
+		return path == strings.TrimSuffix(rt.pattern, "/") || strings.HasPrefix(path, rt.pattern)
+	}
+	return path == rt.pattern
+}
+
+// RouteTimeouts middleware bounds each request with the timeout configured
+// for its route in cfg.RouteTimeouts, falling back to cfg.RequestTimeout
+// When several patterns match the longest, most specific one wins. Requests
+// still running at the deadline get 504 as with chi's Timeout middleware
+func RouteTimeouts(cfg config.ServerConfig) func(next http.Handler) http.Handler {
+	fallback := time.Duration(cfg.RequestTimeout) * time.Second
+	if fallback <= 0 {
+		fallback = DefaultRequestTimeout
+	}
+
+	return func(next http.Handler) http.Handler {
+		routes := make([]routeTimeout, 0, len(cfg.RouteTimeouts))
+		for pattern, timeout := range cfg.RouteTimeouts {
+			pattern = strings.ToLower(pattern)
+			prefix := strings.HasSuffix(pattern, "/*")
+			routes = append(routes, routeTimeout{
+				pattern: strings.TrimSuffix(pattern, "*"),
+				prefix:  prefix,
+				handler: chimiddleware.Timeout(timeout)(next),
+			})
+		}
+		sort.Slice(routes, func(i, j int) bool {
+			return len(routes[i].pattern) > len(routes[j].pattern)
+		})
+		defaultHandler := chimiddleware.Timeout(fallback)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
+			path = strings.ToLower(path)
+
+			for _, route := range routes {
+				if route.matches(path) {
+					route.handler.ServeHTTP(w, r)
+					return
+				}
+			}
+			defaultHandler.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/config"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	// Reports how long the request context allows, as seen by the handler
+	var remaining time.Duration
+	handler := RouteTimeouts(config.ServerConfig{
+		RequestTimeout: 60,
+		RouteTimeouts: map[string]time.Duration{
+			"/api/coupon/*": 2 * time.Second,
+			"/api/order":    30 * time.Second,
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			t.Fatal("expected a request deadline")
+		}
+		remaining = time.Until(deadline)
+	}))
+
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/api/order", 30 * time.Second},
+		{"/API/Order", 30 * time.Second},
+		{"/api/coupon/HAPPYHRS/validate", 2 * time.Second},
+		{"/api/coupon", 2 * time.Second},
+		{"/api/orders", 60 * time.Second},
+		{"/api/product", 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if remaining > tt.want || remaining < tt.want-time.Second {
+				t.Errorf("remaining time = %v, want about %v", remaining, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteTimeouts_Expired(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	handler := RouteTimeouts(config.ServerConfig{
+		RouteTimeouts: map[string]time.Duration{"/api/coupon/*": 10 * time.Millisecond},
+	})(slow)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/coupon/HAPPYHRS/validate", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request ran for %v, expected the route timeout to cut it short", elapsed)
+	}
+}
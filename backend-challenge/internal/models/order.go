@@ -1,22 +1,72 @@
 package models
 
+import "encoding/xml"
+
 // OrderRequest represents an incoming order request
 // Schema matches OpenAPI specification
 type OrderRequest struct {
 	CouponCode string      `json:"couponCode,omitempty"`
 	Items      []OrderItem `json:"items"`
+
+	// StoreID selects the store whose catalog and prices the order uses;
+	// empty means the default store
+	StoreID string `json:"storeId,omitempty"`
+
+	// ExpectedTotal is the total the client was quoted; when set, the order is
+	// rejected if prices changed so the computed total differs by over a cent
+	ExpectedTotal *Price `json:"expectedTotal,omitempty"`
 }
 
 // OrderItem represents a single item in an order
 type OrderItem struct {
-	ProductID string `json:"productId"`
-	Quantity  int    `json:"quantity"`
+	ProductID string `json:"productId" xml:"productId"`
+	Quantity  int    `json:"quantity" xml:"quantity"`
 }
 
 // Order represents a confirmed order
-// Schema matches OpenAPI specification
+// Schema matches OpenAPI specification, extended with pricing and coupon details
 type Order struct {
-	ID       string      `json:"id"`
-	Items    []OrderItem `json:"items"`
-	Products []Product   `json:"products"`
+	XMLName  xml.Name    `json:"-" xml:"order"`
+	ID       string      `json:"id" xml:"id"`
+	Items    []OrderItem `json:"items" xml:"items>item"`
+	Products []Product   `json:"products" xml:"products>product"`
+	Discount Price       `json:"discount" xml:"discount"`
+	Total    Price       `json:"total" xml:"total"`
+
+	// StoreID is the store the order was priced in, empty for the default
+	StoreID string `json:"storeId,omitempty" xml:"storeId,omitempty"`
+
+	// Coupon details let clients confirm whether their coupon was applied
+	AppliedCoupon string `json:"appliedCoupon,omitempty" xml:"appliedCoupon,omitempty"`
+	CouponApplied bool   `json:"couponApplied" xml:"couponApplied"`
+	CouponNote    string `json:"couponNote,omitempty" xml:"couponNote,omitempty"`
+
+	// FreeProduct is the item a free-item coupon (e.g. BUYGETONE) made free
+	FreeProduct *Product `json:"freeProduct,omitempty" xml:"freeProduct>product,omitempty"`
+
+	// Status is "flagged" when the order needs review, e.g. an unusually
+	// large total; empty for orders that were accepted normally
+	Status string `json:"status,omitempty" xml:"status,omitempty"`
+}
+
+// CouponCheckRequest asks whether a coupon applies to a cart before ordering
+type CouponCheckRequest struct {
+	Coupon string       `json:"coupon"`
+	Cart   OrderRequest `json:"cart"`
+}
+
+// CouponCheck reports whether a coupon is valid and applies to a cart, and
+// the pricing the cart would get with it
+type CouponCheck struct {
+	Coupon     string `json:"coupon"`
+	Valid      bool   `json:"valid"`
+	Applicable bool   `json:"applicable"`
+
+	// Reason says why a coupon is not applicable, e.g. "below_minimum"
+	Reason string `json:"reason,omitempty"`
+
+	Subtotal    Price    `json:"subtotal"`
+	Discount    Price    `json:"discount"`
+	Total       Price    `json:"total"`
+	FreeProduct *Product `json:"freeProduct,omitempty"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"math"
+	"strconv"
+)
+
+// Price is a monetary amount in the store currency
+//
+// Prices are emitted in JSON as numbers, not strings, so existing clients
+// and the OpenAPI schema (type: number) keep working, but always with exactly
+// two decimals: arithmetic drift such as 0.30000000000000004 is rounded to
+// whole cents and 13 is written as 13.00
+type Price float64
+
+// MarshalJSON writes the price as a number with exactly two decimals
+func (p Price) MarshalJSON() ([]byte, error) {
+	cents := math.Round(float64(p) * 100)
+	return strconv.AppendFloat(nil, cents/100, 'f', 2, 64), nil
+}
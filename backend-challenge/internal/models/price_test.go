@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrice_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		price Price
+		want  string
+	}{
+		{"exact", 12.99, "12.99"},
+		{"whole amount", 13, "13.00"},
+		{"one decimal", 9.5, "9.50"},
+		{"zero", 0, "0.00"},
+		{"sum drift", Price(0.1 + 0.2), "0.30"},
+		{"multiplication drift", Price(1.1 * 3), "3.30"},
+		{"subtraction drift", Price(34.97 - 6.29), "28.68"},
+		{"sub-cent rounds", 10.005, "10.01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.price)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal(%v) = %s, want %s", float64(tt.price), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrice_RoundTrip(t *testing.T) {
+	data, err := json.Marshal(Product{ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var got Product
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Price != 12.99 {
+		t.Errorf("Price = %v, want 12.99", got.Price)
+	}
+}
@@ -1,10 +1,55 @@
 package models
 
+import (
+	"encoding/xml"
+	"time"
+)
+
 // Product represents a food product available for order
 // Schema matches OpenAPI specification
 type Product struct {
-	ID       int64   `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Category string  `json:"category"`
+	XMLName  xml.Name `json:"-" xml:"product"`
+	ID       int64    `json:"id" xml:"id"`
+	Name     string   `json:"name" xml:"name"`
+	Price    Price    `json:"price" xml:"price"`
+	Category string   `json:"category" xml:"category"`
+
+	// ImageURL is an absolute http(s) URL of the product image
+	ImageURL    string `json:"imageUrl,omitempty" xml:"imageUrl,omitempty"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+
+	// DeletedAt is set when the product is soft-deleted; deleted products are
+	// hidden from listings but kept so historical orders can still show them
+	DeletedAt *time.Time `json:"deletedAt,omitempty" xml:"deletedAt,omitempty"`
+}
+
+// IsDeleted reports whether the product has been soft-deleted
+func (p Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
+
+// ProductList is a list of products
+// It encodes as a plain array in JSON and as a <products> root element in XML
+type ProductList []Product
+
+// MarshalXML wraps the products in a single root element
+func (l ProductList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "products"}
+	return e.EncodeElement(struct {
+		Products []Product `xml:"product"`
+	}{Products: l}, start)
+}
+
+// Categories lists the allowed product categories in display order
+var Categories = []string{"Waffle", "Salad", "Pizza", "Burger"}
+
+// IsValidCategory reports whether category is one of the allowed categories
+// Matching is exact so typos such as "Wafle" or "waffle" are rejected
+func IsValidCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
 }
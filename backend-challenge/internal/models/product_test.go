@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestIsValidCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		want     bool
+	}{
+		{"Waffle", true},
+		{"Salad", true},
+		{"Pizza", true},
+		{"Burger", true},
+		{"Wafle", false},
+		{"waffle", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := IsValidCategory(tt.category); got != tt.want {
+				t.Errorf("IsValidCategory(%q) = %v, want %v", tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProduct_ImageAndDescriptionRoundTrip(t *testing.T) {
+	product := Product{
+		ID:          1,
+		Name:        "Chicken Waffle",
+		Price:       12.99,
+		Category:    "Waffle",
+		ImageURL:    "https://images.example.com/products/chicken-waffle.jpg",
+		Description: "Crispy fried chicken on a waffle",
+	}
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(product)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var got Product
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if got.ImageURL != product.ImageURL || got.Description != product.Description {
+			t.Errorf("round trip = %+v, want %+v", got, product)
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		data, err := xml.Marshal(product)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var got Product
+		if err := xml.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if got.ImageURL != product.ImageURL || got.Description != product.Description {
+			t.Errorf("round trip = %+v, want %+v", got, product)
+		}
+	})
+
+	t.Run("omitted when empty", func(t *testing.T) {
+		data, err := json.Marshal(Product{ID: 2, Name: "Belgian Waffle", Price: 10.99, Category: "Waffle"})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if s := string(data); strings.Contains(s, "imageUrl") || strings.Contains(s, "description") {
+			t.Errorf("expected empty fields to be omitted, got %s", s)
+		}
+	})
+}
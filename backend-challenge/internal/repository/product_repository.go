@@ -2,57 +2,206 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
 )
 
 var (
 	ErrProductNotFound = errors.New("product not found")
+	ErrProductExists   = errors.New("product already exists")
+
+	// ErrDuplicateProductID means seed data lists the same product ID twice
+	ErrDuplicateProductID = errors.New("duplicate product ID in seed data")
+
+	// ErrReloadNotSupported means the products were not loaded from a seed file
+	ErrReloadNotSupported = errors.New("products have no seed file to reload")
 )
 
 // ProductRepository defines the interface for product data access
 type ProductRepository interface {
 	GetAll(ctx context.Context) ([]models.Product, error)
+	Count(ctx context.Context) (int, error)
 	GetByID(ctx context.Context, id int64) (*models.Product, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Product, error)
+	Create(ctx context.Context, product models.Product) (*models.Product, error)
+	Delete(ctx context.Context, id int64) error
+	Iterate(ctx context.Context, fn func(models.Product) error) error
+	Reload(ctx context.Context) error
 }
 
 // InMemoryProductRepository implements ProductRepository with in-memory storage
 type InMemoryProductRepository struct {
 	mu       sync.RWMutex
 	products map[int64]models.Product
+	nextID   int64  // next auto-assigned ID, always above every stored ID
+	seedPath string // seed file Reload rereads, empty for built-in or reader seeds
 }
 
+// productImageBaseURL is where the seeded product images are served from
+const productImageBaseURL = "https://images.example.com/products/"
+
 // NewInMemoryProductRepository creates a new in-memory product repository with seed data
 func NewInMemoryProductRepository() *InMemoryProductRepository {
 	// Seed data based on OpenAPI spec examples
 	products := map[int64]models.Product{
-		1:  {ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
-		2:  {ID: 2, Name: "Belgian Waffle", Price: 10.99, Category: "Waffle"},
-		3:  {ID: 3, Name: "Chocolate Waffle", Price: 11.99, Category: "Waffle"},
-		4:  {ID: 4, Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
-		5:  {ID: 5, Name: "Greek Salad", Price: 9.49, Category: "Salad"},
-		6:  {ID: 6, Name: "Garden Salad", Price: 7.99, Category: "Salad"},
-		7:  {ID: 7, Name: "Margherita Pizza", Price: 14.99, Category: "Pizza"},
-		8:  {ID: 8, Name: "Pepperoni Pizza", Price: 16.99, Category: "Pizza"},
-		9:  {ID: 9, Name: "Veggie Pizza", Price: 15.49, Category: "Pizza"},
-		10: {ID: 10, Name: "Classic Burger", Price: 13.99, Category: "Burger"},
+		1: {
+			ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle",
+			ImageURL:    productImageBaseURL + "chicken-waffle.jpg",
+			Description: "Crispy fried chicken on a buttermilk waffle with maple syrup",
+		},
+		2: {
+			ID: 2, Name: "Belgian Waffle", Price: 10.99, Category: "Waffle",
+			ImageURL:    productImageBaseURL + "belgian-waffle.jpg",
+			Description: "Light, crisp Belgian waffle dusted with powdered sugar",
+		},
+		3: {
+			ID: 3, Name: "Chocolate Waffle", Price: 11.99, Category: "Waffle",
+			ImageURL:    productImageBaseURL + "chocolate-waffle.jpg",
+			Description: "Cocoa waffle with chocolate sauce and fresh strawberries",
+		},
+		4: {
+			ID: 4, Name: "Caesar Salad", Price: 8.99, Category: "Salad",
+			ImageURL:    productImageBaseURL + "caesar-salad.jpg",
+			Description: "Romaine, parmesan and croutons with Caesar dressing",
+		},
+		5: {
+			ID: 5, Name: "Greek Salad", Price: 9.49, Category: "Salad",
+			ImageURL:    productImageBaseURL + "greek-salad.jpg",
+			Description: "Tomato, cucumber, olives and feta with oregano dressing",
+		},
+		6: {
+			ID: 6, Name: "Garden Salad", Price: 7.99, Category: "Salad",
+			ImageURL:    productImageBaseURL + "garden-salad.jpg",
+			Description: "Mixed greens and seasonal vegetables with vinaigrette",
+		},
+		7: {
+			ID: 7, Name: "Margherita Pizza", Price: 14.99, Category: "Pizza",
+			ImageURL:    productImageBaseURL + "margherita-pizza.jpg",
+			Description: "Tomato, mozzarella and fresh basil",
+		},
+		8: {
+			ID: 8, Name: "Pepperoni Pizza", Price: 16.99, Category: "Pizza",
+			ImageURL:    productImageBaseURL + "pepperoni-pizza.jpg",
+			Description: "Tomato, mozzarella and spicy pepperoni",
+		},
+		9: {
+			ID: 9, Name: "Veggie Pizza", Price: 15.49, Category: "Pizza",
+			ImageURL:    productImageBaseURL + "veggie-pizza.jpg",
+			Description: "Tomato, mozzarella, peppers, mushrooms and onions",
+		},
+		10: {
+			ID: 10, Name: "Classic Burger", Price: 13.99, Category: "Burger",
+			ImageURL:    productImageBaseURL + "classic-burger.jpg",
+			Description: "Beef patty, cheddar, lettuce, tomato and pickles",
+		},
 	}
 
 	return &InMemoryProductRepository{
 		products: products,
+		nextID:   11,
+	}
+}
+
+// NewInMemoryProductRepositoryFromSeed creates a repository from a JSON array
+// of products. A duplicate ID is an error rather than silently keeping the
+// last entry, since losing a product is worse than failing at startup
+func NewInMemoryProductRepositoryFromSeed(r io.Reader) (*InMemoryProductRepository, error) {
+	products, nextID, err := decodeSeed(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InMemoryProductRepository{
+		products: products,
+		nextID:   nextID,
+	}, nil
+}
+
+// NewInMemoryProductRepositoryFromFile creates a repository from a JSON seed
+// file, which Reload rereads after it is edited
+func NewInMemoryProductRepositoryFromFile(path string) (*InMemoryProductRepository, error) {
+	products, nextID, err := readSeedFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InMemoryProductRepository{
+		products: products,
+		nextID:   nextID,
+		seedPath: path,
+	}, nil
+}
+
+// decodeSeed decodes a JSON array of products, returning them by ID and the
+// first ID above all of them
+func decodeSeed(r io.Reader) (map[int64]models.Product, int64, error) {
+	var seed []models.Product
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode product seed: %w", err)
+	}
+
+	products := make(map[int64]models.Product, len(seed))
+	var maxID int64
+	for _, product := range seed {
+		if existing, ok := products[product.ID]; ok {
+			return nil, 0, fmt.Errorf("%w: %d (%q and %q)", ErrDuplicateProductID, product.ID, existing.Name, product.Name)
+		}
+		products[product.ID] = product
+		maxID = max(maxID, product.ID)
 	}
+	return products, maxID + 1, nil
 }
 
-// GetAll returns all products sorted by ID for consistent ordering
+// readSeedFile decodes the products in a JSON seed file
+func readSeedFile(path string) (map[int64]models.Product, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open product seed: %w", err)
+	}
+	defer f.Close()
+	return decodeSeed(f)
+}
+
+// Reload rereads the seed file and replaces the whole product set with it
+// in one step, so readers see either the old catalog or the new one
+// Products created or deleted at runtime are discarded. A seed that fails
+// to load leaves the current catalog in place
+func (r *InMemoryProductRepository) Reload(ctx context.Context) error {
+	if r.seedPath == "" {
+		return ErrReloadNotSupported
+	}
+
+	products, nextID, err := readSeedFile(r.seedPath)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products = products
+	r.nextID = nextID
+	return nil
+}
+
+// GetAll returns all products that are not deleted, sorted by ID for consistent ordering
 func (r *InMemoryProductRepository) GetAll(ctx context.Context) ([]models.Product, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	products := make([]models.Product, 0, len(r.products))
 	for _, product := range r.products {
+		if product.IsDeleted() {
+			continue
+		}
 		products = append(products, product)
 	}
 
@@ -64,7 +213,22 @@ func (r *InMemoryProductRepository) GetAll(ctx context.Context) ([]models.Produc
 	return products, nil
 }
 
-// GetByID returns a product by its ID
+// Count returns how many products are not deleted, the length GetAll would return
+func (r *InMemoryProductRepository) Count(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, product := range r.products {
+		if !product.IsDeleted() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetByID returns a product by its ID, including soft-deleted products
+// so historical orders can still be displayed; callers check IsDeleted
 func (r *InMemoryProductRepository) GetByID(ctx context.Context, id int64) (*models.Product, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -75,3 +239,93 @@ func (r *InMemoryProductRepository) GetByID(ctx context.Context, id int64) (*mod
 	}
 	return &product, nil
 }
+
+// GetByIDs returns the products matching the given IDs in request order
+// Unknown or deleted IDs are omitted and duplicate IDs are returned once
+func (r *InMemoryProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]models.Product, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if product, exists := r.products[id]; exists && !product.IsDeleted() {
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
+// Create stores a new product
+// A zero ID is auto-assigned; an ID that is already taken returns ErrProductExists
+func (r *InMemoryProductRepository) Create(ctx context.Context, product models.Product) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if product.ID == 0 {
+		product.ID = r.nextID
+	} else if _, exists := r.products[product.ID]; exists {
+		return nil, ErrProductExists
+	}
+
+	r.products[product.ID] = product
+	if product.ID >= r.nextID {
+		r.nextID = product.ID + 1
+	}
+	return &product, nil
+}
+
+// Delete soft-deletes a product by setting DeletedAt
+// The record is kept, so its ID stays taken and past orders can still show it
+func (r *InMemoryProductRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, exists := r.products[id]
+	if !exists || product.IsDeleted() {
+		return ErrProductNotFound
+	}
+
+	deletedAt := time.Now().UTC()
+	product.DeletedAt = &deletedAt
+	r.products[id] = product
+	return nil
+}
+
+// Iterate calls fn for each product that is not deleted, in ID order
+// Only the sorted IDs are snapshotted, and the lock is not held while fn
+// runs, so callers can stream results without copying the whole catalog
+// Iteration stops at the first error returned by fn, which is returned
+func (r *InMemoryProductRepository) Iterate(ctx context.Context, fn func(models.Product) error) error {
+	r.mu.RLock()
+	ids := make([]int64, 0, len(r.products))
+	for id := range r.products {
+		ids = append(ids, id)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.mu.RLock()
+		product, exists := r.products[id]
+		r.mu.RUnlock()
+
+		if !exists || product.IsDeleted() {
+			continue
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+func TestInMemoryProductRepository_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryProductRepository()
+
+	if _, err := repo.Create(ctx, models.Product{ID: 1, Name: "Duplicate"}); !errors.Is(err, ErrProductExists) {
+		t.Errorf("Create() with existing ID error = %v, want ErrProductExists", err)
+	}
+
+	created, err := repo.Create(ctx, models.Product{ID: 20, Name: "Explicit"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != 20 {
+		t.Errorf("expected ID 20, got %d", created.ID)
+	}
+
+	// Auto-assigned IDs continue after the highest stored ID
+	created, err = repo.Create(ctx, models.Product{Name: "Auto"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != 21 {
+		t.Errorf("expected auto-assigned ID 21, got %d", created.ID)
+	}
+}
+
+func TestInMemoryProductRepository_Create_ConcurrentAutoIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryProductRepository()
+
+	const numGoroutines = 100
+	ids := make(chan int64, numGoroutines)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, err := repo.Create(ctx, models.Product{Name: "Concurrent"})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+				return
+			}
+			ids <- created.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	// Every create gets a distinct ID in the range directly after the seed data
+	seen := make(map[int64]bool, numGoroutines)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("ID %d assigned twice", id)
+		}
+		if id < 11 || id > 10+numGoroutines {
+			t.Errorf("ID %d outside expected range", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != numGoroutines {
+		t.Errorf("expected %d distinct IDs, got %d", numGoroutines, len(seen))
+	}
+
+	all, _ := repo.GetAll(ctx)
+	if len(all) != 10+numGoroutines {
+		t.Errorf("expected %d products, got %d", 10+numGoroutines, len(all))
+	}
+}
+
+func TestInMemoryProductRepository_Delete(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryProductRepository()
+
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, 2); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrProductNotFound", err)
+	}
+	if err := repo.Delete(ctx, 999); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Delete() of unknown ID error = %v, want ErrProductNotFound", err)
+	}
+
+	// Listing and batch fetches exclude the deleted product
+	all, _ := repo.GetAll(ctx)
+	if len(all) != 9 {
+		t.Errorf("expected 9 products after delete, got %d", len(all))
+	}
+	for _, p := range all {
+		if p.ID == 2 {
+			t.Error("deleted product returned by GetAll")
+		}
+	}
+	if count, _ := repo.Count(ctx); count != 9 {
+		t.Errorf("expected Count() of 9 after delete, got %d", count)
+	}
+	if batch, _ := repo.GetByIDs(ctx, []int64{1, 2}); len(batch) != 1 || batch[0].ID != 1 {
+		t.Errorf("expected GetByIDs to omit deleted product, got %+v", batch)
+	}
+
+	// The record is kept for historical lookups
+	product, err := repo.GetByID(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetByID() of deleted product error = %v", err)
+	}
+	if !product.IsDeleted() || product.Name != "Belgian Waffle" {
+		t.Errorf("expected deleted Belgian Waffle, got %+v", product)
+	}
+
+	// Its ID stays taken
+	if _, err := repo.Create(ctx, models.Product{ID: 2, Name: "Reuse"}); !errors.Is(err, ErrProductExists) {
+		t.Errorf("Create() reusing deleted ID error = %v, want ErrProductExists", err)
+	}
+}
+
+func TestNewInMemoryProductRepositoryFromSeed(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      string
+		wantErr   error
+		wantCount int
+	}{
+		{
+			name:      "unique IDs",
+			seed:      `[{"id":1,"name":"Chicken Waffle","price":12.99,"category":"Waffle"},{"id":5,"name":"Greek Salad","price":9.49,"category":"Salad"}]`,
+			wantCount: 2,
+		},
+		{
+			name:    "duplicate ID",
+			seed:    `[{"id":1,"name":"Chicken Waffle","price":12.99,"category":"Waffle"},{"id":1,"name":"Belgian Waffle","price":10.99,"category":"Waffle"}]`,
+			wantErr: ErrDuplicateProductID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := NewInMemoryProductRepositoryFromSeed(strings.NewReader(tt.seed))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewInMemoryProductRepositoryFromSeed() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), ": 1 (") {
+					t.Errorf("error %q does not identify the conflicting ID", err)
+				}
+				return
+			}
+
+			products, _ := repo.GetAll(context.Background())
+			if len(products) != tt.wantCount {
+				t.Errorf("GetAll() returned %d products, want %d", len(products), tt.wantCount)
+			}
+
+			// Auto-assigned IDs continue after the highest seeded ID
+			created, err := repo.Create(context.Background(), models.Product{Name: "New"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if created.ID != 6 {
+				t.Errorf("created ID = %d, want 6", created.ID)
+			}
+		})
+	}
+}
+
+func TestNewInMemoryProductRepositoryFromSeed_InvalidJSON(t *testing.T) {
+	if _, err := NewInMemoryProductRepositoryFromSeed(strings.NewReader(`{`)); err == nil {
+		t.Error("expected error for malformed seed, got nil")
+	}
+}
+
+func TestInMemoryProductRepository_Reload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json")
+	writeSeed := func(seed string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(seed), 0644); err != nil {
+			t.Fatalf("failed to write seed file: %v", err)
+		}
+	}
+
+	writeSeed(`[{"id":1,"name":"Chicken Waffle","price":12.99,"category":"Waffle"}]`)
+	repo, err := NewInMemoryProductRepositoryFromFile(path)
+	if err != nil {
+		t.Fatalf("NewInMemoryProductRepositoryFromFile() error = %v", err)
+	}
+
+	writeSeed(`[{"id":1,"name":"Chicken Waffle","price":13.49,"category":"Waffle"},{"id":7,"name":"Margherita Pizza","price":14.99,"category":"Pizza"}]`)
+	if err := repo.Reload(ctx); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	products, _ := repo.GetAll(ctx)
+	if len(products) != 2 || products[0].Price != 13.49 || products[1].ID != 7 {
+		t.Errorf("GetAll() after reload = %+v, want the edited seed", products)
+	}
+	if created, err := repo.Create(ctx, models.Product{Name: "New"}); err != nil || created.ID != 8 {
+		t.Errorf("Create() after reload = %+v, %v, want ID 8", created, err)
+	}
+
+	// A broken seed leaves the current catalog serving
+	writeSeed(`[{"id":1`)
+	if err := repo.Reload(ctx); err == nil {
+		t.Fatal("expected error reloading a malformed seed, got nil")
+	}
+	if products, _ := repo.GetAll(ctx); len(products) != 3 {
+		t.Errorf("GetAll() after failed reload returned %d products, want 3", len(products))
+	}
+}
+
+func TestInMemoryProductRepository_ReloadNotSupported(t *testing.T) {
+	repo := NewInMemoryProductRepository()
+	if err := repo.Reload(context.Background()); !errors.Is(err, ErrReloadNotSupported) {
+		t.Errorf("Reload() error = %v, want ErrReloadNotSupported", err)
+	}
+}
+
+func TestStoreCatalog(t *testing.T) {
+	ctx := context.Background()
+	catalog, err := NewSeededStoreCatalog(ctx, NewInMemoryProductRepository())
+	if err != nil {
+		t.Fatalf("NewSeededStoreCatalog() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		store     string
+		wantErr   error
+		wantPrice models.Price
+	}{
+		{"empty ID is the default store", "", nil, 12.99},
+		{"default store", DefaultStoreID, nil, 12.99},
+		{"airport store has its own prices", AirportStoreID, nil, 16.24},
+		{"unknown store", "moon", ErrStoreNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := catalog.Store(tt.store)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Store(%q) error = %v, want %v", tt.store, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			product, err := repo.GetByID(ctx, 1)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if product.Price != tt.wantPrice {
+				t.Errorf("product 1 price = %v, want %v", product.Price, tt.wantPrice)
+			}
+		})
+	}
+
+	// Stores are separate catalogs: a change to one doesn't show in another
+	airport, _ := catalog.Store(AirportStoreID)
+	if err := airport.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	defaultStore, _ := catalog.Store(DefaultStoreID)
+	if product, err := defaultStore.GetByID(ctx, 2); err != nil || product.IsDeleted() {
+		t.Errorf("deleting from the airport store affected the default store")
+	}
+}
+
+func TestStoreCatalog_AirportFollowsDefaultCatalog(t *testing.T) {
+	ctx := context.Background()
+	seeded, err := NewInMemoryProductRepositoryFromSeed(strings.NewReader(
+		`[{"id":3,"name":"Espresso","price":4.00,"category":"Coffee"},{"id":7,"name":"Bagel","price":2.50,"category":"Bakery"}]`))
+	if err != nil {
+		t.Fatalf("NewInMemoryProductRepositoryFromSeed() error = %v", err)
+	}
+	if err := seeded.Delete(ctx, 7); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	catalog, err := NewSeededStoreCatalog(ctx, seeded)
+	if err != nil {
+		t.Fatalf("NewSeededStoreCatalog() error = %v", err)
+	}
+	airport, _ := catalog.Store(AirportStoreID)
+
+	products, err := airport.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(products) != 1 || products[0].ID != 3 || products[0].Price != 5 {
+		t.Errorf("airport products = %+v, want only product 3 at 5", products)
+	}
+
+	// New airport products are numbered after the seeded ones
+	created, err := airport.Create(ctx, models.Product{Name: "Muffin", Price: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != 4 {
+		t.Errorf("created ID = %d, want 4", created.ID)
+	}
+}
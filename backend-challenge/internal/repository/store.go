@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+// DefaultStoreID is the store used when a request names none, so clients
+// that predate stores keep getting the original catalog
+const DefaultStoreID = "default"
+
+// AirportStoreID is the seeded second store, selling the default store's
+// products at airport prices
+const AirportStoreID = "airport"
+
+// airportMarkup is how much more the airport store charges than the default
+const airportMarkup = 1.25
+
+// ErrStoreNotFound means no catalog is registered for a store ID
+var ErrStoreNotFound = errors.New("store not found")
+
+// StoreCatalog holds one product repository per store, so products are
+// looked up by (store, product ID) and each store has its own prices
+// Stores are registered at startup and not changed while serving
+type StoreCatalog struct {
+	stores map[string]ProductRepository
+}
+
+// NewStoreCatalog creates a catalog whose default store is defaultStore
+func NewStoreCatalog(defaultStore ProductRepository) *StoreCatalog {
+	return &StoreCatalog{
+		stores: map[string]ProductRepository{DefaultStoreID: defaultStore},
+	}
+}
+
+// NewSeededStoreCatalog creates a catalog with defaultStore and the airport
+// store seeded from it
+func NewSeededStoreCatalog(ctx context.Context, defaultStore ProductRepository) (*StoreCatalog, error) {
+	airport, err := newAirportProductRepository(ctx, defaultStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed airport store: %w", err)
+	}
+
+	catalog := NewStoreCatalog(defaultStore)
+	catalog.AddStore(AirportStoreID, airport)
+	return catalog, nil
+}
+
+// AddStore registers the product repository of a store, replacing any
+// previous one; it must not be called while the catalog is in use
+func (c *StoreCatalog) AddStore(id string, repo ProductRepository) {
+	c.stores[id] = repo
+}
+
+// Store returns the product repository of a store; an empty ID is the
+// default store
+func (c *StoreCatalog) Store(id string) (ProductRepository, error) {
+	if id == "" {
+		id = DefaultStoreID
+	}
+	repo, ok := c.stores[id]
+	if !ok {
+		return nil, ErrStoreNotFound
+	}
+	return repo, nil
+}
+
+// newAirportProductRepository creates the airport store: a copy of the
+// products base has now, marked up by airportMarkup
+// Later changes to base, reloads included, don't reach the copy
+func newAirportProductRepository(ctx context.Context, base ProductRepository) (*InMemoryProductRepository, error) {
+	repo := &InMemoryProductRepository{
+		products: make(map[int64]models.Product),
+		nextID:   1,
+	}
+	err := base.Iterate(ctx, func(product models.Product) error {
+		product.Price = models.Price(math.Round(float64(product.Price)*airportMarkup*100) / 100)
+		repo.products[product.ID] = product
+		repo.nextID = max(repo.nextID, product.ID+1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
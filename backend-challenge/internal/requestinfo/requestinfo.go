@@ -0,0 +1,61 @@
+package requestinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+type apiKeyLabelKey struct{}
+
+// KeyLabel returns a non-sensitive identifier for an API key: a short hash
+// prefix that is stable per key but can't be used to authenticate
+func KeyLabel(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key-" + hex.EncodeToString(sum[:4])
+}
+
+// WithAPIKeyLabel returns a context carrying the caller's API key label
+func WithAPIKeyLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, apiKeyLabelKey{}, label)
+}
+
+// APIKeyLabel returns the caller's API key label, or "" if unauthenticated
+func APIKeyLabel(ctx context.Context) string {
+	label, _ := ctx.Value(apiKeyLabelKey{}).(string)
+	return label
+}
+
+type apiKeyIDKey struct{}
+
+// WithAPIKeyID returns a context carrying the ID of the caller's API key
+func WithAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey{}, id)
+}
+
+// APIKeyID returns the ID of the caller's API key, or "" if unauthenticated
+// A plaintext key's ID is the key itself, so IDs must not be logged
+func APIKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyIDKey{}).(string)
+	return id
+}
+
+// RequestID returns the request ID assigned by the RequestID middleware
+func RequestID(ctx context.Context) string {
+	return chimiddleware.GetReqID(ctx)
+}
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a context carrying the request's Idempotency-Key
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKey returns the request's Idempotency-Key, or "" if it sent none
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
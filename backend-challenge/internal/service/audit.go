@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+// OrderAuditRecord is the compliance record emitted for every created order
+type OrderAuditRecord struct {
+	OrderID       string
+	Items         []models.OrderItem
+	Discount      models.Price
+	Total         models.Price
+	AppliedCoupon string
+	CouponApplied bool
+	Status        string // "flagged" for orders held for review
+	APIKey        string // non-sensitive key label, never the raw key
+	RequestID     string
+	Timestamp     time.Time
+}
+
+// OrderAuditor receives an audit record for every created order
+type OrderAuditor interface {
+	AuditOrder(ctx context.Context, record OrderAuditRecord)
+}
+
+// LogAuditor writes order audit records to a dedicated structured logger
+type LogAuditor struct {
+	log *slog.Logger
+}
+
+// NewLogAuditor creates an auditor writing to log, which should be kept
+// separate from the access logs
+func NewLogAuditor(log *slog.Logger) *LogAuditor {
+	return &LogAuditor{log: log}
+}
+
+// AuditOrder implements OrderAuditor
+func (a *LogAuditor) AuditOrder(ctx context.Context, record OrderAuditRecord) {
+	a.log.LogAttrs(ctx, slog.LevelInfo, "order created",
+		slog.String("order_id", record.OrderID),
+		slog.Any("items", record.Items),
+		slog.Float64("discount", float64(record.Discount)),
+		slog.Float64("total", float64(record.Total)),
+		slog.String("applied_coupon", record.AppliedCoupon),
+		slog.Bool("coupon_applied", record.CouponApplied),
+		slog.String("status", record.Status),
+		slog.String("api_key", record.APIKey),
+		slog.String("request_id", record.RequestID),
+		slog.Time("timestamp", record.Timestamp),
+	)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// recordingAuditor captures audit records
+type recordingAuditor struct {
+	records []OrderAuditRecord
+}
+
+func (a *recordingAuditor) AuditOrder(ctx context.Context, record OrderAuditRecord) {
+	a.records = append(a.records, record)
+}
+
+func TestOrderService_CreateOrder_Audit(t *testing.T) {
+	auditor := &recordingAuditor{}
+	orderService := NewOrderService(repository.NewInMemoryProductRepository(), stubCouponValidator{CouponHappyHours: true})
+	orderService.SetAuditor(auditor)
+
+	ctx := requestinfo.WithAPIKeyLabel(context.Background(), requestinfo.KeyLabel("apitest"))
+	ctx = context.WithValue(ctx, chimiddleware.RequestIDKey, "req-123")
+
+	before := time.Now().UTC()
+	order, err := orderService.CreateOrder(ctx, models.OrderRequest{
+		CouponCode: CouponHappyHours,
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 2}, {ProductID: "4", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+
+	// Failed orders are not audited
+	if _, err := orderService.CreateOrder(ctx, models.OrderRequest{}); err == nil {
+		t.Fatal("expected empty order to fail")
+	}
+
+	if len(auditor.records) != 1 {
+		t.Fatalf("audit records = %d, want 1", len(auditor.records))
+	}
+	rec := auditor.records[0]
+	if rec.OrderID != order.ID || len(rec.Items) != 2 {
+		t.Errorf("record order = %q with %d items, want %q with 2", rec.OrderID, len(rec.Items), order.ID)
+	}
+	if rec.Total != 28.68 || rec.Discount != 6.29 {
+		t.Errorf("record total, discount = %v, %v; want 28.68, 6.29", rec.Total, rec.Discount)
+	}
+	if !rec.CouponApplied || rec.AppliedCoupon != CouponHappyHours {
+		t.Errorf("record coupon = %q applied %v, want %q applied", rec.AppliedCoupon, rec.CouponApplied, CouponHappyHours)
+	}
+	if rec.APIKey != requestinfo.KeyLabel("apitest") || rec.APIKey == "apitest" {
+		t.Errorf("record API key = %q, want the label of the key, not the key", rec.APIKey)
+	}
+	if rec.RequestID != "req-123" {
+		t.Errorf("record request ID = %q, want req-123", rec.RequestID)
+	}
+	if rec.Timestamp.Before(before) {
+		t.Errorf("record timestamp %v is before the request", rec.Timestamp)
+	}
+}
+
+func TestLogAuditor(t *testing.T) {
+	var buf bytes.Buffer
+	auditor := NewLogAuditor(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	auditor.AuditOrder(context.Background(), OrderAuditRecord{
+		OrderID:   "order-1",
+		Items:     []models.OrderItem{{ProductID: "1", Quantity: 2}},
+		Total:     25.98,
+		APIKey:    "key-abcd1234",
+		RequestID: "req-1",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("audit output is not JSON: %v (%s)", err, buf.String())
+	}
+	want := map[string]interface{}{
+		"msg":        "order created",
+		"order_id":   "order-1",
+		"total":      25.98,
+		"api_key":    "key-abcd1234",
+		"request_id": "req-1",
+		"timestamp":  "2024-01-02T03:04:05Z",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+// Known discount coupon codes
+const (
+	CouponHappyHours = "HAPPYHOURS" // 18% off the order total
+	CouponBuyGetOne  = "BUYGETONE"  // Lowest priced item for free
+
+	happyHoursRate = 0.18
+)
+
+// noDiscountNote is reported when a valid coupon matches no discount rule
+const noDiscountNote = "coupon is valid but has no matching discount"
+
+// PricingContext is the order state a discount rule is evaluated against
+type PricingContext struct {
+	Items    []models.OrderItem
+	Products map[int64]models.Product
+
+	// Subtotal is the running subtotal after all previously applied rules
+	Subtotal float64
+
+	// Flags and RolloutKey decide which FlagConditions hold for the order
+	Flags      FeatureFlags
+	RolloutKey string
+}
+
+// DiscountRule calculates the discount a rule grants for an order
+type DiscountRule interface {
+	Discount(pc PricingContext) float64
+}
+
+// PercentageDiscount takes a percentage off the running subtotal
+type PercentageDiscount struct {
+	Rate float64 // e.g. 0.18 for 18% off
+}
+
+// Discount implements DiscountRule
+func (d PercentageDiscount) Discount(pc PricingContext) float64 {
+	return pc.Subtotal * d.Rate
+}
+
+// FreeItemDiscount makes the lowest priced item across the whole order free
+type FreeItemDiscount struct{}
+
+// Discount implements DiscountRule
+func (d FreeItemDiscount) Discount(pc PricingContext) float64 {
+	product, ok := d.FreeProduct(pc)
+	if !ok {
+		return 0
+	}
+	return float64(product.Price)
+}
+
+// FreeProduct returns the product made free: the lowest priced one, with
+// ties going to the lowest product ID so the choice is deterministic
+// Lines whose product is missing from pc.Products are skipped rather than
+// treated as a zero-priced product that would zero the discount
+func (d FreeItemDiscount) FreeProduct(pc PricingContext) (models.Product, bool) {
+	var chosen models.Product
+	found := false
+	for _, item := range pc.Items {
+		productID, err := strconv.ParseInt(item.ProductID, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		product, ok := pc.Products[productID]
+		if !ok {
+			continue
+		}
+		if !found || product.Price < chosen.Price || (product.Price == chosen.Price && product.ID < chosen.ID) {
+			chosen = product
+			found = true
+		}
+	}
+	return chosen, found
+}
+
+// LineDiscountRule takes a percentage off every order line whose product
+// matches the rule, e.g. 20% off all pizzas. A line matches when its product
+// is in Category or listed in ProductIDs
+type LineDiscountRule struct {
+	Category   string
+	ProductIDs []int64
+	Rate       float64 // e.g. 0.2 for 20% off matching lines
+}
+
+// Discount implements DiscountRule
+func (d LineDiscountRule) Discount(pc PricingContext) float64 {
+	var discount float64
+	for _, item := range pc.Items {
+		productID, err := strconv.ParseInt(item.ProductID, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		product, ok := pc.Products[productID]
+		if !ok || !d.matches(product) {
+			continue
+		}
+		discount += float64(product.Price) * float64(item.Quantity) * d.Rate
+	}
+	return discount
+}
+
+// matches reports whether a product is covered by the rule
+func (d LineDiscountRule) matches(product models.Product) bool {
+	if d.Category != "" && product.Category == d.Category {
+		return true
+	}
+	return slices.Contains(d.ProductIDs, product.ID)
+}
+
+// DiscountCondition is implemented by rules that gate their whole stack: a
+// coupon whose condition isn't met grants no discount at all
+type DiscountCondition interface {
+	Met(pc PricingContext) bool
+}
+
+// MinSubtotalCondition requires the order subtotal, before any discount, to
+// be at least Amount; it grants no discount itself
+type MinSubtotalCondition struct {
+	Amount float64
+}
+
+// Discount implements DiscountRule
+func (c MinSubtotalCondition) Discount(pc PricingContext) float64 {
+	return 0
+}
+
+// Met implements DiscountCondition
+func (c MinSubtotalCondition) Met(pc PricingContext) bool {
+	return pc.Subtotal >= c.Amount
+}
+
+// FlagCondition limits a rule stack to the orders a feature flag is enabled
+// for, to roll a new discount out to a percentage of orders; it grants no
+// discount itself
+type FlagCondition struct {
+	Flag string
+}
+
+// Discount implements DiscountRule
+func (c FlagCondition) Discount(pc PricingContext) float64 {
+	return 0
+}
+
+// Met implements DiscountCondition
+func (c FlagCondition) Met(pc PricingContext) bool {
+	return pc.Flags.Enabled(c.Flag, pc.RolloutKey)
+}
+
+// DiscountRules maps coupon codes to the ordered rules they apply
+// Within a stack, item-level rules should precede percentage rules so the
+// percentage is taken from the price after free items are removed
+type DiscountRules map[string][]DiscountRule
+
+// defaultDiscountRules are the built-in discounts used when none are configured
+var defaultDiscountRules = DiscountRules{
+	CouponHappyHours: {PercentageDiscount{Rate: happyHoursRate}},
+	CouponBuyGetOne:  {FreeItemDiscount{}},
+}
+
+// DefaultDiscountRules returns a copy of the built-in discounts
+func DefaultDiscountRules() DiscountRules {
+	rules := make(DiscountRules, len(defaultDiscountRules))
+	for code, stack := range defaultDiscountRules {
+		rules[code] = slices.Clone(stack)
+	}
+	return rules
+}
+
+// calculateDiscount returns the discount for a coupon code and whether the code
+// matched a known discount rule
+func (d DiscountRules) calculateDiscount(code string, pc PricingContext) (float64, bool) {
+	rules, exists := d[code]
+	if !exists {
+		return 0, false
+	}
+
+	return applyDiscounts(rules, pc), true
+}
+
+// freeProductFor returns the product a coupon makes free, if its rules
+// include a FreeItemDiscount, so the response can show which item it was
+func (d DiscountRules) freeProductFor(code string, items []models.OrderItem, productMap map[int64]models.Product) *models.Product {
+	for _, rule := range d[code] {
+		if free, ok := rule.(FreeItemDiscount); ok {
+			product, found := free.FreeProduct(PricingContext{Items: items, Products: productMap})
+			if found {
+				return &product
+			}
+		}
+	}
+	return nil
+}
+
+// conditionsMet reports whether every condition in rules holds for the order
+// Conditions see the subtotal before any discount, wherever they sit in the stack
+func conditionsMet(rules []DiscountRule, pc PricingContext) bool {
+	for _, rule := range rules {
+		if cond, ok := rule.(DiscountCondition); ok && !cond.Met(pc) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyDiscounts runs rules in order, each seeing the subtotal left after the
+// previous rules, and returns the combined discount, or 0 when a condition
+// isn't met. Each step is rounded to cents and capped so the subtotal never
+// goes negative
+func applyDiscounts(rules []DiscountRule, pc PricingContext) float64 {
+	if !conditionsMet(rules, pc) {
+		return 0
+	}
+
+	var total float64
+	for _, rule := range rules {
+		discount := math.Min(roundPrice(rule.Discount(pc)), pc.Subtotal)
+		if discount <= 0 {
+			continue
+		}
+
+		total += discount
+		pc.Subtotal = roundPrice(pc.Subtotal - discount)
+	}
+	return roundPrice(total)
+}
+
+// roundPrice rounds a price to whole cents
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+// Discount rule types accepted in discount definitions
+const (
+	ruleTypePercentage = "percentage"
+	ruleTypeFreeItem   = "free_item"
+	ruleTypeLine       = "line"
+	ruleTypeMinimum    = "minimum"
+	ruleTypeFlag       = "flag"
+)
+
+// DiscountDefinition describes one configured discount rule, e.g.
+// {"type":"percentage","rate":0.18} or {"type":"line","category":"Pizza","rate":0.2}
+// A {"type":"minimum","minSubtotal":20} entry makes the coupon's stack apply
+// only to orders of at least that subtotal, and a {"type":"flag","flag":"x"}
+// entry only to the orders feature flag x is enabled for
+type DiscountDefinition struct {
+	Type        string  `json:"type"`
+	Rate        float64 `json:"rate,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	ProductIDs  []int64 `json:"productIds,omitempty"`
+	MinSubtotal float64 `json:"minSubtotal,omitempty"`
+	Flag        string  `json:"flag,omitempty"`
+}
+
+// ParseDiscountRules parses a JSON object mapping coupon codes to their rule
+// stacks, e.g. {"HAPPYHOURS":[{"type":"percentage","rate":0.18}]}
+// Every definition is validated so a typo fails startup instead of silently
+// granting no discount. Empty input yields the built-in discounts
+func ParseDiscountRules(data string) (DiscountRules, error) {
+	if data == "" {
+		return DefaultDiscountRules(), nil
+	}
+
+	var defs map[string][]DiscountDefinition
+	if err := json.Unmarshal([]byte(data), &defs); err != nil {
+		return nil, fmt.Errorf("invalid discount definitions: %w", err)
+	}
+
+	rules := make(DiscountRules, len(defs))
+	for code, stack := range defs {
+		normalized := coupon.CanonicalizeCode(code)
+		if normalized == "" {
+			return nil, fmt.Errorf("discount definition has an empty coupon code")
+		}
+		if _, dup := rules[normalized]; dup {
+			return nil, fmt.Errorf("coupon %s is defined more than once", normalized)
+		}
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("coupon %s has no discount rules", normalized)
+		}
+
+		for i, def := range stack {
+			rule, err := def.rule()
+			if err != nil {
+				return nil, fmt.Errorf("coupon %s rule %d: %w", normalized, i+1, err)
+			}
+			rules[normalized] = append(rules[normalized], rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// rule validates the definition and builds the rule it describes
+func (d DiscountDefinition) rule() (DiscountRule, error) {
+	switch d.Type {
+	case ruleTypePercentage:
+		if err := validateRate(d.Rate); err != nil {
+			return nil, err
+		}
+		return PercentageDiscount{Rate: d.Rate}, nil
+	case ruleTypeFreeItem:
+		return FreeItemDiscount{}, nil
+	case ruleTypeLine:
+		if err := validateRate(d.Rate); err != nil {
+			return nil, err
+		}
+		if d.Category == "" && len(d.ProductIDs) == 0 {
+			return nil, fmt.Errorf("line discount needs a category or productIds")
+		}
+		if d.Category != "" && !models.IsValidCategory(d.Category) {
+			return nil, fmt.Errorf("unknown category %q", d.Category)
+		}
+		return LineDiscountRule{Category: d.Category, ProductIDs: d.ProductIDs, Rate: d.Rate}, nil
+	case ruleTypeMinimum:
+		if d.MinSubtotal <= 0 {
+			return nil, fmt.Errorf("minimum needs a positive minSubtotal, got %v", d.MinSubtotal)
+		}
+		return MinSubtotalCondition{Amount: d.MinSubtotal}, nil
+	case ruleTypeFlag:
+		if d.Flag == "" {
+			return nil, fmt.Errorf("flag condition needs a flag name")
+		}
+		return FlagCondition{Flag: d.Flag}, nil
+	default:
+		return nil, fmt.Errorf("unknown discount type %q (must be %s, %s, %s, %s or %s)", d.Type, ruleTypePercentage, ruleTypeFreeItem, ruleTypeLine, ruleTypeMinimum, ruleTypeFlag)
+	}
+}
+
+// validateRate checks a discount rate is a fraction in (0, 1]
+func validateRate(rate float64) error {
+	if rate <= 0 || rate > 1 {
+		return fmt.Errorf("rate must be greater than 0 and at most 1, got %v", rate)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+)
+
+func TestParseDiscountRules_Defaults(t *testing.T) {
+	rules, err := ParseDiscountRules("")
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	for _, code := range []string{CouponHappyHours, CouponBuyGetOne} {
+		if _, ok := rules[code]; !ok {
+			t.Errorf("expected built-in discount %s", code)
+		}
+	}
+}
+
+func TestParseDiscountRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed JSON", `{`},
+		{"unknown type", `{"SAVEMORE1":[{"type":"bogus"}]}`},
+		{"rate above one", `{"SAVEMORE1":[{"type":"percentage","rate":1.5}]}`},
+		{"missing rate", `{"SAVEMORE1":[{"type":"percentage"}]}`},
+		{"line without target", `{"SAVEMORE1":[{"type":"line","rate":0.2}]}`},
+		{"line with unknown category", `{"SAVEMORE1":[{"type":"line","category":"Pasta","rate":0.2}]}`},
+		{"flag without a name", `{"SAVEMORE1":[{"type":"flag"},{"type":"free_item"}]}`},
+		{"minimum without subtotal", `{"SAVEMORE1":[{"type":"minimum"},{"type":"free_item"}]}`},
+		{"empty rule stack", `{"SAVEMORE1":[]}`},
+		{"empty code", `{" ":[{"type":"free_item"}]}`},
+		{"duplicate after normalizing", `{"savemore1":[{"type":"free_item"}],"SAVEMORE1":[{"type":"free_item"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDiscountRules(tt.data); err == nil {
+				t.Errorf("ParseDiscountRules(%s) expected an error", tt.data)
+			}
+		})
+	}
+}
+
+func TestParseDiscountRules_CustomDiscountApplied(t *testing.T) {
+	rules, err := ParseDiscountRules(`{
+		"waffleweek": [{"type":"line","category":"Waffle","rate":0.5}],
+		"BIGSPENDER": [{"type":"free_item"},{"type":"percentage","rate":0.1}]
+	}`)
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	if _, ok := rules[CouponHappyHours]; ok {
+		t.Error("configured discounts should replace the built-ins")
+	}
+
+	validator := stubCouponValidator{"WAFFLEWEEK": true, "BIGSPENDER": true}
+	orderService := NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), validator, rules)
+
+	// Product 1 = 12.99 (Waffle), product 4 = 8.99 (Salad) -> subtotal 34.97
+	items := []models.OrderItem{{ProductID: "1", Quantity: 2}, {ProductID: "4", Quantity: 1}}
+
+	tests := []struct {
+		code         string
+		wantDiscount models.Price
+		wantTotal    models.Price
+	}{
+		// Half off the waffle lines only: 50% of 25.98
+		{"WAFFLEWEEK", 12.99, 21.98},
+		// Salad free (8.99), then 10% of the remaining 25.98 (2.60)
+		{"BIGSPENDER", 11.59, 23.38},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{CouponCode: tt.code, Items: items})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			if order.Discount != tt.wantDiscount || order.Total != tt.wantTotal {
+				t.Errorf("discount, total = %v, %v; want %v, %v", order.Discount, order.Total, tt.wantDiscount, tt.wantTotal)
+			}
+		})
+	}
+}
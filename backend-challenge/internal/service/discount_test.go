@@ -0,0 +1,210 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+)
+
+// newTestPricingContext builds a cart of 2x Chicken Waffle (12.99) and 1x Caesar Salad (8.99)
+func newTestPricingContext() PricingContext {
+	return PricingContext{
+		Items: []models.OrderItem{
+			{ProductID: "1", Quantity: 2},
+			{ProductID: "4", Quantity: 1},
+		},
+		Products: map[int64]models.Product{
+			1: {ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
+			4: {ID: 4, Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
+		},
+		Subtotal: 34.97,
+	}
+}
+
+func TestApplyDiscounts_PipelinedVsIndependent(t *testing.T) {
+	pc := newTestPricingContext()
+	rules := []DiscountRule{FreeItemDiscount{}, PercentageDiscount{Rate: 0.18}}
+
+	// Independent: each rule sees the original subtotal
+	// 8.99 + 18% of 34.97 (6.29) = 15.28
+	var independent float64
+	for _, rule := range rules {
+		independent += roundPrice(rule.Discount(pc))
+	}
+	if roundPrice(independent) != 15.28 {
+		t.Fatalf("independent discount = %v, want 15.28", independent)
+	}
+
+	// Pipelined: the percentage applies after the free item is removed
+	// 8.99 + 18% of 25.98 (4.68) = 13.67
+	if got := applyDiscounts(rules, pc); got != 13.67 {
+		t.Errorf("applyDiscounts() = %v, want 13.67", got)
+	}
+
+	// Order matters and is applied deterministically
+	reversed := []DiscountRule{PercentageDiscount{Rate: 0.18}, FreeItemDiscount{}}
+	if got := applyDiscounts(reversed, pc); got != 15.28 {
+		t.Errorf("applyDiscounts(reversed) = %v, want 15.28", got)
+	}
+}
+
+func TestApplyDiscounts_NeverExceedsSubtotal(t *testing.T) {
+	pc := newTestPricingContext()
+	rules := []DiscountRule{
+		PercentageDiscount{Rate: 0.9},
+		FreeItemDiscount{}, // 8.99 but only 3.50 remains
+	}
+
+	if got := applyDiscounts(rules, pc); got != pc.Subtotal {
+		t.Errorf("applyDiscounts() = %v, want capped at subtotal %v", got, pc.Subtotal)
+	}
+}
+
+func TestCalculateDiscount(t *testing.T) {
+	pc := newTestPricingContext()
+
+	tests := []struct {
+		code        string
+		wantAmount  float64
+		wantMatched bool
+	}{
+		{CouponHappyHours, 6.29, true},
+		{CouponBuyGetOne, 8.99, true},
+		{"HAPPYHRS", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			amount, matched := defaultDiscountRules.calculateDiscount(tt.code, pc)
+			if amount != tt.wantAmount || matched != tt.wantMatched {
+				t.Errorf("calculateDiscount(%s) = %v, %v; want %v, %v", tt.code, amount, matched, tt.wantAmount, tt.wantMatched)
+			}
+		})
+	}
+}
+
+// newMixedPricingContext builds a cart of 2x Margherita Pizza (14.99),
+// 1x Pepperoni Pizza (16.99) and 1x Chicken Waffle (12.99)
+func newMixedPricingContext() PricingContext {
+	return PricingContext{
+		Items: []models.OrderItem{
+			{ProductID: "7", Quantity: 2},
+			{ProductID: "8", Quantity: 1},
+			{ProductID: "1", Quantity: 1},
+		},
+		Products: map[int64]models.Product{
+			1: {ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
+			7: {ID: 7, Name: "Margherita Pizza", Price: 14.99, Category: "Pizza"},
+			8: {ID: 8, Name: "Pepperoni Pizza", Price: 16.99, Category: "Pizza"},
+		},
+		Subtotal: 59.96,
+	}
+}
+
+func TestLineDiscountRule(t *testing.T) {
+	pc := newMixedPricingContext()
+
+	tests := []struct {
+		name string
+		rule LineDiscountRule
+		want float64
+	}{
+		// 20% of (2 x 14.99 + 16.99) = 9.39; the waffle line is untouched
+		{"category scoped", LineDiscountRule{Category: "Pizza", Rate: 0.2}, 9.39},
+		// 10% of 16.99 = 1.70
+		{"product scoped", LineDiscountRule{ProductIDs: []int64{8}, Rate: 0.1}, 1.7},
+		// Pizza lines plus the waffle by ID: 10% of 59.96 = 6.00
+		{"category and product", LineDiscountRule{Category: "Pizza", ProductIDs: []int64{1}, Rate: 0.1}, 6},
+		{"no matching lines", LineDiscountRule{Category: "Salad", Rate: 0.2}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyDiscounts([]DiscountRule{tt.rule}, pc); got != tt.want {
+				t.Errorf("applyDiscounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateDiscount_CategoryLine(t *testing.T) {
+	pc := newMixedPricingContext()
+	rules := DiscountRules{"PIZZAPARTY": {LineDiscountRule{Category: "Pizza", Rate: 0.20}}}
+
+	amount, matched := rules.calculateDiscount("PIZZAPARTY", pc)
+	if !matched || amount != 9.39 {
+		t.Errorf("calculateDiscount(PIZZAPARTY) = %v, %v; want 9.39, true", amount, matched)
+	}
+}
+
+func TestFreeItemDiscount_FreeProduct(t *testing.T) {
+	tests := []struct {
+		name   string
+		items  []models.OrderItem
+		wantID int64
+	}{
+		{
+			name:   "clear cheapest",
+			items:  []models.OrderItem{{ProductID: "1", Quantity: 2}, {ProductID: "4", Quantity: 1}},
+			wantID: 4,
+		},
+		{
+			// 11 and 12 share the lowest price; the lower ID wins whatever the line order
+			name:   "tie goes to lowest ID",
+			items:  []models.OrderItem{{ProductID: "12", Quantity: 1}, {ProductID: "1", Quantity: 1}, {ProductID: "11", Quantity: 3}},
+			wantID: 11,
+		},
+	}
+
+	products := map[int64]models.Product{
+		1:  {ID: 1, Name: "Chicken Waffle", Price: 12.99, Category: "Waffle"},
+		4:  {ID: 4, Name: "Caesar Salad", Price: 8.99, Category: "Salad"},
+		11: {ID: 11, Name: "Side Salad", Price: 4.50, Category: "Salad"},
+		12: {ID: 12, Name: "Mini Waffle", Price: 4.50, Category: "Waffle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := defaultDiscountRules.freeProductFor(CouponBuyGetOne, tt.items, products)
+			if product == nil {
+				t.Fatal("freeProductFor() = nil, want a product")
+			}
+			if product.ID != tt.wantID {
+				t.Errorf("free product ID = %d, want %d", product.ID, tt.wantID)
+			}
+
+			pc := PricingContext{Items: tt.items, Products: products}
+			if got := (FreeItemDiscount{}).Discount(pc); got != float64(product.Price) {
+				t.Errorf("Discount() = %v, want the free product's price %v", got, product.Price)
+			}
+		})
+	}
+
+	if product := defaultDiscountRules.freeProductFor(CouponHappyHours, tests[0].items, products); product != nil {
+		t.Errorf("freeProductFor(%s) = %+v, want nil", CouponHappyHours, product)
+	}
+}
+
+func TestFreeItemDiscount_MissingProduct(t *testing.T) {
+	// Product 99 has no entry in the map; a zero-value lookup would make it
+	// the "cheapest" item and zero out the discount
+	pc := newTestPricingContext()
+	pc.Items = append(pc.Items, models.OrderItem{ProductID: "99", Quantity: 1})
+
+	product, ok := FreeItemDiscount{}.FreeProduct(pc)
+	if !ok || product.ID != 4 {
+		t.Errorf("FreeProduct() = %+v, %v; want product 4", product, ok)
+	}
+	if got := (FreeItemDiscount{}).Discount(pc); got != 8.99 {
+		t.Errorf("Discount() = %v, want 8.99", got)
+	}
+
+	// With no known products there is nothing to make free
+	pc.Items = []models.OrderItem{{ProductID: "99", Quantity: 1}}
+	if _, ok := (FreeItemDiscount{}).FreeProduct(pc); ok {
+		t.Error("FreeProduct() found a product for an order with only unknown items")
+	}
+	if product := defaultDiscountRules.freeProductFor(CouponBuyGetOne, pc.Items, pc.Products); product != nil {
+		t.Errorf("freeProductFor() = %+v, want nil", product)
+	}
+}
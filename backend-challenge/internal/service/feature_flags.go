@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+)
+
+// FeatureFlags maps flag names to the percentage of orders (0-100) they are
+// enabled for, so new discount rules can be rolled out gradually
+type FeatureFlags map[string]int
+
+// ParseFeatureFlags parses a JSON object of flag percentages, e.g.
+// {"saladdays":25}; empty input yields no flags
+func ParseFeatureFlags(data string) (FeatureFlags, error) {
+	if data == "" {
+		return FeatureFlags{}, nil
+	}
+
+	var flags FeatureFlags
+	if err := json.Unmarshal([]byte(data), &flags); err != nil {
+		return nil, fmt.Errorf("invalid feature flags: %w", err)
+	}
+	for name, percent := range flags {
+		if name == "" {
+			return nil, fmt.Errorf("feature flag has an empty name")
+		}
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("feature flag %s: percentage must be 0-100, got %d", name, percent)
+		}
+	}
+	return flags, nil
+}
+
+// Enabled reports whether a flag is on for the order bucketed by key. The
+// bucket is a hash of the flag and key, so the same order always gets the
+// same treatment and each flag splits orders independently. Unknown flags
+// are off
+func (f FeatureFlags) Enabled(flag, key string) bool {
+	percent := f[flag]
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+	return flagBucket(flag, key) < percent
+}
+
+// flagBucket places a key in one of 100 buckets for a flag
+func flagBucket(flag, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+)
+
+func TestFeatureFlags_Enabled(t *testing.T) {
+	flags := FeatureFlags{"off": 0, "on": 100, "half": 50}
+
+	tests := []struct {
+		name string
+		flag string
+		want func(enabled int) bool
+	}{
+		{"0% is off for every order", "off", func(n int) bool { return n == 0 }},
+		{"100% is on for every order", "on", func(n int) bool { return n == 1000 }},
+		{"unknown flags are off", "missing", func(n int) bool { return n == 0 }},
+		{"50% splits orders", "half", func(n int) bool { return n > 400 && n < 600 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled := 0
+			for i := 0; i < 1000; i++ {
+				if flags.Enabled(tt.flag, fmt.Sprintf("order-%d", i)) {
+					enabled++
+				}
+			}
+			if !tt.want(enabled) {
+				t.Errorf("flag %s enabled for %d of 1000 orders", tt.flag, enabled)
+			}
+		})
+	}
+}
+
+func TestFeatureFlags_DeterministicBucketing(t *testing.T) {
+	flags := FeatureFlags{"saladdays": 30}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("order-%d", i)
+		first := flags.Enabled("saladdays", key)
+		for j := 0; j < 5; j++ {
+			if flags.Enabled("saladdays", key) != first {
+				t.Fatalf("key %s got a different treatment on a later check", key)
+			}
+		}
+	}
+}
+
+func TestParseFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"valid", `{"saladdays":25,"bigspender":100}`, false},
+		{"malformed JSON", `{`, true},
+		{"above 100", `{"saladdays":101}`, true},
+		{"negative", `{"saladdays":-1}`, true},
+		{"empty name", `{"":10}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFeatureFlags(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFeatureFlags(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderService_CreateOrder_FlaggedDiscount(t *testing.T) {
+	rules, err := ParseDiscountRules(`{"SALADDAYS":[{"type":"flag","flag":"saladdays"},{"type":"percentage","rate":0.5}]}`)
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	items := []models.OrderItem{{ProductID: "4", Quantity: 2}} // 2x 8.99
+
+	tests := []struct {
+		name         string
+		percent      int
+		wantDiscount models.Price
+	}{
+		{"flag off", 0, 0},
+		{"flag on", 100, 8.99},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), stubCouponValidator{"SALADDAYS": true}, rules)
+			orderService.SetFeatureFlags(FeatureFlags{"saladdays": tt.percent})
+
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{CouponCode: "SALADDAYS", Items: items})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			if order.Discount != tt.wantDiscount {
+				t.Errorf("Discount = %v, want %v", order.Discount, tt.wantDiscount)
+			}
+		})
+	}
+
+	// The same Idempotency-Key gets the same treatment on every attempt
+	orderService := NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), stubCouponValidator{"SALADDAYS": true}, rules)
+	flags := FeatureFlags{"saladdays": 50}
+	orderService.SetFeatureFlags(flags)
+	ctx := requestinfo.WithIdempotencyKey(context.Background(), "checkout-42")
+	want := models.Price(0)
+	if flags.Enabled("saladdays", "checkout-42") {
+		want = 8.99
+	}
+	for i := 0; i < 3; i++ {
+		order, err := orderService.CreateOrder(ctx, models.OrderRequest{CouponCode: "SALADDAYS", Items: items})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		if order.Discount != want {
+			t.Fatalf("attempt %d: Discount = %v, want %v", i+1, order.Discount, want)
+		}
+	}
+
+	// A coupon check sent with the order's Idempotency-Key matches it
+	check, err := orderService.CheckCoupon(ctx, "SALADDAYS", models.OrderRequest{Items: items})
+	if err != nil {
+		t.Fatalf("CheckCoupon() error = %v", err)
+	}
+	if check.Discount != want {
+		t.Errorf("checked Discount = %v, want %v", check.Discount, want)
+	}
+}
+
+func TestOrderService_CreateOrder_FlagBucketsOrdersNotAPIKeys(t *testing.T) {
+	rules, err := ParseDiscountRules(`{"SALADDAYS":[{"type":"flag","flag":"saladdays"},{"type":"percentage","rate":0.5}]}`)
+	if err != nil {
+		t.Fatalf("ParseDiscountRules() error = %v", err)
+	}
+	items := []models.OrderItem{{ProductID: "4", Quantity: 2}} // 2x 8.99
+
+	orderService := NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), stubCouponValidator{"SALADDAYS": true}, rules)
+	flags := FeatureFlags{"saladdays": 50}
+	orderService.SetFeatureFlags(flags)
+
+	// Orders from one API key are split by their own Idempotency-Keys
+	apiKeyCtx := requestinfo.WithAPIKeyLabel(context.Background(), "key-00000001")
+	seen := make(map[models.Price]bool)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("checkout-%d", i)
+		want := models.Price(0)
+		if flags.Enabled("saladdays", key) {
+			want = 8.99
+		}
+
+		order, err := orderService.CreateOrder(requestinfo.WithIdempotencyKey(apiKeyCtx, key), models.OrderRequest{CouponCode: "SALADDAYS", Items: items})
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		if order.Discount != want {
+			t.Errorf("%s: Discount = %v, want %v", key, order.Discount, want)
+		}
+		seen[order.Discount] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("orders from one API key all got Discount %v, want both treatments", seen)
+	}
+}
@@ -3,9 +3,17 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
+	"time"
 
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/coupon"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/requestinfo"
+	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/timing"
 	"github.com/google/uuid"
 )
 
@@ -14,6 +22,28 @@ var (
 	ErrInvalidQuantity = errors.New("quantity must be positive")
 	ErrEmptyOrder      = errors.New("order must contain at least one item")
 	ErrInvalidCoupon   = errors.New("coupon code is not valid")
+	ErrPriceChanged    = errors.New("order total differs from the expected total")
+	ErrOrderTooLarge   = errors.New("order total exceeds the maximum allowed")
+
+	// ErrUnknownStore means an order names a store with no catalog
+	ErrUnknownStore = errors.New("unknown store")
+
+	// ErrMissingProductID means an order item has no productId, e.g. a null
+	// entry in the items array; it is wrapped with the item's position
+	ErrMissingProductID = errors.New("productId is required")
+
+	// ErrCouponNotApplicable means the coupon is valid but matches no
+	// discount rule, and such orders are set to be rejected
+	ErrCouponNotApplicable = errors.New("coupon is valid but no discount applies")
+
+	// ErrCouponValidatorMissing means a coupon was submitted but no validator
+	// is configured; use CouponsDisabled to turn coupons off intentionally
+	ErrCouponValidatorMissing = errors.New("coupon validator is not configured")
+
+	// ErrCouponUnavailable means the coupon could not be checked right now,
+	// e.g. while the validator's file searches are failing; retrying later
+	// may succeed
+	ErrCouponUnavailable = errors.New("coupon validation is unavailable")
 )
 
 // CouponValidator interface for coupon validation
@@ -22,10 +52,74 @@ type CouponValidator interface {
 	IsValid(ctx context.Context, code string) bool
 }
 
+// CouponVerifier is implemented by validators that can tell an invalid code
+// from one they could not check; orders then fail with ErrCouponUnavailable
+// instead of ErrInvalidCoupon when the check did not complete
+type CouponVerifier interface {
+	Verify(ctx context.Context, code string) (bool, error)
+}
+
+// CouponCanonicalizer is implemented by validators with their own code
+// canonicalization; discounts are then looked up by the same canonical code
+// the validator checked, otherwise by coupon.CanonicalizeCode
+type CouponCanonicalizer interface {
+	Canonicalize(code string) string
+}
+
+// CouponsDisabled is a CouponValidator for deployments that intentionally
+// turn coupons off: submitted coupon codes are ignored and no discount applies
+// A nil validator is instead treated as a misconfiguration
+var CouponsDisabled CouponValidator = couponsDisabled{}
+
+type couponsDisabled struct{}
+
+func (couponsDisabled) IsValid(ctx context.Context, code string) bool {
+	return false
+}
+
+// priceChangeTolerance is how far the total may drift from ExpectedTotal;
+// slightly over a cent so float rounding never rejects an exact cent
+const priceChangeTolerance = 0.01 + 1e-9
+
+// OrderStatusFlagged marks an order accepted but held for review
+const OrderStatusFlagged = "flagged"
+
+// ZeroQuantityPolicy decides what happens to order lines with quantity 0
+type ZeroQuantityPolicy string
+
+const (
+	// ZeroQuantityReject rejects orders with zero-quantity lines with
+	// ErrInvalidQuantity, like any other non-positive quantity
+	ZeroQuantityReject ZeroQuantityPolicy = "reject"
+
+	// ZeroQuantityDrop ignores zero-quantity lines, for clients that send
+	// them to mean "removed"; negative quantities are still rejected
+	ZeroQuantityDrop ZeroQuantityPolicy = "drop"
+)
+
+// OrderLimit bounds the total of a single order to catch suspicious orders
+type OrderLimit struct {
+	MaxTotal float64 // highest allowed total after discounts, 0 disables the limit
+	Flag     bool    // flag over-limit orders for review instead of rejecting them
+}
+
 // OrderService handles order business logic
 type OrderService struct {
 	productRepo     ProductRepository
 	couponValidator CouponValidator
+	discounts       DiscountRules
+	auditor         OrderAuditor // optional, nil disables auditing
+	limit           OrderLimit
+	rejectUnmatched bool // reject valid coupons matching no discount instead of noting them
+	zeroQuantity    ZeroQuantityPolicy
+	log             *slog.Logger
+
+	// stores prices orders naming a store against that store's catalog;
+	// nil means productRepo is the only, default store
+	stores *repository.StoreCatalog
+
+	// flags roll out flag-gated discount rules to a share of orders
+	flags FeatureFlags
 }
 
 // ProductRepository interface for product data access
@@ -33,73 +127,378 @@ type ProductRepository interface {
 	GetByID(ctx context.Context, id int64) (*models.Product, error)
 }
 
-// NewOrderService creates a new order service
+// NewOrderService creates a new order service with the built-in discounts
 func NewOrderService(productRepo ProductRepository, couponValidator CouponValidator) *OrderService {
+	return NewOrderServiceWithDiscounts(productRepo, couponValidator, defaultDiscountRules)
+}
+
+// NewOrderServiceWithDiscounts creates a new order service with the given discounts
+func NewOrderServiceWithDiscounts(productRepo ProductRepository, couponValidator CouponValidator, discounts DiscountRules) *OrderService {
 	return &OrderService{
 		productRepo:     productRepo,
 		couponValidator: couponValidator,
+		discounts:       discounts,
+		log:             slog.New(slog.DiscardHandler),
+	}
+}
+
+// SetLogger sets the logger for order events worth analysing, such as valid
+// coupons that match no discount; nil discards them
+func (s *OrderService) SetLogger(log *slog.Logger) {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
 	}
+	s.log = log
+}
+
+// SetRejectUnmatchedCoupons makes orders whose valid coupon matches no
+// discount fail with ErrCouponNotApplicable; by default they succeed with
+// a coupon note
+func (s *OrderService) SetRejectUnmatchedCoupons(reject bool) {
+	s.rejectUnmatched = reject
+}
+
+// SetZeroQuantityPolicy sets how zero-quantity order lines are handled; the
+// zero value rejects them
+func (s *OrderService) SetZeroQuantityPolicy(policy ZeroQuantityPolicy) {
+	s.zeroQuantity = policy
 }
 
-// CreateOrder creates a new order with optional coupon validation
+// SetStores sets the per-store catalogs orders are priced against; the
+// service's own product repository should be their default store
+func (s *OrderService) SetStores(stores *repository.StoreCatalog) {
+	s.stores = stores
+}
+
+// SetFeatureFlags sets the flags FlagCondition rules are checked against;
+// nil turns every flag off
+func (s *OrderService) SetFeatureFlags(flags FeatureFlags) {
+	s.flags = flags
+}
+
+// SetAuditor sets the auditor notified of every created order; nil disables it
+func (s *OrderService) SetAuditor(auditor OrderAuditor) {
+	s.auditor = auditor
+}
+
+// SetOrderLimit sets the maximum order total; the zero value disables it
+func (s *OrderService) SetOrderLimit(limit OrderLimit) {
+	s.limit = limit
+}
+
+// CreateOrder creates a new order with optional coupon validation and discount
+// ctx.Err() is returned as-is when the request is cancelled or times out
 func (s *OrderService) CreateOrder(ctx context.Context, req models.OrderRequest) (*models.Order, error) {
-	// Validate request
-	if len(req.Items) == 0 {
-		return nil, ErrEmptyOrder
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	products, err := s.storeProducts(req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	req.Items = s.dropZeroQuantity(req.Items)
+	productMap, subtotal, err := priceItems(ctx, products, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert map to slice for response
+	orderProducts := make([]models.Product, 0, len(productMap))
+	for _, product := range productMap {
+		orderProducts = append(orderProducts, product)
+	}
+
+	// Generate order ID using UUID
+	orderID := generateOrderID()
+
+	order := &models.Order{
+		ID:       orderID,
+		StoreID:  req.StoreID,
+		Items:    req.Items,
+		Products: orderProducts,
+		Total:    models.Price(roundPrice(subtotal)),
+	}
+
+	// Validate and apply coupon if provided
+	if req.CouponCode != "" && s.couponValidator == nil {
+		return nil, ErrCouponValidatorMissing
+	}
+	if req.CouponCode != "" && s.couponValidator != CouponsDisabled {
+		start := time.Now()
+		valid, err := s.validateCoupon(ctx, req.CouponCode)
+		timing.FromContext(ctx).Since("coupon", start)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			// An unfinished search is not proof the coupon is invalid
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidCoupon
+		}
+
+		code := s.canonicalCouponCode(req.CouponCode)
+		discount, matched := s.discounts.calculateDiscount(code, PricingContext{
+			Items:      req.Items,
+			Products:   productMap,
+			Subtotal:   subtotal,
+			Flags:      s.flags,
+			RolloutKey: rolloutKey(ctx, orderID),
+		})
+		if !matched {
+			// Marketing wants to know which codes are used without an active promo
+			s.log.InfoContext(ctx, "valid coupon matched no discount",
+				"coupon", code,
+				"rejected", s.rejectUnmatched,
+				"api_key", requestinfo.APIKeyLabel(ctx),
+				"request_id", requestinfo.RequestID(ctx),
+			)
+			if s.rejectUnmatched {
+				return nil, ErrCouponNotApplicable
+			}
+			order.CouponNote = noDiscountNote
+		}
+
+		// No discount means a condition held the rules back, free item included
+		if discount > 0 {
+			order.FreeProduct = s.discounts.freeProductFor(code, req.Items, productMap)
+		}
+		order.AppliedCoupon = code
+		order.CouponApplied = true
+		order.Discount = models.Price(discount)
+		order.Total = models.Price(roundPrice(subtotal - discount))
+	}
+
+	// Reject rather than silently charge a price the client wasn't quoted
+	if req.ExpectedTotal != nil && math.Abs(float64(order.Total)-float64(*req.ExpectedTotal)) > priceChangeTolerance {
+		return nil, ErrPriceChanged
+	}
+
+	// Orders above the limit are rejected, or accepted and flagged for review
+	if s.limit.MaxTotal > 0 && float64(order.Total) > s.limit.MaxTotal {
+		if !s.limit.Flag {
+			return nil, ErrOrderTooLarge
+		}
+		order.Status = OrderStatusFlagged
+	}
+
+	s.audit(ctx, order)
+	return order, nil
+}
+
+// Reasons a CouponCheck reports a coupon as not applicable
+const (
+	CheckReasonInvalid         = "invalid"
+	CheckReasonNoDiscount      = "no_discount"
+	CheckReasonBelowMinimum    = "below_minimum"
+	CheckReasonNoEligibleItems = "no_eligible_items"
+)
+
+// CheckCoupon reports whether a coupon is valid and applicable to a cart,
+// with the discount and total an order for the cart would get, without
+// creating the order. Cart errors are the ones CreateOrder returns; an
+// invalid or inapplicable coupon is a result, not an error
+func (s *OrderService) CheckCoupon(ctx context.Context, code string, cart models.OrderRequest) (*models.CouponCheck, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	products, err := s.storeProducts(cart.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	cart.Items = s.dropZeroQuantity(cart.Items)
+	productMap, subtotal, err := priceItems(ctx, products, cart.Items)
+	if err != nil {
+		return nil, err
+	}
+	if s.couponValidator == nil {
+		return nil, ErrCouponValidatorMissing
+	}
+
+	check := &models.CouponCheck{
+		Coupon:   s.canonicalCouponCode(code),
+		Subtotal: models.Price(roundPrice(subtotal)),
+		Total:    models.Price(roundPrice(subtotal)),
+		Reason:   CheckReasonInvalid,
+	}
+	if code == "" || s.couponValidator == CouponsDisabled {
+		return check, nil
+	}
+
+	valid, err := s.validateCoupon(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		// An unfinished search is not proof the coupon is invalid
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return check, nil
+	}
+	check.Valid = true
+
+	rules, matched := s.discounts[check.Coupon]
+	pc := PricingContext{
+		Items:      cart.Items,
+		Products:   productMap,
+		Subtotal:   subtotal,
+		Flags:      s.flags,
+		RolloutKey: rolloutKey(ctx, ""),
+	}
+	discount := applyDiscounts(rules, pc)
+	switch {
+	case !matched:
+		check.Reason = CheckReasonNoDiscount
+	case !conditionsMet(rules, pc):
+		check.Reason = CheckReasonBelowMinimum
+	case discount <= 0:
+		check.Reason = CheckReasonNoEligibleItems
+	default:
+		check.Applicable = true
+		check.Reason = ""
+		check.Discount = models.Price(discount)
+		check.Total = models.Price(roundPrice(subtotal - discount))
+		check.FreeProduct = s.discounts.freeProductFor(check.Coupon, cart.Items, productMap)
+	}
+	return check, nil
+}
+
+// storeProducts returns the product repository of the store an order names;
+// an empty store ID is the default store
+func (s *OrderService) storeProducts(storeID string) (ProductRepository, error) {
+	if s.stores == nil {
+		if storeID != "" && storeID != repository.DefaultStoreID {
+			return nil, ErrUnknownStore
+		}
+		return s.productRepo, nil
+	}
+
+	products, err := s.stores.Store(storeID)
+	if err != nil {
+		return nil, ErrUnknownStore
+	}
+	return products, nil
+}
+
+// priceItems validates order items, fetching each product once from
+// products, and returns the products by ID with the order subtotal
+func priceItems(ctx context.Context, products ProductRepository, items []models.OrderItem) (map[int64]models.Product, float64, error) {
+	if len(items) == 0 {
+		return nil, 0, ErrEmptyOrder
 	}
 
-	// Validate items and fetch products (deduplicated)
 	productMap := make(map[int64]models.Product)
+	var subtotal float64
 
-	for _, item := range req.Items {
+	for i, item := range items {
+		if item.ProductID == "" {
+			return nil, 0, fmt.Errorf("items[%d]: %w", i, ErrMissingProductID)
+		}
 		if item.Quantity <= 0 {
-			return nil, ErrInvalidQuantity
+			return nil, 0, ErrInvalidQuantity
 		}
 
 		productID, err := strconv.ParseInt(item.ProductID, 10, 64)
 		if err != nil {
-			return nil, ErrInvalidProduct
-		}
-
-		// Skip if we've already fetched this product
-		if _, exists := productMap[productID]; exists {
-			continue
+			return nil, 0, ErrInvalidProduct
 		}
 
-		product, err := s.productRepo.GetByID(ctx, productID)
-		if err != nil {
-			return nil, ErrInvalidProduct
+		// Only fetch products we haven't seen yet
+		product, exists := productMap[productID]
+		if !exists {
+			fetched, err := products.GetByID(ctx, productID)
+			if err != nil || fetched.IsDeleted() {
+				return nil, 0, ErrInvalidProduct
+			}
+			product = *fetched
+			productMap[productID] = product
 		}
 
-		productMap[productID] = *product
+		subtotal += float64(product.Price) * float64(item.Quantity)
 	}
+	return productMap, subtotal, nil
+}
 
-	// Convert map to slice for response
-	products := make([]models.Product, 0, len(productMap))
-	for _, product := range productMap {
-		products = append(products, product)
+// dropZeroQuantity removes zero-quantity lines under ZeroQuantityDrop and
+// returns items unchanged otherwise. An order left empty then fails with
+// ErrEmptyOrder like any other empty order
+func (s *OrderService) dropZeroQuantity(items []models.OrderItem) []models.OrderItem {
+	if s.zeroQuantity != ZeroQuantityDrop {
+		return items
 	}
 
-	// Validate coupon if provided
-	if req.CouponCode != "" && s.couponValidator != nil {
-		if !s.couponValidator.IsValid(ctx, req.CouponCode) {
-			return nil, ErrInvalidCoupon
+	// Lines without a product aren't quantity choices; they stay to be rejected
+	kept := make([]models.OrderItem, 0, len(items))
+	for _, item := range items {
+		if item.Quantity != 0 || item.ProductID == "" {
+			kept = append(kept, item)
 		}
 	}
+	return kept
+}
 
-	// Generate order ID using UUID
-	orderID := generateOrderID()
+// rolloutKey is the key orders are bucketed by for feature flags: the
+// request's Idempotency-Key, so retries and a coupon check sent with the same
+// key as the order get the same treatment, or else orderID
+func rolloutKey(ctx context.Context, orderID string) string {
+	if key := requestinfo.IdempotencyKey(ctx); key != "" {
+		return key
+	}
+	return orderID
+}
 
-	order := &models.Order{
-		ID:       orderID,
-		Items:    req.Items,
-		Products: products,
+// canonicalCouponCode returns the code discounts are looked up by: the
+// validator's canonical form when it has one, else the default
+func (s *OrderService) canonicalCouponCode(code string) string {
+	if canonicalizer, ok := s.couponValidator.(CouponCanonicalizer); ok {
+		return canonicalizer.Canonicalize(code)
 	}
+	return coupon.CanonicalizeCode(code)
+}
 
-	return order, nil
+// audit emits the audit record for a created order
+func (s *OrderService) audit(ctx context.Context, order *models.Order) {
+	if s.auditor == nil {
+		return
+	}
+	s.auditor.AuditOrder(ctx, OrderAuditRecord{
+		OrderID:       order.ID,
+		Items:         order.Items,
+		Discount:      order.Discount,
+		Total:         order.Total,
+		AppliedCoupon: order.AppliedCoupon,
+		CouponApplied: order.CouponApplied,
+		Status:        order.Status,
+		APIKey:        requestinfo.APIKeyLabel(ctx),
+		RequestID:     requestinfo.RequestID(ctx),
+		Timestamp:     time.Now().UTC(),
+	})
 }
 
 // generateOrderID generates a unique order ID using UUID
 func generateOrderID() string {
 	return uuid.New().String()
 }
+
+// validateCoupon checks a code with the configured validator, using Verify
+// when it is available so that an unfinished check is not reported as an
+// invalid coupon
+func (s *OrderService) validateCoupon(ctx context.Context, code string) (bool, error) {
+	verifier, ok := s.couponValidator.(CouponVerifier)
+	if !ok {
+		return s.couponValidator.IsValid(ctx, code), nil
+	}
+
+	valid, err := verifier.Verify(ctx, code)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return false, ctxErr
+		}
+		return false, fmt.Errorf("%w: %w", ErrCouponUnavailable, err)
+	}
+	return valid, nil
+}
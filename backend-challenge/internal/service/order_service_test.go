@@ -1,7 +1,11 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
@@ -161,3 +165,573 @@ func TestOrderService_CreateOrder_DuplicateProducts(t *testing.T) {
 		productIDs[product.ID] = true
 	}
 }
+
+// stubCouponValidator treats a fixed set of codes as valid
+type stubCouponValidator map[string]bool
+
+func (s stubCouponValidator) IsValid(ctx context.Context, code string) bool {
+	return s[code]
+}
+
+func TestOrderService_CreateOrder_Coupons(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	validator := stubCouponValidator{
+		CouponHappyHours: true,
+		CouponBuyGetOne:  true,
+		"HAPPYHRS":       true,
+	}
+	orderService := NewOrderService(productRepo, validator)
+
+	// Product 1 = 12.99, product 4 = 8.99 -> subtotal 34.97
+	items := []models.OrderItem{
+		{ProductID: "1", Quantity: 2},
+		{ProductID: "4", Quantity: 1},
+	}
+
+	tests := []struct {
+		name         string
+		couponCode   string
+		wantErr      error
+		wantApplied  bool
+		wantCoupon   string
+		wantDiscount models.Price
+		wantTotal    models.Price
+		wantNote     bool
+		wantFreeID   int64
+	}{
+		{
+			name:      "no coupon",
+			wantTotal: 34.97,
+		},
+		{
+			name:         "percentage discount applied",
+			couponCode:   CouponHappyHours,
+			wantApplied:  true,
+			wantCoupon:   CouponHappyHours,
+			wantDiscount: 6.29,
+			wantTotal:    28.68,
+		},
+		{
+			name:         "lowest priced item free",
+			couponCode:   CouponBuyGetOne,
+			wantApplied:  true,
+			wantCoupon:   CouponBuyGetOne,
+			wantDiscount: 8.99,
+			wantTotal:    25.98,
+			wantFreeID:   4,
+		},
+		{
+			name:        "valid coupon with no discount rule",
+			couponCode:  "HAPPYHRS",
+			wantApplied: true,
+			wantCoupon:  "HAPPYHRS",
+			wantTotal:   34.97,
+			wantNote:    true,
+		},
+		{
+			name:       "rejected coupon",
+			couponCode: "SUPER100",
+			wantErr:    ErrInvalidCoupon,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{
+				CouponCode: tt.couponCode,
+				Items:      items,
+			})
+
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Errorf("CreateOrder() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateOrder() unexpected error = %v", err)
+			}
+
+			if order.CouponApplied != tt.wantApplied {
+				t.Errorf("CouponApplied = %v, want %v", order.CouponApplied, tt.wantApplied)
+			}
+			if order.AppliedCoupon != tt.wantCoupon {
+				t.Errorf("AppliedCoupon = %q, want %q", order.AppliedCoupon, tt.wantCoupon)
+			}
+			if order.Discount != tt.wantDiscount {
+				t.Errorf("Discount = %v, want %v", order.Discount, tt.wantDiscount)
+			}
+			if order.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", order.Total, tt.wantTotal)
+			}
+			if (order.CouponNote != "") != tt.wantNote {
+				t.Errorf("CouponNote = %q, want note present = %v", order.CouponNote, tt.wantNote)
+			}
+			var freeID int64
+			if order.FreeProduct != nil {
+				freeID = order.FreeProduct.ID
+			}
+			if freeID != tt.wantFreeID {
+				t.Errorf("FreeProduct ID = %d, want %d", freeID, tt.wantFreeID)
+			}
+		})
+	}
+}
+
+func TestOrderService_CreateOrder_UnmatchedCoupon(t *testing.T) {
+	items := []models.OrderItem{{ProductID: "1", Quantity: 1}}
+
+	tests := []struct {
+		name     string
+		reject   bool
+		wantErr  error
+		wantNote string
+	}{
+		{"note mode accepts with a note", false, nil, noDiscountNote},
+		{"reject mode refuses the order", true, ErrCouponNotApplicable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			orderService := NewOrderService(repository.NewInMemoryProductRepository(), stubCouponValidator{"HAPPYHRS": true})
+			orderService.SetLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+			orderService.SetRejectUnmatchedCoupons(tt.reject)
+
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{CouponCode: "HAPPYHRS", Items: items})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && order.CouponNote != tt.wantNote {
+				t.Errorf("CouponNote = %q, want %q", order.CouponNote, tt.wantNote)
+			}
+
+			// Logged either way for analytics
+			if !strings.Contains(logs.String(), `"coupon":"HAPPYHRS"`) {
+				t.Errorf("expected the unmatched coupon to be logged, got %q", logs.String())
+			}
+		})
+	}
+
+	// Coupons with a discount are not logged
+	var logs bytes.Buffer
+	orderService := NewOrderService(repository.NewInMemoryProductRepository(), stubCouponValidator{CouponHappyHours: true})
+	orderService.SetLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+	orderService.SetRejectUnmatchedCoupons(true)
+	if _, err := orderService.CreateOrder(context.Background(), models.OrderRequest{CouponCode: CouponHappyHours, Items: items}); err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no log for a matched coupon, got %q", logs.String())
+	}
+}
+
+func TestOrderService_CreateOrder_DeletedProduct(t *testing.T) {
+	ctx := context.Background()
+	productRepo := repository.NewInMemoryProductRepository()
+	orderService := NewOrderService(productRepo, nil)
+
+	req := models.OrderRequest{Items: []models.OrderItem{{ProductID: "2", Quantity: 1}}}
+
+	if _, err := orderService.CreateOrder(ctx, req); err != nil {
+		t.Fatalf("CreateOrder() before delete error = %v", err)
+	}
+
+	if err := productRepo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := orderService.CreateOrder(ctx, req); err != ErrInvalidProduct {
+		t.Errorf("CreateOrder() with deleted product error = %v, want %v", err, ErrInvalidProduct)
+	}
+}
+
+func TestOrderService_CreateOrder_CouponValidatorConfig(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	req := models.OrderRequest{
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 1}},
+		CouponCode: "HAPPYHOURS",
+	}
+
+	t.Run("coupons disabled ignores the code", func(t *testing.T) {
+		order, err := NewOrderService(productRepo, CouponsDisabled).CreateOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreateOrder() unexpected error = %v", err)
+		}
+		if order.CouponApplied || order.Discount != 0 {
+			t.Errorf("expected no coupon applied, got applied=%v discount=%v", order.CouponApplied, order.Discount)
+		}
+	})
+
+	t.Run("missing validator is an error", func(t *testing.T) {
+		_, err := NewOrderService(productRepo, nil).CreateOrder(context.Background(), req)
+		if err != ErrCouponValidatorMissing {
+			t.Errorf("CreateOrder() error = %v, want %v", err, ErrCouponValidatorMissing)
+		}
+	})
+
+	t.Run("missing validator without a coupon is fine", func(t *testing.T) {
+		noCoupon := models.OrderRequest{Items: req.Items}
+		if _, err := NewOrderService(productRepo, nil).CreateOrder(context.Background(), noCoupon); err != nil {
+			t.Errorf("CreateOrder() unexpected error = %v", err)
+		}
+	})
+}
+
+// cancellingValidator cancels the request context mid-validation, like a
+// server timeout firing during a slow coupon file search
+type cancellingValidator struct {
+	cancel context.CancelFunc
+}
+
+func (v cancellingValidator) IsValid(ctx context.Context, code string) bool {
+	v.cancel()
+	return false
+}
+
+func TestOrderService_CreateOrder_ContextCancelledDuringCoupon(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := models.OrderRequest{
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 1}},
+		CouponCode: "HAPPYHOURS",
+	}
+	_, err := NewOrderService(productRepo, cancellingValidator{cancel: cancel}).CreateOrder(ctx, req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateOrder() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestOrderService_CreateOrder_ExpectedTotal(t *testing.T) {
+	orderService := NewOrderService(repository.NewInMemoryProductRepository(), nil)
+	price := func(p models.Price) *models.Price { return &p }
+
+	// Product 1 = 12.99 x 2 -> total 25.98
+	tests := []struct {
+		name     string
+		expected *models.Price
+		wantErr  error
+	}{
+		{"not provided", nil, nil},
+		{"matching total", price(25.98), nil},
+		{"within a cent", price(25.97), nil},
+		{"price went up", price(24.98), ErrPriceChanged},
+		{"price went down", price(26.50), ErrPriceChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := orderService.CreateOrder(context.Background(), models.OrderRequest{
+				Items:         []models.OrderItem{{ProductID: "1", Quantity: 2}},
+				ExpectedTotal: tt.expected,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderService_CreateOrder_OrderLimit(t *testing.T) {
+	// Product 1 = 12.99 x 2 -> total 25.98
+	tests := []struct {
+		name       string
+		limit      OrderLimit
+		wantErr    error
+		wantStatus string
+	}{
+		{"reject below limit", OrderLimit{MaxTotal: 30}, nil, ""},
+		{"reject at limit", OrderLimit{MaxTotal: 25.98}, nil, ""},
+		{"reject above limit", OrderLimit{MaxTotal: 25.97}, ErrOrderTooLarge, ""},
+		{"flag below limit", OrderLimit{MaxTotal: 30, Flag: true}, nil, ""},
+		{"flag at limit", OrderLimit{MaxTotal: 25.98, Flag: true}, nil, ""},
+		{"flag above limit", OrderLimit{MaxTotal: 25.97, Flag: true}, nil, OrderStatusFlagged},
+		{"no limit", OrderLimit{}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := NewOrderService(repository.NewInMemoryProductRepository(), nil)
+			orderService.SetOrderLimit(tt.limit)
+
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{
+				Items: []models.OrderItem{{ProductID: "1", Quantity: 2}},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && order.Status != tt.wantStatus {
+				t.Errorf("order status = %q, want %q", order.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestOrderService_CreateOrder_OrderLimitAfterDiscount(t *testing.T) {
+	// 25.98 less HAPPYHOURS 18% (4.68) = 21.30, under a 25 limit
+	orderService := NewOrderService(repository.NewInMemoryProductRepository(), stubCouponValidator{CouponHappyHours: true})
+	orderService.SetOrderLimit(OrderLimit{MaxTotal: 25})
+
+	order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{
+		CouponCode: "HAPPYHOURS",
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	if order.Total != 21.30 {
+		t.Errorf("order total = %v, want 21.30", order.Total)
+	}
+}
+
+// canonicalizingValidator accepts codes that canonicalize to a valid code
+type canonicalizingValidator struct {
+	stubCouponValidator
+	canonicalize func(string) string
+}
+
+func (v canonicalizingValidator) IsValid(ctx context.Context, code string) bool {
+	return v.stubCouponValidator[v.canonicalize(code)]
+}
+
+func (v canonicalizingValidator) Canonicalize(code string) string {
+	return v.canonicalize(code)
+}
+
+func TestOrderService_CreateOrder_FormattedCoupon(t *testing.T) {
+	items := []models.OrderItem{{ProductID: "1", Quantity: 2}}
+
+	tests := []struct {
+		name       string
+		validator  CouponValidator
+		code       string
+		wantCoupon string
+	}{
+		{
+			name:       "default normalization strips formatting",
+			validator:  stubCouponValidator{"happy-hours": true},
+			code:       "happy-hours",
+			wantCoupon: CouponHappyHours,
+		},
+		{
+			name: "validator canonicalization is used for the discount lookup",
+			validator: canonicalizingValidator{
+				stubCouponValidator: stubCouponValidator{CouponHappyHours: true},
+				canonicalize: func(code string) string {
+					return strings.ToUpper(strings.ReplaceAll(code, ".", ""))
+				},
+			},
+			code:       "happy.hours",
+			wantCoupon: CouponHappyHours,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := NewOrderService(repository.NewInMemoryProductRepository(), tt.validator)
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{CouponCode: tt.code, Items: items})
+			if err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+			// 18% of 25.98 = 4.68
+			if order.AppliedCoupon != tt.wantCoupon || order.Discount != 4.68 {
+				t.Errorf("applied coupon %q with discount %v, want %q with 4.68", order.AppliedCoupon, order.Discount, tt.wantCoupon)
+			}
+		})
+	}
+}
+
+// failingVerifier reports that coupons can't be checked, like a validator
+// whose file search circuit breaker is open
+type failingVerifier struct {
+	err error
+}
+
+func (v failingVerifier) IsValid(ctx context.Context, code string) bool {
+	return false
+}
+
+func (v failingVerifier) Verify(ctx context.Context, code string) (bool, error) {
+	return false, v.err
+}
+
+func TestOrderService_CreateOrder_CouponUnavailable(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	errBreakerOpen := errors.New("coupon file search unavailable")
+
+	req := models.OrderRequest{
+		Items:      []models.OrderItem{{ProductID: "1", Quantity: 1}},
+		CouponCode: "HAPPYHOURS",
+	}
+	_, err := NewOrderService(productRepo, failingVerifier{err: errBreakerOpen}).CreateOrder(context.Background(), req)
+	if !errors.Is(err, ErrCouponUnavailable) || !errors.Is(err, errBreakerOpen) {
+		t.Errorf("CreateOrder() error = %v, want %v wrapping %v", err, ErrCouponUnavailable, errBreakerOpen)
+	}
+	if errors.Is(err, ErrInvalidCoupon) {
+		t.Error("an unchecked coupon must not be reported as invalid")
+	}
+}
+
+func TestOrderService_CheckCoupon(t *testing.T) {
+	rules := DefaultDiscountRules()
+	rules["BIGSPENDER"] = []DiscountRule{MinSubtotalCondition{Amount: 30}, PercentageDiscount{Rate: 0.1}}
+	rules["PIZZAPARTY"] = []DiscountRule{LineDiscountRule{Category: "Pizza", Rate: 0.2}}
+	validator := stubCouponValidator{"BIGSPENDER": true, "HAPPYHRS": true, "PIZZAPARTY": true}
+	orderService := NewOrderServiceWithDiscounts(repository.NewInMemoryProductRepository(), validator, rules)
+
+	// Product 1 = 12.99, product 4 = 8.99
+	bigCart := []models.OrderItem{{ProductID: "1", Quantity: 2}, {ProductID: "4", Quantity: 1}}
+	smallCart := []models.OrderItem{{ProductID: "1", Quantity: 1}}
+
+	tests := []struct {
+		name           string
+		code           string
+		items          []models.OrderItem
+		wantValid      bool
+		wantApplicable bool
+		wantReason     string
+		wantDiscount   models.Price
+		wantTotal      models.Price
+	}{
+		{"valid and applicable", "BIGSPENDER", bigCart, true, true, "", 3.50, 31.47},
+		{"below the minimum subtotal", "BIGSPENDER", smallCart, true, false, CheckReasonBelowMinimum, 0, 12.99},
+		{"valid coupon with no discount rule", "HAPPYHRS", bigCart, true, false, CheckReasonNoDiscount, 0, 34.97},
+		{"no item the discount covers", "PIZZAPARTY", bigCart, true, false, CheckReasonNoEligibleItems, 0, 34.97},
+		{"invalid coupon", "SUPER100", bigCart, false, false, CheckReasonInvalid, 0, 34.97},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := orderService.CheckCoupon(context.Background(), tt.code, models.OrderRequest{Items: tt.items})
+			if err != nil {
+				t.Fatalf("CheckCoupon() error = %v", err)
+			}
+			if check.Valid != tt.wantValid || check.Applicable != tt.wantApplicable || check.Reason != tt.wantReason {
+				t.Errorf("CheckCoupon() valid=%v applicable=%v reason=%q, want %v, %v, %q",
+					check.Valid, check.Applicable, check.Reason, tt.wantValid, tt.wantApplicable, tt.wantReason)
+			}
+			if check.Discount != tt.wantDiscount || check.Total != tt.wantTotal {
+				t.Errorf("CheckCoupon() discount=%v total=%v, want %v, %v", check.Discount, check.Total, tt.wantDiscount, tt.wantTotal)
+			}
+		})
+	}
+
+	if _, err := orderService.CheckCoupon(context.Background(), "BIGSPENDER", models.OrderRequest{}); !errors.Is(err, ErrEmptyOrder) {
+		t.Errorf("CheckCoupon() on an empty cart error = %v, want ErrEmptyOrder", err)
+	}
+}
+
+func TestOrderService_CreateOrder_ZeroQuantityPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    ZeroQuantityPolicy
+		items     []models.OrderItem
+		wantErr   error
+		wantItems int
+		wantTotal models.Price
+	}{
+		{
+			name:    "reject refuses a zero-quantity line",
+			policy:  ZeroQuantityReject,
+			items:   []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "4", Quantity: 0}},
+			wantErr: ErrInvalidQuantity,
+		},
+		{
+			name:    "default policy rejects",
+			items:   []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "4", Quantity: 0}},
+			wantErr: ErrInvalidQuantity,
+		},
+		{
+			name:      "drop ignores a zero-quantity line",
+			policy:    ZeroQuantityDrop,
+			items:     []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "4", Quantity: 0}},
+			wantItems: 1,
+			wantTotal: 12.99,
+		},
+		{
+			name:    "drop still rejects negative quantities",
+			policy:  ZeroQuantityDrop,
+			items:   []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "4", Quantity: -1}},
+			wantErr: ErrInvalidQuantity,
+		},
+		{
+			name:    "drop still rejects an empty line",
+			policy:  ZeroQuantityDrop,
+			items:   []models.OrderItem{{ProductID: "1", Quantity: 1}, {}},
+			wantErr: ErrMissingProductID,
+		},
+		{
+			name:    "drop leaving no lines is an empty order",
+			policy:  ZeroQuantityDrop,
+			items:   []models.OrderItem{{ProductID: "1", Quantity: 0}},
+			wantErr: ErrEmptyOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := NewOrderService(repository.NewInMemoryProductRepository(), CouponsDisabled)
+			orderService.SetZeroQuantityPolicy(tt.policy)
+
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{Items: tt.items})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(order.Items) != tt.wantItems {
+				t.Errorf("order has %d items, want %d", len(order.Items), tt.wantItems)
+			}
+			if order.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", order.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestOrderService_CreateOrder_Store(t *testing.T) {
+	productRepo := repository.NewInMemoryProductRepository()
+	orderService := NewOrderService(productRepo, CouponsDisabled)
+	stores, err := repository.NewSeededStoreCatalog(context.Background(), productRepo)
+	if err != nil {
+		t.Fatalf("NewSeededStoreCatalog() error = %v", err)
+	}
+	orderService.SetStores(stores)
+
+	// Product 1 = 12.99 in the default store, 16.24 at the airport
+	items := []models.OrderItem{{ProductID: "1", Quantity: 2}}
+
+	tests := []struct {
+		name      string
+		store     string
+		wantErr   error
+		wantTotal models.Price
+	}{
+		{"default store", "", nil, 25.98},
+		{"default store by ID", repository.DefaultStoreID, nil, 25.98},
+		{"airport prices", repository.AirportStoreID, nil, 32.48},
+		{"unknown store", "moon", ErrUnknownStore, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := orderService.CreateOrder(context.Background(), models.OrderRequest{Items: items, StoreID: tt.store})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if order.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", order.Total, tt.wantTotal)
+			}
+			if order.StoreID != tt.store {
+				t.Errorf("StoreID = %q, want %q", order.StoreID, tt.store)
+			}
+		})
+	}
+}
@@ -2,14 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"strings"
 
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/models"
 	"github.com/Lixing-Zhang/kart-challenge/backend-challenge/internal/repository"
 )
 
+var (
+	ErrInvalidProductName     = errors.New("product name is required")
+	ErrInvalidProductPrice    = errors.New("product price must be positive")
+	ErrInvalidProductCategory = errors.New("product category is not allowed")
+	ErrInvalidProductImageURL = errors.New("product image URL must be an absolute http or https URL")
+)
+
 // ProductService handles business logic for products
 type ProductService struct {
-	repo repository.ProductRepository
+	repo   repository.ProductRepository
+	stores *repository.StoreCatalog // optional per-store catalogs, nil for a single store
 }
 
 // NewProductService creates a new product service
@@ -19,12 +30,97 @@ func NewProductService(repo repository.ProductRepository) *ProductService {
 	}
 }
 
+// SetStores sets the per-store catalogs ListStoreProducts reads; the
+// service's own repository should be their default store
+func (s *ProductService) SetStores(stores *repository.StoreCatalog) {
+	s.stores = stores
+}
+
 // ListProducts returns all available products
 func (s *ProductService) ListProducts(ctx context.Context) ([]models.Product, error) {
 	return s.repo.GetAll(ctx)
 }
 
+// ListStoreProducts returns all available products of a store, with that
+// store's prices. Without per-store catalogs only the default store exists
+// Unknown stores return repository.ErrStoreNotFound
+func (s *ProductService) ListStoreProducts(ctx context.Context, storeID string) ([]models.Product, error) {
+	if s.stores == nil {
+		if storeID != "" && storeID != repository.DefaultStoreID {
+			return nil, repository.ErrStoreNotFound
+		}
+		return s.repo.GetAll(ctx)
+	}
+
+	repo, err := s.stores.Store(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetAll(ctx)
+}
+
+// CountProducts returns how many products are available
+func (s *ProductService) CountProducts(ctx context.Context) (int, error) {
+	return s.repo.Count(ctx)
+}
+
+// IterateProducts calls fn for each available product in ID order
+func (s *ProductService) IterateProducts(ctx context.Context, fn func(models.Product) error) error {
+	return s.repo.Iterate(ctx, fn)
+}
+
 // GetProduct returns a product by ID
-func (s *ProductService) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
-	return s.repo.GetByID(ctx, id)
+// Soft-deleted products are reported as not found unless includeDeleted is set
+func (s *ProductService) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.IsDeleted() && !includeDeleted {
+		return nil, repository.ErrProductNotFound
+	}
+	return product, nil
+}
+
+// GetProducts returns the products matching the given IDs, omitting unknown IDs
+func (s *ProductService) GetProducts(ctx context.Context, ids []int64) ([]models.Product, error) {
+	return s.repo.GetByIDs(ctx, ids)
+}
+
+// CreateProduct validates and stores a new product
+// A zero ID is auto-assigned by the repository
+func (s *ProductService) CreateProduct(ctx context.Context, product models.Product) (*models.Product, error) {
+	product.Name = strings.TrimSpace(product.Name)
+	if product.Name == "" {
+		return nil, ErrInvalidProductName
+	}
+	if product.Price <= 0 {
+		return nil, ErrInvalidProductPrice
+	}
+	if !models.IsValidCategory(product.Category) {
+		return nil, ErrInvalidProductCategory
+	}
+	product.ImageURL = strings.TrimSpace(product.ImageURL)
+	if product.ImageURL != "" && !isValidImageURL(product.ImageURL) {
+		return nil, ErrInvalidProductImageURL
+	}
+	product.Description = strings.TrimSpace(product.Description)
+
+	return s.repo.Create(ctx, product)
+}
+
+// isValidImageURL reports whether s is an absolute http or https URL
+func isValidImageURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// DeleteProduct soft-deletes a product
+func (s *ProductService) DeleteProduct(ctx context.Context, id int64) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// ReloadProducts replaces the catalog with the current contents of its seed file
+func (s *ProductService) ReloadProducts(ctx context.Context) error {
+	return s.repo.Reload(ctx)
 }
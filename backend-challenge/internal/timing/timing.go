@@ -0,0 +1,67 @@
+package timing
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric is one named duration reported in the Server-Timing header
+type Metric struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Recorder collects per-request timing metrics; a nil Recorder ignores
+// everything so instrumented code needs no enabled checks
+type Recorder struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+type recorderKey struct{}
+
+// NewContext returns a context carrying rec
+func NewContext(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+// FromContext returns the request's recorder, or nil when timing is disabled
+func FromContext(ctx context.Context) *Recorder {
+	rec, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return rec
+}
+
+// Add records a metric; safe for concurrent use
+func (r *Recorder) Add(name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, Metric{Name: name, Duration: d})
+}
+
+// Since records the time elapsed since start under name
+func (r *Recorder) Since(name string, start time.Time) {
+	r.Add(name, time.Since(start))
+}
+
+// Header formats the metrics as a Server-Timing header value, e.g.
+// "coupon;dur=12.345, total;dur=20.1" with durations in milliseconds
+func (r *Recorder) Header() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		ms := float64(m.Duration) / float64(time.Millisecond)
+		parts = append(parts, m.Name+";dur="+strconv.FormatFloat(ms, 'f', 3, 64))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,31 @@
+package timing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecorder_Header(t *testing.T) {
+	rec := &Recorder{}
+	rec.Add("coupon", 1500*time.Microsecond)
+	rec.Add("total", 20*time.Millisecond)
+
+	if got, want := rec.Header(), "coupon;dur=1.500, total;dur=20.000"; got != want {
+		t.Errorf("Header() = %q, want %q", got, want)
+	}
+}
+
+func TestRecorder_DisabledIsNoop(t *testing.T) {
+	// Without a recorder in the context every call must be a safe no-op
+	rec := FromContext(context.Background())
+	rec.Since("coupon", time.Now())
+	if got := rec.Header(); got != "" {
+		t.Errorf("Header() = %q, want empty", got)
+	}
+
+	enabled := &Recorder{}
+	if FromContext(NewContext(context.Background(), enabled)) != enabled {
+		t.Error("FromContext() did not return the stored recorder")
+	}
+}